@@ -0,0 +1,204 @@
+//go:build linux
+
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// cgroupTracking starts every command in a cgroup of its own
+// (process_tracking: cgroup), so kills reach processes that left the
+// process group, like daemonized grandchildren.
+var cgroupTracking atomic.Bool
+
+var (
+	cgroupSeq        atomic.Int64
+	cgroupParentOnce sync.Once
+	cgroupParentDir  string
+	cgroupParentErr  error
+	cgroupWarned     atomic.Bool
+)
+
+func setProcessTracking(mode string) {
+	cgroupTracking.Store(mode == "cgroup")
+}
+
+// cgroupScope is the cgroup one command runs in. A nil scope does nothing.
+type cgroupScope struct {
+	path string
+	dir  *os.File
+}
+
+// newCgroupScope creates a cgroup for cmd (suite-<pid>-<n>) under suite's
+// own cgroup v2 and makes cmd start inside it. The first failure is
+// returned so it can be reported once; afterwards commands quietly use
+// their process group only.
+func newCgroupScope(cmd *exec.Cmd) (*cgroupScope, error) {
+	if !cgroupTracking.Load() {
+		return nil, nil
+	}
+	cgroupParentOnce.Do(func() {
+		cgroupParentDir, cgroupParentErr = cgroupParent()
+	})
+	err := cgroupParentErr
+	var scope *cgroupScope
+	if err == nil {
+		name := fmt.Sprintf("suite-%d-%d", os.Getpid(), cgroupSeq.Add(1))
+		scope, err = openCgroupScope(filepath.Join(cgroupParentDir, name))
+	}
+	if err != nil {
+		if cgroupWarned.CompareAndSwap(false, true) {
+			return nil, err
+		}
+		return nil, nil
+	}
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.UseCgroupFD = true
+	cmd.SysProcAttr.CgroupFD = int(scope.dir.Fd())
+	return scope, nil
+}
+
+func openCgroupScope(path string) (*cgroupScope, error) {
+	if err := os.Mkdir(path, 0o755); err != nil {
+		return nil, fmt.Errorf("cgroup tracking unavailable: %w", err)
+	}
+	dir, err := os.Open(path)
+	if err != nil {
+		_ = os.Remove(path)
+		return nil, err
+	}
+	return &cgroupScope{path: path, dir: dir}, nil
+}
+
+// cgroupParent is the directory of the cgroup suite runs in.
+func cgroupParent() (string, error) {
+	mount, root, err := cgroup2Mount()
+	if err != nil {
+		return "", err
+	}
+	own, err := ownCgroup()
+	if err != nil {
+		return "", err
+	}
+	// The mount may expose a subtree rather than the whole hierarchy.
+	return filepath.Join(mount, strings.TrimPrefix(own, root)), nil
+}
+
+func cgroup2Mount() (mountPoint, root string, err error) {
+	file, err := os.Open("/proc/self/mountinfo")
+	if err != nil {
+		return "", "", err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		// id parent dev root mountpoint options ... - fstype source options
+		fields := strings.Fields(scanner.Text())
+		for i, field := range fields {
+			if field == "-" && i+1 < len(fields) && fields[i+1] == "cgroup2" && len(fields) > 4 {
+				return fields[4], fields[3], nil
+			}
+		}
+	}
+	return "", "", errors.New("cgroup tracking unavailable: no cgroup v2 mount")
+}
+
+func ownCgroup() (string, error) {
+	data, err := os.ReadFile("/proc/self/cgroup")
+	if err != nil {
+		return "", err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if path, ok := strings.CutPrefix(line, "0::"); ok {
+			return path, nil
+		}
+	}
+	return "", errors.New("cgroup tracking unavailable: not in a cgroup v2 hierarchy")
+}
+
+func (s *cgroupScope) Path() string {
+	if s == nil {
+		return ""
+	}
+	return s.path
+}
+
+// terminate sends SIGTERM to everything in the cgroup.
+func (s *cgroupScope) terminate() {
+	if s == nil {
+		return
+	}
+	for _, pid := range cgroupPIDs(s.path) {
+		_ = syscall.Kill(pid, syscall.SIGTERM)
+	}
+}
+
+// kill stops everything left in the cgroup.
+func (s *cgroupScope) kill() {
+	if s == nil {
+		return
+	}
+	killCgroup(s.path)
+}
+
+// close kills what is left and removes the cgroup.
+func (s *cgroupScope) close() {
+	if s == nil {
+		return
+	}
+	_ = s.dir.Close()
+	removeCgroup(s.path)
+}
+
+// removeCgroup kills what is left in the cgroup at path and removes it.
+func removeCgroup(path string) {
+	killCgroup(path)
+	_ = os.Remove(path)
+}
+
+// killCgroup kills every process in the cgroup at path and waits briefly
+// for it to empty. cgroup.kill needs Linux 5.14; older kernels get SIGKILL
+// per process until nothing is left.
+func killCgroup(path string) {
+	if err := os.WriteFile(filepath.Join(path, "cgroup.kill"), []byte("1"), 0o644); err != nil {
+		for _, pid := range cgroupPIDs(path) {
+			_ = syscall.Kill(pid, syscall.SIGKILL)
+		}
+	}
+	for i := 0; i < 50 && len(cgroupPIDs(path)) > 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+		for _, pid := range cgroupPIDs(path) {
+			_ = syscall.Kill(pid, syscall.SIGKILL)
+		}
+	}
+}
+
+func cgroupPIDs(path string) []int {
+	if path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(filepath.Join(path, "cgroup.procs"))
+	if err != nil {
+		return nil
+	}
+	var pids []int
+	for _, field := range strings.Fields(string(data)) {
+		if pid, err := strconv.Atoi(field); err == nil {
+			pids = append(pids, pid)
+		}
+	}
+	return pids
+}
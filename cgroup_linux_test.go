@@ -0,0 +1,70 @@
+//go:build linux
+
+package main
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestCgroupTrackingKillsEscapedProcesses(t *testing.T) {
+	parent, err := cgroupParent()
+	if err != nil {
+		t.Skipf("no cgroup v2: %v", err)
+	}
+	probe, err := openCgroupScope(parent + "/suite-probe-" + strconv.Itoa(os.Getpid()))
+	if err != nil {
+		t.Skipf("cgroup not writable: %v", err)
+	}
+	probe.close()
+
+	setProcessTracking("cgroup")
+	t.Cleanup(func() { setProcessTracking("") })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	msgCh := make(chan tea.Msg, 16)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _ = runSingle(ctx, "setsid sleep 30 & echo $!; sleep 30", "/bin/sh", nil, msgCh, "task")
+	}()
+
+	var escaped int
+	select {
+	case msg := <-msgCh:
+		out, ok := msg.(TaskOutputMsg)
+		if !ok {
+			t.Fatalf("unexpected message %#v", msg)
+		}
+		if strings.Contains(out.Line, "unavailable") {
+			t.Skip(out.Line)
+		}
+		escaped, _ = strconv.Atoi(out.Line)
+	case <-time.After(2 * time.Second):
+		t.Fatalf("expected the escaped pid")
+	}
+	if escaped <= 0 {
+		t.Fatalf("unexpected pid")
+	}
+	cancel()
+	<-done
+
+	deadline := time.Now().Add(2 * time.Second)
+	for syscall.Kill(escaped, 0) == nil {
+		if data, _ := os.ReadFile("/proc/" + strconv.Itoa(escaped) + "/stat"); strings.Contains(string(data), ") Z ") {
+			break
+		}
+		if time.Now().After(deadline) {
+			_ = syscall.Kill(escaped, syscall.SIGKILL)
+			t.Fatalf("expected the setsid child to be killed with its cgroup")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
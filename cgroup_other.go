@@ -0,0 +1,25 @@
+//go:build !linux
+
+package main
+
+import "os/exec"
+
+// cgroups are Linux only; elsewhere process_tracking: cgroup falls back to
+// process groups.
+type cgroupScope struct{}
+
+func setProcessTracking(mode string) {}
+
+func newCgroupScope(cmd *exec.Cmd) (*cgroupScope, error) { return nil, nil }
+
+func (s *cgroupScope) Path() string { return "" }
+
+func (s *cgroupScope) terminate() {}
+
+func (s *cgroupScope) kill() {}
+
+func (s *cgroupScope) close() {}
+
+func removeCgroup(path string) {}
+
+func cgroupPIDs(path string) []int { return nil }
@@ -0,0 +1,370 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"text/tabwriter"
+)
+
+// runSubcommand dispatches `suite <name> ...`. It reports false when name
+// isn't a subcommand so main can fall back to the TUI flags.
+func runSubcommand(name string, args []string) (int, bool) {
+	switch name {
+	case "list":
+		return runListCommand(args, os.Stdout), true
+	case "validate":
+		return runValidateCommand(args, os.Stdout), true
+	case "graph":
+		return runGraphCommand(args, os.Stdout), true
+	case "run":
+		return runRunCommand(args, os.Stdout), true
+	case "import":
+		return runImportCommand(args, os.Stdout), true
+	case "attach":
+		return runAttachCommand(args), true
+	case "stop":
+		return runStopCommand(args), true
+	case "doctor":
+		return runDoctorCommand(args, os.Stdout), true
+	}
+	return 0, false
+}
+
+func commandFlags(name string) (*flag.FlagSet, *string) {
+	fs := flag.NewFlagSet("suite "+name, flag.ContinueOnError)
+	configPath := fs.String("config", defaultConfigName, "path to config file")
+	fs.StringVar(configPath, "c", defaultConfigName, "path to config file (shorthand)")
+	return fs, configPath
+}
+
+type listTask struct {
+	Name       string     `json:"name"`
+	Key        string     `json:"key,omitempty"`
+	Hidden     bool       `json:"hidden"`
+	Persistent bool       `json:"persistent"`
+	Combo      bool       `json:"combo,omitempty"`
+	Mode       string     `json:"mode"`
+	Summary    string     `json:"summary"`
+	Steps      []listStep `json:"steps"`
+}
+
+type listStep struct {
+	Name  string `json:"name"`
+	Kind  string `json:"kind"`
+	Value string `json:"value"`
+}
+
+func runListCommand(args []string, out io.Writer) int {
+	fs, configPath := commandFlags("list")
+	format := fs.String("format", "table", "output format: table or json")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	cfg, err := LoadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "config error: %v\n", err)
+		return 1
+	}
+
+	tasks := listTasks(cfg)
+	switch *format {
+	case "json":
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(tasks); err != nil {
+			fmt.Fprintf(os.Stderr, "list error: %v\n", err)
+			return 1
+		}
+	case "table":
+		tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "KEY\tNAME\tMODE\tSTEPS\tFLAGS")
+		for _, task := range tasks {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", task.Key, task.Name, task.Mode, firstLine(task.Summary), listFlags(task))
+		}
+		tw.Flush()
+	default:
+		fmt.Fprintf(os.Stderr, "unknown format %q (use table or json)\n", *format)
+		return 2
+	}
+	return 0
+}
+
+func listTasks(cfg Config) []listTask {
+	resolve := configResolver(cfg)
+	tasks := make([]listTask, 0, len(cfg.Tasks))
+	for _, def := range cfg.Tasks {
+		mode, steps, multi := taskSteps(def.Name, def, resolve)
+		task := listTask{
+			Name:       def.Name,
+			Key:        def.Key,
+			Hidden:     def.Hidden,
+			Persistent: def.Persistent,
+			Combo:      def.Combo,
+			Mode:       listModeName(mode, multi),
+			Summary:    summarizeSteps(steps),
+			Steps:      make([]listStep, 0, len(steps)),
+		}
+		for _, step := range steps {
+			kind, value := resolveStepKind(step, resolve)
+			kindName := "command"
+			if kind == StepTask {
+				kindName = "task"
+			}
+			task.Steps = append(task.Steps, listStep{Name: stepDisplayName(step), Kind: kindName, Value: value})
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks
+}
+
+func configResolver(cfg Config) TaskResolver {
+	defs := make(map[string]TaskDef, len(cfg.Tasks))
+	for _, def := range cfg.Tasks {
+		defs[def.Name] = def
+	}
+	return func(name string) (TaskDef, bool) {
+		def, ok := defs[name]
+		return def, ok
+	}
+}
+
+func listModeName(mode StepMode, multi bool) string {
+	switch {
+	case !multi:
+		return "cmd"
+	case mode == StepModeParallel:
+		return "parallel"
+	default:
+		return "seq"
+	}
+}
+
+func listFlags(task listTask) string {
+	flags := []string{}
+	if task.Hidden {
+		flags = append(flags, "hidden")
+	}
+	if task.Persistent {
+		flags = append(flags, "persistent")
+	}
+	if task.Combo {
+		flags = append(flags, "combo")
+	}
+	return strings.Join(flags, ",")
+}
+
+func firstLine(text string) string {
+	if idx := strings.IndexByte(text, '\n'); idx >= 0 {
+		return text[:idx] + " …"
+	}
+	return text
+}
+
+// runRunCommand is `suite run [--dry-run] <task>...`: a headless run, or
+// the plan of what it would execute.
+func runRunCommand(args []string, out io.Writer) int {
+	fs, configPath := commandFlags("run")
+	dryRun := fs.Bool("dry-run", false, "print the execution plan without running anything")
+	eventsPath := fs.String("events", "", "write task events as NDJSON to path (- for stdout)")
+	var reportPaths stringList
+	fs.Var(&reportPaths, "report", "write a JUnit XML (or .md summary) report; repeatable")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	cfg, err := LoadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "config error: %v\n", err)
+		return 1
+	}
+	if !*dryRun {
+		return headlessMain(cfg, *configPath, fs.Args(), *eventsPath, reportPaths)
+	}
+
+	if fs.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "suite run --dry-run needs at least one task name")
+		return 2
+	}
+	resolve := configResolver(cfg)
+	for i, name := range fs.Args() {
+		if _, ok := resolve(name); !ok {
+			fmt.Fprintf(os.Stderr, "unknown task %q\n", name)
+			return 2
+		}
+		if i > 0 {
+			fmt.Fprintln(out)
+		}
+		for _, line := range explainTask(cfg, name, resolve) {
+			fmt.Fprintln(out, line)
+		}
+	}
+	return 0
+}
+
+func runImportCommand(args []string, out io.Writer) int {
+	fs, configPath := commandFlags("import")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "usage: suite import <Makefile|package.json|Procfile|justfile|Rakefile|bin>...")
+		return 2
+	}
+	for _, source := range fs.Args() {
+		count, err := runImport(*configPath, source)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "import error: %v\n", err)
+			return 1
+		}
+		fmt.Fprintf(out, "Imported %d tasks from %s into %s.\n", count, source, *configPath)
+	}
+	return 0
+}
+
+type validationIssue struct {
+	Warning bool
+	Message string
+}
+
+func (i validationIssue) String() string {
+	if i.Warning {
+		return "warning: " + i.Message
+	}
+	return "error: " + i.Message
+}
+
+func runValidateCommand(args []string, out io.Writer) int {
+	fs, configPath := commandFlags("validate")
+	strict := fs.Bool("strict", false, "treat warnings as errors")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	cfg, err := LoadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintf(out, "error: %v\n", err)
+		return 1
+	}
+
+	failed := false
+	issues := validateConfigDeep(cfg, exec.LookPath)
+	for _, issue := range issues {
+		fmt.Fprintln(out, issue)
+		failed = failed || !issue.Warning || *strict
+	}
+	if failed {
+		return 1
+	}
+	if len(issues) == 0 {
+		fmt.Fprintf(out, "%s is valid (%d tasks)\n", *configPath, len(cfg.Tasks))
+	}
+	return 0
+}
+
+// validateConfigDeep runs the checks LoadConfig skips because they depend on
+// the environment or are only suspicious rather than invalid. Unknown task
+// references never get here; LoadConfig already rejects them.
+func validateConfigDeep(cfg Config, lookPath func(string) (string, error)) []validationIssue {
+	resolve := configResolver(cfg)
+	referenced := map[string]bool{}
+	issues := []validationIssue{}
+
+	for _, def := range cfg.Tasks {
+		for _, list := range []StepList{def.Cmd, def.Seq, def.Parallel} {
+			for idx, step := range list {
+				where := fmt.Sprintf("task %q step %d (%s)", def.Name, idx+1, firstLine(stepDisplayName(step)))
+				kind, value := resolveStepKind(step, resolve)
+				if kind == StepTask {
+					referenced[value] = true
+					if step.Kind == StepAuto && commandExists(value, lookPath) {
+						issues = append(issues, validationIssue{Warning: true, Message: fmt.Sprintf("%s runs task %q, shadowing the %q command; use {task: %s} or {cmd: %s} to be explicit", where, value, value, value, value)})
+					}
+					continue
+				}
+				if !def.Matrix.IsZero() {
+					continue
+				}
+				if issue, ok := checkCommand(where, value, cfg, lookPath); ok {
+					issues = append(issues, issue)
+				}
+			}
+		}
+		hooks := []CommandList{def.OnStart, def.OnSuccess, def.OnFailure, def.OnExit}
+		for i, name := range []string{"on_start", "on_success", "on_failure", "on_exit"} {
+			for _, command := range hooks[i] {
+				if issue, ok := checkCommand(fmt.Sprintf("task %q %s hook", def.Name, name), command, cfg, lookPath); ok {
+					issues = append(issues, issue)
+				}
+			}
+		}
+	}
+
+	for _, def := range cfg.Tasks {
+		if where := builtinKeys[def.Key]; where != "" {
+			issues = append(issues, validationIssue{Warning: true, Message: fmt.Sprintf("task %q key %q is a built-in key %s, so it doesn't start the task there", def.Name, def.Key, where)})
+		}
+		if def.Hidden && def.Key == "" && !def.Autostart && def.Schedule == "" && !referenced[def.Name] {
+			issues = append(issues, validationIssue{Warning: true, Message: fmt.Sprintf("hidden task %q has no key and is never referenced, so it can't run", def.Name)})
+		}
+	}
+	return issues
+}
+
+func checkCommand(where, command string, cfg Config, lookPath func(string) (string, error)) (validationIssue, bool) {
+	name := commandName(command)
+	if name == "" || commandExists(command, lookPath) {
+		return validationIssue{}, false
+	}
+	issue := validationIssue{Message: fmt.Sprintf("%s: command %q not found on PATH", where, name)}
+	if len(cfg.Init) > 0 {
+		issue.Warning = true
+		issue.Message += " (init may provide it)"
+	}
+	return issue, true
+}
+
+// commandName returns the program a shell command starts with, skipping
+// leading VAR=value assignments. It returns "" for anything too dynamic to
+// check (variables, quoting, subshells) and for shell builtins.
+func commandName(command string) string {
+	for _, line := range strings.Split(command, "\n") {
+		fields := strings.Fields(line)
+		for len(fields) > 0 && strings.Contains(fields[0], "=") && !strings.HasPrefix(fields[0], "=") {
+			fields = fields[1:]
+		}
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		name := fields[0]
+		if strings.ContainsAny(name, "$`'\"(){}<>|&;*?") || shellBuiltins[name] {
+			return ""
+		}
+		return name
+	}
+	return ""
+}
+
+func commandExists(command string, lookPath func(string) (string, error)) bool {
+	name := commandName(command)
+	if name == "" {
+		return shellBuiltins[strings.TrimSpace(command)]
+	}
+	if strings.Contains(name, "/") {
+		_, err := os.Stat(name)
+		return err == nil
+	}
+	_, err := lookPath(name)
+	return err == nil
+}
+
+var shellBuiltins = map[string]bool{
+	".": true, ":": true, "[": true, "[[": true, "alias": true, "bg": true, "break": true, "case": true,
+	"cd": true, "command": true, "continue": true, "echo": true, "eval": true, "exec": true, "exit": true,
+	"export": true, "false": true, "fg": true, "for": true, "function": true, "if": true, "kill": true,
+	"printf": true, "pwd": true, "read": true, "return": true, "set": true, "shift": true, "source": true,
+	"test": true, "time": true, "trap": true, "true": true, "type": true, "ulimit": true, "umask": true,
+	"unset": true, "until": true, "wait": true, "while": true, "!": true,
+}
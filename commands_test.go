@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func fakeLookPath(found ...string) func(string) (string, error) {
+	return func(name string) (string, error) {
+		for _, f := range found {
+			if f == name {
+				return "/usr/bin/" + name, nil
+			}
+		}
+		return "", errors.New("not found")
+	}
+}
+
+func TestValidateConfigDeep(t *testing.T) {
+	cfg := Config{
+		Tasks: []TaskDef{
+			{Name: "lint", Cmd: StepList{{Value: "rubocop", Kind: StepAuto}}},
+			{Name: "helper", Hidden: true, Cmd: StepList{{Value: "echo hi", Kind: StepAuto}}},
+			{Name: "orphan", Hidden: true, Cmd: StepList{{Value: "true", Kind: StepAuto}}},
+			{Name: "full", Seq: StepList{
+				{Value: "lint", Kind: StepAuto},
+				{Value: "helper", Kind: StepTask},
+				{Value: "FOO=1 missing-tool arg", Kind: StepAuto},
+				{Value: "$EDITOR file", Kind: StepAuto},
+			}},
+			{Name: "install", Key: "i", Cmd: StepList{{Value: "true", Kind: StepAuto}}},
+		},
+	}
+
+	issues := validateConfigDeep(cfg, fakeLookPath("rubocop", "lint"))
+	got := []string{}
+	for _, issue := range issues {
+		got = append(got, issue.String())
+	}
+	want := []string{
+		`warning: task "full" step 1 (lint) runs task "lint", shadowing the "lint" command; use {task: lint} or {cmd: lint} to be explicit`,
+		`error: task "full" step 3 (FOO=1 missing-tool arg): command "missing-tool" not found on PATH`,
+		`warning: hidden task "orphan" has no key and is never referenced, so it can't run`,
+		`warning: task "install" key "i" is a built-in key in the output pane, so it doesn't start the task there`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("unexpected issues:\n%s", strings.Join(got, "\n"))
+	}
+}
+
+func TestValidateMissingCommandWithInitIsWarning(t *testing.T) {
+	cfg := Config{
+		Init:  CommandList{`eval "$(mise activate bash)"`},
+		Tasks: []TaskDef{{Name: "test", Cmd: StepList{{Value: "rake test", Kind: StepAuto}}}},
+	}
+	issues := validateConfigDeep(cfg, fakeLookPath())
+	if len(issues) != 1 || !issues[0].Warning {
+		t.Fatalf("expected a single warning, got %v", issues)
+	}
+}
+
+func TestListCommandJSON(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, ".suite.yml")
+	data := `tasks:
+  - name: test
+    key: t
+    cmd: bin/test
+  - name: full
+    parallel:
+      - test
+      - cmd: bin/lint
+        name: lint
+`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	var out bytes.Buffer
+	if code := runListCommand([]string{"-c", path, "--format", "json"}, &out); code != 0 {
+		t.Fatalf("expected exit 0, got %d", code)
+	}
+	for _, want := range []string{`"mode": "parallel"`, `"summary": "test (+1)"`, `"kind": "task"`, `"name": "lint"`} {
+		if !strings.Contains(out.String(), want) {
+			t.Fatalf("expected %s in output:\n%s", want, out.String())
+		}
+	}
+
+	out.Reset()
+	if code := runListCommand([]string{"-c", path}, &out); code != 0 {
+		t.Fatalf("expected exit 0, got %d", code)
+	}
+	if !strings.HasPrefix(out.String(), "KEY") || !strings.Contains(out.String(), "full") {
+		t.Fatalf("unexpected table:\n%s", out.String())
+	}
+}
+
+func TestValidateCommandReportsUnknownTasks(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, ".suite.yml")
+	data := `tasks:
+  - name: full
+    key: f
+    seq:
+      - task: missing
+`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	var out bytes.Buffer
+	if code := runValidateCommand([]string{"-c", path}, &out); code != 1 {
+		t.Fatalf("expected exit 1, got %d", code)
+	}
+	if !strings.Contains(out.String(), `unknown task "missing"`) {
+		t.Fatalf("unexpected output:\n%s", out.String())
+	}
+}
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Condition gates a task or step. Every populated field must hold for the
+// condition to pass; an empty condition always passes.
+type Condition struct {
+	Cmd     string
+	Env     []string
+	OS      []string
+	Exists  []string
+	Changed []string
+}
+
+func (c *Condition) UnmarshalYAML(value *yaml.Node) error {
+	cond, err := parseConditionNode(value)
+	if err != nil {
+		return err
+	}
+	*c = cond
+	return nil
+}
+
+func parseConditionNode(node *yaml.Node) (Condition, error) {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		if node.Tag == "!!null" {
+			return Condition{}, nil
+		}
+		return Condition{Cmd: strings.TrimSpace(node.Value)}, nil
+	case yaml.MappingNode:
+		var cond Condition
+		for i := 0; i < len(node.Content); i += 2 {
+			key := node.Content[i]
+			val := node.Content[i+1]
+			if key.Kind != yaml.ScalarNode {
+				continue
+			}
+			k := strings.TrimSpace(key.Value)
+			switch k {
+			case "cmd":
+				if val.Kind != yaml.ScalarNode {
+					return Condition{}, fmt.Errorf("if cmd must be a string")
+				}
+				cond.Cmd = strings.TrimSpace(val.Value)
+			case "env", "os", "exists", "changed":
+				var list CommandList
+				if err := list.UnmarshalYAML(val); err != nil {
+					return Condition{}, fmt.Errorf("if %s must be a string or list", k)
+				}
+				list = normalizeCommandList(list)
+				switch k {
+				case "env":
+					cond.Env = list
+				case "os":
+					cond.OS = list
+				case "exists":
+					cond.Exists = list
+				case "changed":
+					cond.Changed = list
+				}
+			default:
+				return Condition{}, fmt.Errorf("unknown if key %q", k)
+			}
+		}
+		return cond, nil
+	case 0:
+		return Condition{}, nil
+	default:
+		return Condition{}, fmt.Errorf("if must be a string or map")
+	}
+}
+
+func (c Condition) IsZero() bool {
+	return c.Cmd == "" && len(c.Env) == 0 && len(c.OS) == 0 && len(c.Exists) == 0 && len(c.Changed) == 0
+}
+
+func (c Condition) validate() error {
+	if hasEmptyCommand(c.Env) || hasEmptyCommand(c.OS) || hasEmptyCommand(c.Exists) || hasEmptyCommand(c.Changed) {
+		return fmt.Errorf("if has empty values")
+	}
+	for _, expr := range c.Env {
+		if _, _, _, err := parseEnvExpr(expr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// evalCondition reports whether cond holds. Cheap checks run first so the
+// shell command only runs when everything else already passed. The hashes
+// behind a changed: check are returned rather than recorded; the caller
+// records them once the gated run passes, so a failed run is retried.
+func evalCondition(ctx context.Context, cond Condition, shell string, init CommandList, target string) (bool, fileHashes, error) {
+	if cond.IsZero() {
+		return true, nil, nil
+	}
+	if len(cond.OS) > 0 && !matchOS(cond.OS) {
+		return false, nil, nil
+	}
+	for _, expr := range cond.Env {
+		ok, err := evalEnvExpr(ctx, expr)
+		if err != nil || !ok {
+			return false, nil, err
+		}
+	}
+	for _, path := range cond.Exists {
+		if _, err := os.Stat(path); err != nil {
+			return false, nil, nil
+		}
+	}
+	var seen fileHashes
+	if len(cond.Changed) > 0 {
+		var changed bool
+		changed, seen = changeTracker.changed(cond.Changed)
+		if !changed {
+			return false, nil, nil
+		}
+	}
+	if cond.Cmd != "" {
+		cmd := shellCommand(ctx, cond.Cmd, shell, init)
+		if err := runTracked(ctx, cmd, target, cond.Cmd); err != nil {
+			if ctx.Err() != nil {
+				return false, nil, ctx.Err()
+			}
+			if _, ok := err.(*exec.ExitError); ok {
+				return false, nil, nil
+			}
+			return false, nil, err
+		}
+	}
+	return true, seen, nil
+}
+
+func matchOS(list []string) bool {
+	matched := false
+	wanted := false
+	for _, name := range list {
+		name = strings.ToLower(strings.TrimSpace(name))
+		negate := strings.HasPrefix(name, "!")
+		name = strings.TrimPrefix(name, "!")
+		hit := name == runtime.GOOS ||
+			name == "macos" && runtime.GOOS == "darwin" ||
+			name == "unix" && runtime.GOOS != "windows"
+		if negate {
+			if hit {
+				return false
+			}
+			continue
+		}
+		wanted = true
+		matched = matched || hit
+	}
+	return matched || !wanted
+}
+
+// parseEnvExpr splits NAME, !NAME, NAME=value and NAME!=value.
+func parseEnvExpr(expr string) (name string, op string, value string, err error) {
+	expr = strings.TrimSpace(expr)
+	if idx := strings.Index(expr, "!="); idx > 0 {
+		name, op, value = expr[:idx], "!=", expr[idx+2:]
+	} else if idx := strings.Index(expr, "="); idx > 0 {
+		name, op, value = expr[:idx], "=", expr[idx+1:]
+	} else if strings.HasPrefix(expr, "!") {
+		name, op = expr[1:], "unset"
+	} else {
+		name, op = expr, "set"
+	}
+	name = strings.TrimSpace(name)
+	if name == "" || strings.ContainsAny(name, " \t") {
+		return "", "", "", fmt.Errorf("invalid env condition %q", expr)
+	}
+	return name, op, strings.TrimSpace(value), nil
+}
+
+func evalEnvExpr(ctx context.Context, expr string) (bool, error) {
+	name, op, value, err := parseEnvExpr(expr)
+	if err != nil {
+		return false, err
+	}
+	current, ok := stepEnv(ctx)[name]
+	if !ok {
+		current = os.Getenv(name)
+	}
+	switch op {
+	case "=":
+		return current == value, nil
+	case "!=":
+		return current != value, nil
+	case "unset":
+		return current == "", nil
+	default:
+		return current != "", nil
+	}
+}
+
+// fileHashes maps absolute paths to content hashes.
+type fileHashes map[string]string
+
+type fileChangeTracker struct {
+	mu     sync.Mutex
+	hashes map[string]string
+}
+
+var changeTracker = &fileChangeTracker{hashes: make(map[string]string)}
+
+// changed reports whether any of the files differ from their recorded
+// hashes, along with the current hashes. A file that was never recorded
+// counts as changed.
+func (t *fileChangeTracker) changed(paths []string) (bool, fileHashes) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	changed := false
+	seen := make(fileHashes, len(paths))
+	for _, path := range paths {
+		key := path
+		if abs, err := filepath.Abs(path); err == nil {
+			key = abs
+		}
+		sum := fileHash(path)
+		if prev, ok := t.hashes[key]; !ok || prev != sum {
+			changed = true
+		}
+		seen[key] = sum
+	}
+	return changed, seen
+}
+
+// record remembers hashes as the last seen contents of their files.
+func (t *fileChangeTracker) record(hashes fileHashes) {
+	if len(hashes) == 0 {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for key, sum := range hashes {
+		t.hashes[key] = sum
+	}
+}
+
+func fileHash(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("%x", sha256.Sum256(data))
+}
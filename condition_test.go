@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestConditionUnmarshal(t *testing.T) {
+	var cfg struct {
+		If Condition `yaml:"if"`
+	}
+
+	if err := yaml.Unmarshal([]byte("if: test -f Gemfile"), &cfg); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if cfg.If.Cmd != "test -f Gemfile" {
+		t.Fatalf("unexpected condition: %#v", cfg.If)
+	}
+
+	data := "if: {os: [linux, darwin], env: CI, exists: Gemfile, changed: Gemfile.lock}"
+	if err := yaml.Unmarshal([]byte(data), &cfg); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if len(cfg.If.OS) != 2 || len(cfg.If.Env) != 1 || cfg.If.Exists[0] != "Gemfile" || cfg.If.Changed[0] != "Gemfile.lock" {
+		t.Fatalf("unexpected condition: %#v", cfg.If)
+	}
+
+	if err := yaml.Unmarshal([]byte("if: {weird: true}"), &cfg); err == nil {
+		t.Fatalf("expected error for unknown key")
+	}
+}
+
+func TestEvalConditionChecks(t *testing.T) {
+	t.Setenv("SUITE_COND_TEST", "yes")
+	dir := t.TempDir()
+	file := filepath.Join(dir, "present")
+	if err := os.WriteFile(file, []byte("x"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	cases := []struct {
+		name string
+		cond Condition
+		want bool
+	}{
+		{"empty", Condition{}, true},
+		{"cmd true", Condition{Cmd: "true"}, true},
+		{"cmd false", Condition{Cmd: "exit 1"}, false},
+		{"env set", Condition{Env: []string{"SUITE_COND_TEST"}}, true},
+		{"env equals", Condition{Env: []string{"SUITE_COND_TEST=yes"}}, true},
+		{"env not equals", Condition{Env: []string{"SUITE_COND_TEST!=yes"}}, false},
+		{"env unset", Condition{Env: []string{"!SUITE_COND_MISSING"}}, true},
+		{"os match", Condition{OS: []string{runtime.GOOS}}, true},
+		{"os negated", Condition{OS: []string{"!" + runtime.GOOS}}, false},
+		{"exists", Condition{Exists: []string{file}}, true},
+		{"missing", Condition{Exists: []string{filepath.Join(dir, "missing")}}, false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, _, err := evalCondition(context.Background(), tc.cond, "/bin/sh", nil, "task")
+			if err != nil {
+				t.Fatalf("eval: %v", err)
+			}
+			if got != tc.want {
+				t.Fatalf("expected %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestConditionChangedTracksContent(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "Gemfile.lock")
+	if err := os.WriteFile(file, []byte("one"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	tracker := &fileChangeTracker{hashes: make(map[string]string)}
+	changed, seen := tracker.changed([]string{file})
+	if !changed {
+		t.Fatalf("expected first check to count as changed")
+	}
+	if changed, _ := tracker.changed([]string{file}); !changed {
+		t.Fatalf("expected unrecorded hashes to still count as changed")
+	}
+	tracker.record(seen)
+	if changed, _ := tracker.changed([]string{file}); changed {
+		t.Fatalf("expected unchanged file to be skipped")
+	}
+	if err := os.WriteFile(file, []byte("two"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if changed, _ := tracker.changed([]string{file}); !changed {
+		t.Fatalf("expected changed content to be detected")
+	}
+}
+
+func TestChangedConditionRetriesFailedRuns(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "Gemfile.lock")
+	if err := os.WriteFile(file, []byte("one"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	marker := filepath.Join(dir, "ok")
+	def := TaskDef{Seq: StepList{{
+		Value: fmt.Sprintf("test -e %q && printf 'installed\\n'", marker),
+		Kind:  StepCommand,
+		If:    Condition{Changed: []string{file}},
+	}}}
+
+	outputs, _ := runTaskAndCollect(context.Background(), "install", def)
+	if len(outputs) != 0 {
+		t.Fatalf("expected the first run to fail quietly, got %v", outputs)
+	}
+	if err := os.WriteFile(marker, nil, 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	outputs, _ = runTaskAndCollect(context.Background(), "install", def)
+	if len(outputs) != 1 || outputs[0].Line != "installed" {
+		t.Fatalf("expected the failed run to be retried, got %v", outputs)
+	}
+	outputs, _ = runTaskAndCollect(context.Background(), "install", def)
+	if len(outputs) != 0 {
+		t.Fatalf("expected the passed run to be skipped, got %v", outputs)
+	}
+}
+
+func TestChangedConditionWaitsForCmdCheck(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "Gemfile.lock")
+	if err := os.WriteFile(file, []byte("one"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	cond := Condition{Changed: []string{file}, Cmd: "false"}
+	if ok, seen, _ := evalCondition(context.Background(), cond, "/bin/sh", nil, "task"); ok || seen != nil {
+		t.Fatalf("expected failing cmd to hold back the hashes, got %v %v", ok, seen)
+	}
+	cond.Cmd = ""
+	if ok, _, _ := evalCondition(context.Background(), cond, "/bin/sh", nil, "task"); !ok {
+		t.Fatalf("expected file to still count as changed")
+	}
+}
+
+func TestConditionCmdCancelKillsChildren(t *testing.T) {
+	marker := filepath.Join(t.TempDir(), "marker")
+	cond := Condition{Cmd: fmt.Sprintf("(sleep 1; touch %s) & sleep 30", marker)}
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	if ok, _, err := evalCondition(ctx, cond, "/bin/sh", nil, "task"); ok || err == nil {
+		t.Fatalf("expected a canceled check to fail, got %v %v", ok, err)
+	}
+	time.Sleep(1500 * time.Millisecond)
+	if _, err := os.Stat(marker); err == nil {
+		t.Fatalf("expected the check's background child to be killed")
+	}
+}
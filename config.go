@@ -10,32 +10,61 @@ import (
 )
 
 type Config struct {
-	Title        string      `yaml:"title"`
-	SidebarWidth int         `yaml:"sidebar_width"`
-	Shell        string      `yaml:"shell"`
-	Theme        string      `yaml:"theme"`
-	Init         CommandList `yaml:"init"`
-	Tasks        []TaskDef   `yaml:"tasks"`
-	Combos       []ComboDef  `yaml:"combos"`
+	Title        string       `yaml:"title"`
+	SidebarWidth int          `yaml:"sidebar_width"`
+	Shell        string       `yaml:"shell"`
+	Theme        string       `yaml:"theme"`
+	Jobs         int          `yaml:"jobs"`
+	MaxLines     int          `yaml:"max_lines"` // 0 = default, -1 = unbounded
+	Mode         string       `yaml:"mode"`      // "" | procfile
+	PortBase     int          `yaml:"port_base"`
+	Tracking     string       `yaml:"process_tracking"` // group | cgroup
+	Notify       NotifyConfig `yaml:"notify"`
+	Init         CommandList  `yaml:"init"`
+	OnStartup    CommandList  `yaml:"on_startup"`
+	OnShutdown   CommandList  `yaml:"on_shutdown"`
+	Tasks        []TaskDef    `yaml:"tasks"`
+	Combos       []ComboDef   `yaml:"combos"`
 }
 
 type TaskDef struct {
-	Name       string   `yaml:"name"`
-	Key        string   `yaml:"key"`
-	Hidden     bool     `yaml:"hidden"`
-	Persistent bool     `yaml:"persistent"`
-	Autostart  bool     `yaml:"autostart"`
-	Cmd        StepList `yaml:"cmd"`
-	Parallel   StepList `yaml:"parallel"`
-	Seq        StepList `yaml:"seq"`
+	Name        string    `yaml:"name"`
+	Key         string    `yaml:"key"`
+	Hidden      bool      `yaml:"hidden"`
+	Persistent  bool      `yaml:"persistent"`
+	Autostart   bool      `yaml:"autostart"`
+	If          Condition `yaml:"if"`
+	StopOnFail  *bool     `yaml:"stop_on_fail"`
+	FailFast    bool      `yaml:"fail_fast"`
+	MaxParallel int       `yaml:"max_parallel"`
+	Matrix      Matrix    `yaml:"matrix"`
+	Schedule    string    `yaml:"schedule"`
+	OnRetrigger string    `yaml:"on_retrigger"` // ignore | queue | restart
+	MaxMemory   string    `yaml:"max_memory"`
+	Interactive bool      `yaml:"interactive"`
+	StripANSI   bool      `yaml:"strip_ansi"`
+	// Actions name signals for the signal menu, like reload: SIGHUP.
+	Actions   map[string]string `yaml:"actions"`
+	Notify    NotifyConfig      `yaml:"notify"`
+	TaskHooks `yaml:",inline"`
+	Cmd       StepList `yaml:"cmd"`
+	Parallel  StepList `yaml:"parallel"`
+	Seq       StepList `yaml:"seq"`
+
+	// Combo marks tasks migrated from the deprecated combos section.
+	Combo bool `yaml:"-"`
+	// Procfile marks the task synthesized by procfile mode.
+	Procfile bool `yaml:"-"`
 }
 
+// ComboDef is deprecated: combos are migrated to tasks when the config loads.
 type ComboDef struct {
 	Name       string   `yaml:"name"`
 	Key        string   `yaml:"key"`
 	Mode       string   `yaml:"mode"` // parallel | sequential
-	Run        []string `yaml:"run"`
+	Run        StepList `yaml:"run"`
 	StopOnFail *bool    `yaml:"stop_on_fail"`
+	FailFast   *bool    `yaml:"fail_fast"`
 }
 
 func LoadConfig(path string) (Config, error) {
@@ -43,7 +72,11 @@ func LoadConfig(path string) (Config, error) {
 	if err != nil {
 		return Config{}, err
 	}
+	return parseConfig(data, path)
+}
 
+// parseConfig loads config data as if it were read from path.
+func parseConfig(data []byte, path string) (Config, error) {
 	var cfg Config
 	if err := yaml.Unmarshal(data, &cfg); err != nil {
 		return Config{}, err
@@ -53,6 +86,12 @@ func LoadConfig(path string) (Config, error) {
 	if err := cfg.validate(); err != nil {
 		return Config{}, err
 	}
+	cfg.migrateCombos()
+	if cfg.Mode == modeProcfile {
+		if err := cfg.enableProcfile(); err != nil {
+			return Config{}, err
+		}
+	}
 
 	return cfg, nil
 }
@@ -77,6 +116,8 @@ func (c *Config) normalize(path string) {
 	}
 	c.Shell = strings.TrimSpace(c.Shell)
 	c.Theme = strings.ToLower(strings.TrimSpace(c.Theme))
+	c.Mode = strings.ToLower(strings.TrimSpace(c.Mode))
+	c.Tracking = strings.ToLower(strings.TrimSpace(c.Tracking))
 	if c.Shell == "" {
 		c.Shell = strings.TrimSpace(os.Getenv("SHELL"))
 		if c.Shell == "" {
@@ -84,6 +125,8 @@ func (c *Config) normalize(path string) {
 		}
 	}
 	c.Init = normalizeCommandList(c.Init)
+	c.OnStartup = normalizeCommandList(c.OnStartup)
+	c.OnShutdown = normalizeCommandList(c.OnShutdown)
 
 	for i := range c.Tasks {
 		t := &c.Tasks[i]
@@ -92,6 +135,12 @@ func (c *Config) normalize(path string) {
 		t.Cmd = normalizeStepList(t.Cmd)
 		t.Parallel = normalizeStepList(t.Parallel)
 		t.Seq = normalizeStepList(t.Seq)
+		t.OnRetrigger = strings.ToLower(strings.TrimSpace(t.OnRetrigger))
+		t.Schedule = strings.TrimSpace(t.Schedule)
+		t.OnStart = normalizeCommandList(t.OnStart)
+		t.OnSuccess = normalizeCommandList(t.OnSuccess)
+		t.OnFailure = normalizeCommandList(t.OnFailure)
+		t.OnExit = normalizeCommandList(t.OnExit)
 		if t.Name == "" {
 			t.Name = defaultTaskName(*t)
 		}
@@ -102,6 +151,12 @@ func (c *Config) normalize(path string) {
 		cb.Key = strings.TrimSpace(cb.Key)
 		cb.Name = strings.TrimSpace(cb.Name)
 		cb.Mode = strings.ToLower(strings.TrimSpace(cb.Mode))
+		cb.Run = normalizeStepList(cb.Run)
+		for j := range cb.Run {
+			if cb.Run[j].Kind == StepAuto {
+				cb.Run[j].Kind = StepTask
+			}
+		}
 		if cb.Mode == "" {
 			cb.Mode = "sequential"
 		}
@@ -121,11 +176,35 @@ func (c Config) validate() error {
 	if c.Theme != "" && c.Theme != "auto" && c.Theme != "light" && c.Theme != "dark" {
 		return fmt.Errorf("theme must be one of auto, light, or dark")
 	}
+	if c.Jobs < 0 {
+		return fmt.Errorf("jobs must be zero (unlimited) or positive")
+	}
+	if c.MaxLines < -1 {
+		return fmt.Errorf("max_lines must be positive, or -1 to keep all output in memory")
+	}
+	if c.Mode != "" && c.Mode != modeProcfile {
+		return fmt.Errorf("mode must be procfile when set")
+	}
+	if c.Tracking != "" && c.Tracking != "group" && c.Tracking != "cgroup" {
+		return fmt.Errorf("process_tracking must be group or cgroup")
+	}
+	if c.PortBase < 0 || c.PortBase > 65535 {
+		return fmt.Errorf("port_base must be a port number")
+	}
+	if err := c.Notify.validate(); err != nil {
+		return err
+	}
+	if hasEmptyCommand(c.OnStartup) || hasEmptyCommand(c.OnShutdown) {
+		return fmt.Errorf("on_startup and on_shutdown commands must be non-empty")
+	}
 
 	taskNames := map[string]struct{}{}
 	keyUsed := map[string]string{}
 
 	for _, t := range c.Tasks {
+		if t.Combo {
+			continue
+		}
 		if t.Key != "" && len([]rune(t.Key)) != 1 {
 			return fmt.Errorf("task key %q must be a single character", t.Key)
 		}
@@ -138,6 +217,56 @@ func (c Config) validate() error {
 		if hasEmptyStep(t.Cmd) || hasEmptyStep(t.Parallel) || hasEmptyStep(t.Seq) {
 			return fmt.Errorf("task %q has empty commands", t.Name)
 		}
+		if err := validateTaskConditions(t); err != nil {
+			return err
+		}
+		if t.StopOnFail != nil && len(t.Parallel) > 0 {
+			return fmt.Errorf("task %q: stop_on_fail only applies to seq/cmd lists", t.Name)
+		}
+		if t.FailFast && len(t.Parallel) == 0 {
+			return fmt.Errorf("task %q: fail_fast only applies to parallel", t.Name)
+		}
+		if t.MaxParallel < 0 || t.MaxParallel > 0 && len(t.Parallel) == 0 {
+			return fmt.Errorf("task %q: max_parallel must be positive and only applies to parallel", t.Name)
+		}
+		if t.Schedule != "" {
+			if _, err := parseSchedule(t.Schedule); err != nil {
+				return fmt.Errorf("task %q: %v", t.Name, err)
+			}
+		}
+		if !t.Matrix.IsZero() {
+			if err := t.Matrix.validate(); err != nil {
+				return fmt.Errorf("task %q: %v", t.Name, err)
+			}
+			if len(t.Cmd)+len(t.Parallel)+len(t.Seq) != 1 {
+				return fmt.Errorf("task %q: matrix tasks need exactly one cmd, seq, or parallel step", t.Name)
+			}
+		}
+		if t.OnRetrigger != "" && t.OnRetrigger != "ignore" && t.OnRetrigger != "queue" && t.OnRetrigger != "restart" {
+			return fmt.Errorf("task %q on_retrigger must be one of ignore, queue, or restart", t.Name)
+		}
+		if t.Interactive && (len(t.Parallel) > 0 || t.Schedule != "") {
+			return fmt.Errorf("task %q: interactive tasks can't run parallel steps or on a schedule", t.Name)
+		}
+		for name, sig := range t.Actions {
+			if strings.TrimSpace(name) == "" {
+				return fmt.Errorf("task %q: action names must be non-empty", t.Name)
+			}
+			if _, ok := normalizeSignal(sig); !ok {
+				return fmt.Errorf("task %q action %q: unknown signal %q (use one of %s)", t.Name, name, sig, strings.Join(menuSignals, ", "))
+			}
+		}
+		if t.MaxMemory != "" {
+			if _, err := parseByteSize(t.MaxMemory); err != nil {
+				return fmt.Errorf("task %q max_memory: %v", t.Name, err)
+			}
+		}
+		if err := t.Notify.validate(); err != nil {
+			return fmt.Errorf("task %q: %v", t.Name, err)
+		}
+		if err := t.TaskHooks.validate(); err != nil {
+			return fmt.Errorf("task %q: %v", t.Name, err)
+		}
 		if t.Name == "" {
 			return fmt.Errorf("task name is required")
 		}
@@ -155,6 +284,21 @@ func (c Config) validate() error {
 	}
 
 	comboNames := map[string]struct{}{}
+	for _, cb := range c.Combos {
+		if _, ok := taskNames[cb.Name]; ok {
+			return fmt.Errorf("combo %q has the same name as a task", cb.Name)
+		}
+		comboNames[cb.Name] = struct{}{}
+	}
+	refNames := make(map[string]struct{}, len(taskNames)+len(comboNames))
+	for name := range taskNames {
+		refNames[name] = struct{}{}
+	}
+	for name := range comboNames {
+		refNames[name] = struct{}{}
+	}
+
+	seenCombos := map[string]struct{}{}
 	for _, cb := range c.Combos {
 		if cb.Key == "" {
 			return fmt.Errorf("combo %q is missing key", cb.Name)
@@ -171,26 +315,38 @@ func (c Config) validate() error {
 		if cb.Name == "" {
 			return fmt.Errorf("combo name is required")
 		}
-		if _, ok := comboNames[cb.Name]; ok {
+		if _, ok := seenCombos[cb.Name]; ok {
 			return fmt.Errorf("duplicate combo name %q", cb.Name)
 		}
-		comboNames[cb.Name] = struct{}{}
+		seenCombos[cb.Name] = struct{}{}
 		if prev, ok := keyUsed[cb.Key]; ok {
 			return fmt.Errorf("key %q already assigned to %s", cb.Key, prev)
 		}
 		keyUsed[cb.Key] = fmt.Sprintf("combo %q", cb.Name)
 
-		for _, name := range cb.Run {
-			if _, ok := taskNames[name]; !ok {
-				return fmt.Errorf("combo %q references unknown task %q", cb.Name, name)
+		for _, step := range cb.Run {
+			if step.Kind != StepTask {
+				return fmt.Errorf("combo %q can only run tasks", cb.Name)
 			}
+			if _, ok := refNames[step.Value]; !ok {
+				return fmt.Errorf("combo %q references unknown task %q", cb.Name, step.Value)
+			}
+		}
+		if err := validateStepConditions(cb.Run); err != nil {
+			return fmt.Errorf("combo %q %v", cb.Name, err)
 		}
 	}
 
 	for _, t := range c.Tasks {
-		if err := validateTaskStepRefs(t, taskNames); err != nil {
+		if err := validateTaskStepRefs(t, refNames); err != nil {
 			return err
 		}
+		if !t.Matrix.IsZero() {
+			_, base := matrixSteps(t)
+			if _, ok := refNames[base[0].Value]; ok || base[0].Kind == StepTask {
+				return fmt.Errorf("task %q: matrix steps must be commands, not task references", t.Name)
+			}
+		}
 	}
 
 	return nil
@@ -221,6 +377,67 @@ func validateTaskStepRefs(t TaskDef, taskNames map[string]struct{}) error {
 	return nil
 }
 
+func validateTaskConditions(t TaskDef) error {
+	if err := t.If.validate(); err != nil {
+		return fmt.Errorf("task %q: %v", t.Name, err)
+	}
+	for _, list := range []StepList{t.Cmd, t.Parallel, t.Seq} {
+		if err := validateStepConditions(list); err != nil {
+			return fmt.Errorf("task %q %v", t.Name, err)
+		}
+	}
+	return nil
+}
+
+func validateStepConditions(list StepList) error {
+	for _, step := range list {
+		if err := step.If.validate(); err != nil {
+			return fmt.Errorf("step %q: %v", stepDisplayName(step), err)
+		}
+	}
+	return nil
+}
+
+// migrateCombos turns each combo into an equivalent task so combos share the
+// task runner, sidebar entries, and controls. It is safe to call twice.
+func (c *Config) migrateCombos() {
+	existing := make(map[string]bool, len(c.Tasks))
+	for _, t := range c.Tasks {
+		existing[t.Name] = true
+	}
+	for _, cb := range c.Combos {
+		if existing[cb.Name] {
+			continue
+		}
+		c.Tasks = append(c.Tasks, comboTask(cb))
+		existing[cb.Name] = true
+	}
+}
+
+func comboTask(cb ComboDef) TaskDef {
+	def := TaskDef{
+		Name:  cb.Name,
+		Key:   cb.Key,
+		Combo: true,
+	}
+	steps := make(StepList, len(cb.Run))
+	copy(steps, cb.Run)
+	if cb.Mode == "parallel" {
+		def.Parallel = steps
+		def.FailFast = cb.FailFast != nil && *cb.FailFast
+		return def
+	}
+	def.Seq = steps
+	if cb.StopOnFail == nil && cb.FailFast != nil {
+		failFast := *cb.FailFast
+		def.StopOnFail = &failFast
+	} else {
+		stop := stopOnFail(cb)
+		def.StopOnFail = &stop
+	}
+	return def
+}
+
 func stopOnFail(cb ComboDef) bool {
 	if cb.StopOnFail == nil {
 		return true
@@ -228,6 +445,20 @@ func stopOnFail(cb ComboDef) bool {
 	return *cb.StopOnFail
 }
 
+func taskStopOnFail(def TaskDef) bool {
+	if def.StopOnFail == nil {
+		return true
+	}
+	return *def.StopOnFail
+}
+
+func retriggerPolicy(def TaskDef) string {
+	if def.OnRetrigger == "" {
+		return "ignore"
+	}
+	return def.OnRetrigger
+}
+
 func normalizeCommandList(list CommandList) CommandList {
 	if len(list) == 0 {
 		return nil
@@ -102,21 +102,21 @@ func TestConfigValidateErrors(t *testing.T) {
 			name: "combo unknown task",
 			cfg: Config{
 				Tasks:  []TaskDef{{Name: "a", Key: "a", Cmd: StepList{{Value: "echo", Kind: StepCommand}}}},
-				Combos: []ComboDef{{Name: "c", Key: "c", Mode: "sequential", Run: []string{"missing"}}},
+				Combos: []ComboDef{{Name: "c", Key: "c", Mode: "sequential", Run: StepList{{Value: "missing", Kind: StepTask}}}},
 			},
 		},
 		{
 			name: "combo invalid mode",
 			cfg: Config{
 				Tasks:  []TaskDef{{Name: "a", Key: "a", Cmd: StepList{{Value: "echo", Kind: StepCommand}}}},
-				Combos: []ComboDef{{Name: "c", Key: "c", Mode: "weird", Run: []string{"a"}}},
+				Combos: []ComboDef{{Name: "c", Key: "c", Mode: "weird", Run: StepList{{Value: "a", Kind: StepTask}}}},
 			},
 		},
 		{
 			name: "combo key length",
 			cfg: Config{
 				Tasks:  []TaskDef{{Name: "a", Key: "a", Cmd: StepList{{Value: "echo", Kind: StepCommand}}}},
-				Combos: []ComboDef{{Name: "c", Key: "cc", Mode: "sequential", Run: []string{"a"}}},
+				Combos: []ComboDef{{Name: "c", Key: "cc", Mode: "sequential", Run: StepList{{Value: "a", Kind: StepTask}}}},
 			},
 		},
 		{
@@ -130,7 +130,7 @@ func TestConfigValidateErrors(t *testing.T) {
 			name: "combo duplicate name",
 			cfg: Config{
 				Tasks:  []TaskDef{{Name: "a", Key: "a", Cmd: StepList{{Value: "echo", Kind: StepCommand}}}},
-				Combos: []ComboDef{{Name: "c", Key: "c", Mode: "sequential", Run: []string{"a"}}, {Name: "c", Key: "d", Mode: "sequential", Run: []string{"a"}}},
+				Combos: []ComboDef{{Name: "c", Key: "c", Mode: "sequential", Run: StepList{{Value: "a", Kind: StepTask}}}, {Name: "c", Key: "d", Mode: "sequential", Run: StepList{{Value: "a", Kind: StepTask}}}},
 			},
 		},
 		{
@@ -141,6 +141,65 @@ func TestConfigValidateErrors(t *testing.T) {
 			name: "both seq and parallel",
 			cfg:  Config{Tasks: []TaskDef{{Name: "a", Key: "a", Seq: StepList{{Value: "b", Kind: StepAuto}}, Parallel: StepList{{Value: "echo", Kind: StepCommand}}}}},
 		},
+		{
+			name: "stop_on_fail with parallel",
+			cfg:  Config{Tasks: []TaskDef{{Name: "a", StopOnFail: new(bool), Parallel: StepList{{Value: "echo", Kind: StepCommand}}}}},
+		},
+		{
+			name: "invalid on_retrigger",
+			cfg:  Config{Tasks: []TaskDef{{Name: "a", OnRetrigger: "later", Cmd: StepList{{Value: "echo", Kind: StepCommand}}}}},
+		},
+		{
+			name: "interactive parallel",
+			cfg:  Config{Tasks: []TaskDef{{Name: "a", Interactive: true, Parallel: StepList{{Value: "echo", Kind: StepCommand}}}}},
+		},
+		{
+			name: "unknown action signal",
+			cfg:  Config{Tasks: []TaskDef{{Name: "a", Actions: map[string]string{"reload": "SIGRELOAD"}, Cmd: StepList{{Value: "echo", Kind: StepCommand}}}}},
+		},
+		{
+			name: "invalid max_memory",
+			cfg:  Config{Tasks: []TaskDef{{Name: "a", MaxMemory: "lots", Cmd: StepList{{Value: "echo", Kind: StepCommand}}}}},
+		},
+		{
+			name: "matrix with several steps",
+			cfg: Config{Tasks: []TaskDef{{
+				Name:   "a",
+				Matrix: Matrix{Keys: []string{"db"}, Values: map[string][]string{"db": {"pg"}}},
+				Seq:    StepList{{Value: "echo", Kind: StepCommand}, {Value: "echo", Kind: StepCommand}},
+			}}},
+		},
+		{
+			name: "matrix empty values",
+			cfg: Config{Tasks: []TaskDef{{
+				Name:   "a",
+				Matrix: Matrix{Keys: []string{"db"}, Values: map[string][]string{"db": nil}},
+				Cmd:    StepList{{Value: "echo", Kind: StepCommand}},
+			}}},
+		},
+		{
+			name: "matrix keys with the same env name",
+			cfg: Config{Tasks: []TaskDef{{
+				Name:   "a",
+				Matrix: Matrix{Keys: []string{"node-version", "node_version"}, Values: map[string][]string{"node-version": {"20"}, "node_version": {"22"}}},
+				Cmd:    StepList{{Value: "echo", Kind: StepCommand}},
+			}}},
+		},
+		{
+			name: "matrix key with a dot",
+			cfg: Config{Tasks: []TaskDef{{
+				Name:   "a",
+				Matrix: Matrix{Keys: []string{"db.name"}, Values: map[string][]string{"db.name": {"pg"}}},
+				Cmd:    StepList{{Value: "echo", Kind: StepCommand}},
+			}}},
+		},
+		{
+			name: "matrix task reference",
+			cfg: Config{Tasks: []TaskDef{
+				{Name: "a", Matrix: Matrix{Keys: []string{"db"}, Values: map[string][]string{"db": {"pg"}}}, Cmd: StepList{{Value: "b", Kind: StepAuto}}},
+				{Name: "b", Cmd: StepList{{Value: "echo", Kind: StepCommand}}},
+			}},
+		},
 		{
 			name: "task step unknown",
 			cfg:  Config{Tasks: []TaskDef{{Name: "a", Key: "a", Cmd: StepList{{Value: "missing", Kind: StepTask}}}}},
@@ -299,3 +358,73 @@ func TestThemeValidation(t *testing.T) {
 		t.Fatalf("expected invalid theme error")
 	}
 }
+
+func TestCombosMigrateToTasks(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "tasks.yml")
+
+	data := `tasks:
+  - name: lint
+    cmd: bin/lint
+  - name: test
+    cmd: bin/test
+
+combos:
+  - name: checks
+    key: c
+    mode: parallel
+    fail_fast: true
+    run: [lint, test]
+  - name: ship
+    key: s
+    stop_on_fail: false
+    run:
+      - checks
+      - task: test
+        allow_failure: true
+`
+
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+	if len(cfg.Tasks) != 4 {
+		t.Fatalf("expected combos appended as tasks, got %d tasks", len(cfg.Tasks))
+	}
+
+	checks := cfg.Tasks[2]
+	if !checks.Combo || checks.Key != "c" || len(checks.Parallel) != 2 || !checks.FailFast {
+		t.Fatalf("unexpected parallel combo task: %#v", checks)
+	}
+	if checks.Parallel[0].Kind != StepTask {
+		t.Fatalf("expected combo entries to be task references")
+	}
+
+	ship := cfg.Tasks[3]
+	if len(ship.Seq) != 2 || ship.Seq[0].Value != "checks" || !ship.Seq[1].AllowFailure {
+		t.Fatalf("unexpected sequential combo task: %#v", ship)
+	}
+	if taskStopOnFail(ship) {
+		t.Fatalf("expected stop_on_fail false to carry over")
+	}
+
+	cfg.migrateCombos()
+	if len(cfg.Tasks) != 4 {
+		t.Fatalf("expected migration to be idempotent, got %d tasks", len(cfg.Tasks))
+	}
+}
+
+func TestComboNameClashesWithTask(t *testing.T) {
+	cfg := Config{
+		Tasks:  []TaskDef{{Name: "a", Cmd: StepList{{Value: "echo", Kind: StepCommand}}}},
+		Combos: []ComboDef{{Name: "a", Key: "c", Run: StepList{{Value: "a", Kind: StepTask}}}},
+	}
+	cfg.normalize("tasks.yml")
+	if err := cfg.validate(); err == nil {
+		t.Fatalf("expected name clash error")
+	}
+}
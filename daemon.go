@@ -0,0 +1,628 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// daemonEnv marks the re-executed child that runs the daemon.
+const daemonEnv = "SUITE_DAEMON"
+
+// daemonRequest is sent by attached UIs and `suite stop`. Steps are named by
+// ID; the daemon looks the command up in its own config, so clients can't
+// make it run anything else.
+type daemonRequest struct {
+	Op      string `json:"op"` // start | step | kill | signal | input | close_input | pause | stop
+	Target  string `json:"target,omitempty"`
+	Command string `json:"command,omitempty"` // signal name or input text
+}
+
+// daemonEvent is one runner message as sent to attached UIs: an --events
+// line plus the run source (top-level task name or step ID) it belongs to.
+type daemonEvent struct {
+	Source string `json:"source"`
+	event
+}
+
+// daemonRequestMsg delivers a client request to the daemon's model.
+type daemonRequestMsg daemonRequest
+
+// remoteStreamMsg is a runner message received from the daemon.
+type remoteStreamMsg struct {
+	Source string
+	Run    int
+	Time   time.Time
+	Msg    tea.Msg
+}
+
+// remoteClosedMsg reports that the connection to the daemon ended: the
+// daemon went away, or it detached this client for falling behind.
+type remoteClosedMsg struct {
+	Detached bool
+}
+
+// detachedEvent is the last event a client that fell behind gets before
+// the daemon closes its connection.
+const detachedEvent = "detached"
+
+func daemonSocketPath(configPath string) (string, error) {
+	return sessionPath(configPath, ".sock")
+}
+
+// dialSocket connects to the daemon socket at path, but only to a socket of
+// the current user's: attach forwards typed input, passwords included.
+func dialSocket(path string) (net.Conn, error) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !userPrivate(info) {
+		return nil, fmt.Errorf("%s is not private to you; not connecting", path)
+	}
+	return net.Dial("unix", path)
+}
+
+func daemonLogPath(socketPath string) string {
+	return socketPath[:len(socketPath)-len(filepath.Ext(socketPath))] + ".log"
+}
+
+// startDaemon re-executes suite with args in the background and waits until
+// its socket accepts connections.
+func startDaemon(configPath string, args []string) int {
+	socketPath, err := daemonSocketPath(configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "daemon error: %v\n", err)
+		return 1
+	}
+	if conn, err := dialSocket(socketPath); err == nil {
+		conn.Close()
+		fmt.Fprintln(os.Stderr, "suite daemon is already running; use suite attach")
+		return 1
+	}
+	offerLeftoverCleanup(configPath)
+	exe, err := os.Executable()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "daemon error: %v\n", err)
+		return 1
+	}
+	logPath := daemonLogPath(socketPath)
+	logFile, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "daemon error: %v\n", err)
+		return 1
+	}
+	defer logFile.Close()
+
+	cmd := exec.Command(exe, args...)
+	cmd.Env = append(os.Environ(), daemonEnv+"=1")
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	detachCommand(cmd)
+	if err := cmd.Start(); err != nil {
+		fmt.Fprintf(os.Stderr, "daemon error: %v\n", err)
+		return 1
+	}
+	exited := make(chan struct{})
+	go func() {
+		_ = cmd.Wait()
+		close(exited)
+	}()
+
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		if conn, err := dialSocket(socketPath); err == nil {
+			conn.Close()
+			fmt.Fprintf(os.Stdout, "suite daemon started (pid %d). Attach with suite attach, stop with suite stop. Log: %s\n", cmd.Process.Pid, logPath)
+			return 0
+		}
+		select {
+		case <-exited:
+			fmt.Fprintf(os.Stderr, "suite daemon exited during startup; see %s\n", logPath)
+			return 1
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+	fmt.Fprintf(os.Stderr, "suite daemon did not start in time; see %s\n", logPath)
+	return 1
+}
+
+// runDaemon is the background side of --daemon: the usual model drives the
+// tasks without a renderer while clients attach over the socket.
+func runDaemon(cfg Config, configPath string) int {
+	socketPath, err := daemonSocketPath(configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "daemon error: %v\n", err)
+		return 1
+	}
+	if conn, err := dialSocket(socketPath); err == nil {
+		conn.Close()
+		fmt.Fprintln(os.Stderr, "suite daemon is already running")
+		return 1
+	}
+	_ = os.Remove(socketPath)
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "daemon error: %v\n", err)
+		return 1
+	}
+	_ = os.Chmod(socketPath, 0o600)
+	defer os.Remove(socketPath)
+	defer startProcessTracking(configPath)()
+	inputs = newInputTable()
+
+	if err := runSuiteHook("on_startup", cfg.OnStartup, cfg.Shell, cfg.Init); err != nil {
+		listener.Close()
+		fmt.Fprintf(os.Stderr, "hook error: %v\n", err)
+		return 1
+	}
+
+	server := newDaemonServer()
+	m := newModel(cfg)
+	m.daemon = server
+	p := tea.NewProgram(m, tea.WithInput(nil), tea.WithOutput(io.Discard), tea.WithoutRenderer(), tea.WithoutSignalHandler())
+	server.send = p.Send
+	go server.serve(listener)
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	signal.Ignore(syscall.SIGHUP)
+	go func() {
+		<-signals
+		p.Send(daemonRequestMsg{Op: "stop"})
+	}()
+
+	finalModel, err := p.Run()
+	listener.Close()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "run error: %v\n", err)
+	}
+	if final, ok := finalModel.(model); ok {
+		final.killAllTasks()
+		final.waitForTasks(10 * time.Second)
+	}
+	code := 0
+	if err := runSuiteHook("on_shutdown", cfg.OnShutdown, cfg.Shell, cfg.Init); err != nil {
+		fmt.Fprintf(os.Stderr, "hook error: %v\n", err)
+		code = 1
+	}
+	server.close()
+	return code
+}
+
+// handleDaemonRequest applies a client request inside the daemon's model.
+func (m *model) handleDaemonRequest(req daemonRequestMsg) tea.Cmd {
+	switch req.Op {
+	case "start":
+		return m.startTask(req.Target, true)
+	case "step":
+		if step, ok := m.stepEntry(req.Target); ok {
+			return m.startStepEntry(step)
+		}
+	case "kill":
+		if cancel := m.stepCancel[req.Target]; cancel != nil {
+			cancel()
+			return nil
+		}
+		if task := m.taskByName[req.Target]; task != nil && task.cancel != nil {
+			delete(m.queuePending, task.Def.Name)
+			task.cancel()
+		}
+	case "signal":
+		m.sendSignal(req.Target, req.Command)
+	case "input", "close_input":
+		target, ok := inputs.resolve(req.Target)
+		if !ok {
+			return nil
+		}
+		if req.Op == "close_input" {
+			_ = inputs.close(target)
+		} else {
+			_ = inputs.write(target, req.Command)
+		}
+	case "pause":
+		m.schedulePaused = !m.schedulePaused
+	case "stop":
+		return tea.Quit
+	}
+	return nil
+}
+
+// stepEntry looks up the command step with the given ID in the config.
+func (m *model) stepEntry(id string) (entry, bool) {
+	for _, task := range m.tasks {
+		mode, steps, multi := taskSteps(task.Def.Name, task.Def, m.resolveTask)
+		if !multi {
+			continue
+		}
+		for idx, step := range steps {
+			if stepID(task.Def.Name, mode, idx) != id {
+				continue
+			}
+			kind, value := resolveStepKind(step, m.resolveTask)
+			if kind != StepCommand {
+				return entry{}, false
+			}
+			return entry{Kind: entryStep, Target: id, Label: stepDisplayName(step), Command: value, Env: step.Env}, true
+		}
+	}
+	return entry{}, false
+}
+
+// daemonServer fans runner messages out to attached clients and keeps the
+// latest run of every source so new clients can replay it.
+type daemonServer struct {
+	mu      sync.Mutex
+	send    func(tea.Msg)
+	history map[string]*daemonHistory
+	clients map[*daemonConn]bool
+	now     func() time.Time
+}
+
+// daemonHistory is the replay of one source's latest run. Output is capped
+// at max_lines like an outputBuffer; other events are kept so a replay can
+// rebuild the run's steps and statuses.
+type daemonHistory struct {
+	events  []daemonEvent
+	outputs int
+}
+
+func (h *daemonHistory) add(ev daemonEvent) {
+	h.events = append(h.events, ev)
+	if ev.Type != "output" {
+		return
+	}
+	h.outputs++
+	limit := int(outputMaxLines.Load())
+	// Trim in batches so each event costs O(1) amortized.
+	if limit <= 0 || h.outputs <= limit+limit/4 {
+		return
+	}
+	drop := h.outputs - limit
+	kept := h.events[:0]
+	for _, e := range h.events {
+		if drop > 0 && e.Type == "output" {
+			drop--
+			continue
+		}
+		kept = append(kept, e)
+	}
+	clear(h.events[len(kept):])
+	h.events = kept
+	h.outputs = limit
+}
+
+type daemonConn struct {
+	conn    net.Conn
+	out     chan daemonEvent
+	dropped bool // set before out is closed
+}
+
+func newDaemonServer() *daemonServer {
+	return &daemonServer{
+		history: make(map[string]*daemonHistory),
+		clients: make(map[*daemonConn]bool),
+		now:     time.Now,
+	}
+}
+
+// publish records msg for source and forwards it to every client. A nil
+// server does nothing, like a nil eventLog.
+func (s *daemonServer) publish(source string, run int, msg tea.Msg) {
+	if s == nil {
+		return
+	}
+	ev, ok := eventFor(msg)
+	if !ok {
+		return
+	}
+	ev.Time = s.now().UTC().Format(time.RFC3339Nano)
+	ev.Run = run
+	dev := daemonEvent{Source: source, event: ev}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// Usage samples are only interesting live; replaying them would grow
+	// the history of long-running tasks without end.
+	if ev.Type != "usage" {
+		history := s.history[source]
+		if history == nil || history.events[0].Run != run {
+			history = &daemonHistory{}
+			s.history[source] = history
+		}
+		history.add(dev)
+	}
+	for client := range s.clients {
+		select {
+		case client.out <- dev:
+		default:
+			// A client that can't keep up is dropped rather than stalling
+			// the tasks; it is told so and can attach again.
+			delete(s.clients, client)
+			client.dropped = true
+			close(client.out)
+		}
+	}
+}
+
+func (s *daemonServer) serve(listener net.Listener) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *daemonServer) handle(conn net.Conn) {
+	client := &daemonConn{conn: conn, out: make(chan daemonEvent, 4096)}
+	replay := s.attach(client)
+	go func() {
+		// Closing conn ends the read loop below, which detaches client.
+		defer conn.Close()
+		enc := json.NewEncoder(conn)
+		for _, ev := range replay {
+			if err := enc.Encode(ev); err != nil {
+				return
+			}
+		}
+		for ev := range client.out {
+			if err := enc.Encode(ev); err != nil {
+				return
+			}
+		}
+		if client.dropped {
+			_ = enc.Encode(daemonEvent{event: event{Type: detachedEvent}})
+		}
+	}()
+
+	dec := json.NewDecoder(bufio.NewReader(conn))
+	for {
+		var req daemonRequest
+		if err := dec.Decode(&req); err != nil {
+			break
+		}
+		if s.send != nil {
+			s.send(daemonRequestMsg(req))
+		}
+	}
+	s.detach(client)
+}
+
+// attach subscribes client and returns a copy of the recorded runs, oldest
+// first, for the client's writer to send before live events. Nothing is
+// written under the lock, so a slow client can't hold up publish.
+func (s *daemonServer) attach(client *daemonConn) []daemonEvent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sources := make([]string, 0, len(s.history))
+	total := 0
+	for source, history := range s.history {
+		sources = append(sources, source)
+		total += len(history.events)
+	}
+	sort.Slice(sources, func(i, j int) bool {
+		return s.history[sources[i]].events[0].Run < s.history[sources[j]].events[0].Run
+	})
+	replay := make([]daemonEvent, 0, total)
+	for _, source := range sources {
+		replay = append(replay, s.history[source].events...)
+	}
+	s.clients[client] = true
+	return replay
+}
+
+func (s *daemonServer) detach(client *daemonConn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.clients[client] {
+		delete(s.clients, client)
+		close(client.out)
+	}
+}
+
+func (s *daemonServer) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for client := range s.clients {
+		delete(s.clients, client)
+		close(client.out)
+	}
+}
+
+// daemonClient is an attached UI's connection. Received messages are read
+// from msgs; requests go out through send.
+type daemonClient struct {
+	mu   sync.Mutex
+	conn net.Conn
+	enc  *json.Encoder
+	msgs chan tea.Msg
+}
+
+func dialDaemon(configPath string) (*daemonClient, error) {
+	socketPath, err := daemonSocketPath(configPath)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := dialSocket(socketPath)
+	if errors.Is(err, os.ErrNotExist) || errors.Is(err, syscall.ECONNREFUSED) {
+		return nil, fmt.Errorf("no suite daemon running for %s (start one with suite --daemon)", configPath)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return newDaemonClient(conn), nil
+}
+
+func newDaemonClient(conn net.Conn) *daemonClient {
+	c := &daemonClient{conn: conn, enc: json.NewEncoder(conn), msgs: make(chan tea.Msg, 256)}
+	go c.read()
+	return c
+}
+
+func (c *daemonClient) read() {
+	defer close(c.msgs)
+	dec := json.NewDecoder(bufio.NewReader(c.conn))
+	for {
+		var ev daemonEvent
+		if err := dec.Decode(&ev); err != nil {
+			c.msgs <- remoteClosedMsg{}
+			return
+		}
+		if ev.Type == detachedEvent {
+			c.msgs <- remoteClosedMsg{Detached: true}
+			return
+		}
+		msg, ok := eventMsg(ev.event)
+		if !ok {
+			continue
+		}
+		at, _ := time.Parse(time.RFC3339Nano, ev.Time)
+		c.msgs <- remoteStreamMsg{Source: ev.Source, Run: ev.Run, Time: at, Msg: msg}
+	}
+}
+
+func (c *daemonClient) send(req daemonRequest) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_ = c.enc.Encode(req)
+}
+
+func (c *daemonClient) Close() error {
+	return c.conn.Close()
+}
+
+// remoteBatchMsg is a frame's worth of messages from the daemon.
+type remoteBatchMsg []tea.Msg
+
+func listenRemote(ch <-chan tea.Msg) tea.Cmd {
+	return func() tea.Msg {
+		msgs := collectFrame(ch)
+		if msgs == nil {
+			return nil
+		}
+		return remoteBatchMsg(msgs)
+	}
+}
+
+// handleRemoteStream applies a message from the daemon. The first message of
+// a run sets up the run the way startTask/startStepEntry would locally, with
+// a cancel that asks the daemon to kill it.
+func (m *model) handleRemoteStream(msg remoteStreamMsg) []tea.Cmd {
+	if m.runIDs[msg.Source] != msg.Run {
+		m.runIDs[msg.Source] = msg.Run
+		source, remote := msg.Source, m.remote
+		kill := func() { remote.send(daemonRequest{Op: "kill", Target: source}) }
+		if _, ok := stepTaskFromID(source); ok {
+			step := m.stepByID[source]
+			if step == nil {
+				step = &StepRun{ID: source, Label: m.entryLabel(source), Status: StatusIdle}
+				m.stepByID[source] = step
+			}
+			m.stepCancel[source] = kill
+		} else if task := m.taskByName[source]; task != nil {
+			task.cancel = kill
+			if _, ok := msg.Msg.(TaskStartedMsg); ok {
+				m.handleTaskStarted(source)
+				task.StartedAt = msg.Time
+			}
+		}
+	}
+	return m.handleStreamMsg(taskStreamMsg{Source: msg.Source, Msg: msg.Msg})
+}
+
+// entryLabel finds the sidebar label for target, falling back to target.
+func (m *model) entryLabel(target string) string {
+	for _, entry := range m.entries {
+		if entry.Target == target {
+			return entry.Label
+		}
+	}
+	return target
+}
+
+// runAttachCommand opens the UI on a running daemon. Quitting detaches and
+// leaves the tasks running.
+func runAttachCommand(args []string) int {
+	fs, configPath := commandFlags("attach")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	cfg, err := LoadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "config error: %v\n", err)
+		return 1
+	}
+	applyTheme(cfg.Theme)
+	client, err := dialDaemon(*configPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer client.Close()
+
+	m := newModel(cfg)
+	m.remote = client
+	p := tea.NewProgram(m, tea.WithAltScreen(), tea.WithMouseCellMotion())
+	finalModel, err := p.Run()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "run error: %v\n", err)
+		return 1
+	}
+	if final, ok := finalModel.(model); ok && final.remoteClosed != nil {
+		if final.remoteClosed.Detached {
+			fmt.Fprintln(os.Stderr, "detached: this UI fell too far behind the daemon's output; its tasks are still running, run suite attach again")
+			return 1
+		}
+		fmt.Fprintln(os.Stderr, "suite daemon stopped")
+	}
+	return 0
+}
+
+// runStopCommand asks the daemon to stop its tasks and waits until it has.
+func runStopCommand(args []string) int {
+	fs, configPath := commandFlags("stop")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	client, err := dialDaemon(*configPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer client.Close()
+	client.send(daemonRequest{Op: "stop"})
+	timeout := time.After(30 * time.Second)
+	for {
+		select {
+		case msg, ok := <-client.msgs:
+			if !ok {
+				return 0
+			}
+			if closed, ok := msg.(remoteClosedMsg); ok && !closed.Detached {
+				fmt.Fprintln(os.Stdout, "suite daemon stopped")
+				return 0
+			} else if ok {
+				fmt.Fprintln(os.Stderr, "lost the connection to the suite daemon while it was stopping")
+				return 1
+			}
+		case <-timeout:
+			fmt.Fprintln(os.Stderr, "suite daemon did not stop in time")
+			return 1
+		}
+	}
+}
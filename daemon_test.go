@@ -0,0 +1,249 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestEventMsgRoundTrip(t *testing.T) {
+	msgs := []tea.Msg{
+		TaskStartedMsg{TaskName: "full"},
+		TaskOutputMsg{Target: "full", Line: "hi"},
+		TaskOutputMsg{Target: "full::seq::1", Line: ""},
+		StepStartedMsg{StepID: "full::seq::1"},
+		StepFinishedMsg{StepID: "full::seq::1", ExitCode: 2, Err: errors.New("exit status 2")},
+		StepFinishedMsg{StepID: "full::seq::2", Skipped: true},
+		TaskFinishedMsg{TaskID: "full", Warned: true},
+		TaskFinishedMsg{TaskID: "full", ExitCode: -1, Err: errors.New("signal: killed"), Canceled: true},
+		JobSlotMsg{Target: "full::par::0", Waiting: true},
+		TaskUsageMsg{TaskID: "full", CPU: 12.5, RSS: 4096},
+	}
+	for _, msg := range msgs {
+		ev, ok := eventFor(msg)
+		if !ok {
+			t.Fatalf("no event for %#v", msg)
+		}
+		got, ok := eventMsg(ev)
+		if !ok || !reflect.DeepEqual(got, msg) {
+			t.Fatalf("round trip of %#v gave %#v", msg, got)
+		}
+	}
+}
+
+func TestDaemonServerReplaysAndForwards(t *testing.T) {
+	socketPath := filepath.Join(t.TempDir(), "d.sock")
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Skipf("unix sockets unavailable: %v", err)
+	}
+	defer listener.Close()
+
+	requests := make(chan tea.Msg, 1)
+	server := newDaemonServer()
+	server.send = func(msg tea.Msg) { requests <- msg }
+	defer server.close()
+	go server.serve(listener)
+
+	server.publish("web", 1, TaskStartedMsg{TaskName: "web"})
+	server.publish("test", 2, TaskStartedMsg{TaskName: "test"})
+	server.publish("test", 2, TaskFinishedMsg{TaskID: "test"})
+	server.publish("web", 1, TaskOutputMsg{Target: "web", Line: "listening"})
+	// A new run of test replaces the recorded one.
+	server.publish("test", 3, TaskStartedMsg{TaskName: "test"})
+
+	conn, err := net.Dial("unix", socketPath)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	client := newDaemonClient(conn)
+	defer client.Close()
+
+	var got []string
+	for len(got) < 3 {
+		msg := (<-client.msgs).(remoteStreamMsg)
+		ev, _ := eventFor(msg.Msg)
+		got = append(got, msg.Source+":"+ev.Type)
+		if msg.Source == "test" && msg.Run != 3 {
+			t.Fatalf("expected only the latest test run, got run %d", msg.Run)
+		}
+	}
+	want := []string{"web:task_started", "web:output", "test:task_started"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected replay %v, got %v", want, got)
+	}
+
+	// Wait until the client is subscribed before publishing live.
+	deadline := time.Now().Add(time.Second)
+	for {
+		server.mu.Lock()
+		n := len(server.clients)
+		server.mu.Unlock()
+		if n == 1 || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	server.publish("test", 3, TaskOutputMsg{Target: "test", Line: "ok"})
+	if msg := (<-client.msgs).(remoteStreamMsg); msg.Msg != (TaskOutputMsg{Target: "test", Line: "ok"}) {
+		t.Fatalf("expected live output, got %#v", msg)
+	}
+
+	client.send(daemonRequest{Op: "start", Target: "lint"})
+	select {
+	case msg := <-requests:
+		if req := msg.(daemonRequestMsg); req.Op != "start" || req.Target != "lint" {
+			t.Fatalf("unexpected request %#v", req)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected request to reach the daemon")
+	}
+}
+
+func TestDaemonTellsSlowClientItWasDetached(t *testing.T) {
+	socketPath := filepath.Join(t.TempDir(), "d.sock")
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Skipf("unix sockets unavailable: %v", err)
+	}
+	defer listener.Close()
+
+	server := newDaemonServer()
+	defer server.close()
+	go server.serve(listener)
+
+	conn, err := net.Dial("unix", socketPath)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	client := newDaemonClient(conn)
+	defer client.Close()
+	deadline := time.Now().Add(time.Second)
+	for {
+		server.mu.Lock()
+		n := len(server.clients)
+		server.mu.Unlock()
+		if n == 1 || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// Nothing reads client.msgs yet, so the connection backs up.
+	line := strings.Repeat("x", 200)
+	for i := 0; i < 50000; i++ {
+		server.publish("web", 1, TaskOutputMsg{Target: "web", Line: line})
+	}
+	var last tea.Msg
+	for msg := range client.msgs {
+		last = msg
+	}
+	if closed, ok := last.(remoteClosedMsg); !ok || !closed.Detached {
+		t.Fatalf("expected the client to learn it was detached, got %#v", last)
+	}
+}
+
+func TestDaemonHistoryCapsOutput(t *testing.T) {
+	setOutputLimit(4)
+	t.Cleanup(func() { setOutputLimit(0) })
+
+	server := newDaemonServer()
+	server.publish("web", 1, TaskStartedMsg{TaskName: "web"})
+	for i := 0; i < 20; i++ {
+		server.publish("web", 1, TaskOutputMsg{Target: "web", Line: fmt.Sprint(i)})
+	}
+
+	// attach only copies; it must not write to the connection.
+	replay := server.attach(&daemonConn{out: make(chan daemonEvent, 1)})
+	if replay[0].Type != "task_started" {
+		t.Fatalf("expected the run's start to be kept, got %+v", replay[0])
+	}
+	outputs := replay[1:]
+	if len(outputs) < 4 || len(outputs) > 5 || *outputs[len(outputs)-1].Line != "19" {
+		t.Fatalf("expected the last few lines only, got %+v", outputs)
+	}
+}
+
+func TestDaemonStepRequestUsesConfig(t *testing.T) {
+	cfg := Config{
+		Shell: "/bin/sh",
+		Tasks: []TaskDef{
+			{Name: "full", Key: "f", Seq: StepList{{Value: "true", Kind: StepCommand}, {Value: "lint", Kind: StepTask}}},
+			{Name: "lint", Key: "l", Cmd: StepList{{Value: "true", Kind: StepCommand}}},
+		},
+	}
+	m := newModel(cfg)
+	t.Cleanup(m.killAllTasks)
+
+	if cmd := m.handleDaemonRequest(daemonRequestMsg{Op: "step", Target: "full::seq::1"}); cmd != nil {
+		t.Fatalf("expected a task step to be refused")
+	}
+	if cmd := m.handleDaemonRequest(daemonRequestMsg{Op: "step", Target: "nope::seq::0", Command: "rm -rf /"}); cmd != nil {
+		t.Fatalf("expected an unknown step to be refused")
+	}
+	if cmd := m.handleDaemonRequest(daemonRequestMsg{Op: "step", Target: "full::seq::0"}); cmd == nil || m.stepCancel["full::seq::0"] == nil {
+		t.Fatalf("expected the configured step to start")
+	}
+}
+
+func TestAttachedModelMirrorsDaemon(t *testing.T) {
+	cfg := Config{
+		Tasks: []TaskDef{
+			{Name: "web", Key: "w", Persistent: true, Cmd: StepList{{Value: "bin/server", Kind: StepCommand}}},
+		},
+		SidebarWidth: 32,
+	}
+	local, remote := net.Pipe()
+	defer local.Close()
+	defer remote.Close()
+
+	m := newModel(cfg)
+	m.remote = &daemonClient{conn: local, enc: json.NewEncoder(local), msgs: make(chan tea.Msg)}
+	requests := make(chan daemonRequest, 4)
+	go func() {
+		scanner := bufio.NewScanner(remote)
+		for scanner.Scan() {
+			var req daemonRequest
+			if json.Unmarshal(scanner.Bytes(), &req) == nil {
+				requests <- req
+			}
+		}
+	}()
+	readRequest := func() daemonRequest {
+		t.Helper()
+		select {
+		case req := <-requests:
+			return req
+		case <-time.After(time.Second):
+			t.Fatalf("expected a request")
+		}
+		return daemonRequest{}
+	}
+
+	m.triggerTask("web")
+	if req := readRequest(); req.Op != "start" || req.Target != "web" {
+		t.Fatalf("expected start request, got %#v", req)
+	}
+
+	m.handleRemoteStream(remoteStreamMsg{Source: "web", Run: 7, Msg: TaskStartedMsg{TaskName: "web"}})
+	m.handleRemoteStream(remoteStreamMsg{Source: "web", Run: 7, Msg: TaskOutputMsg{Target: "web", Line: "listening"}})
+	task := m.taskByName["web"]
+	if !task.Running || task.Output.Len() != 1 || m.runIDs["web"] != 7 {
+		t.Fatalf("expected running task with output, got %#v", task)
+	}
+
+	m.killAllTasks()
+	m.killSelectedTask()
+	if req := readRequest(); req.Op != "kill" || req.Target != "web" {
+		t.Fatalf("expected kill request after detach-safe killAll, got %#v", req)
+	}
+}
@@ -0,0 +1,508 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// detectedTask is a task found in another runner's config.
+type detectedTask struct {
+	Name       string
+	Key        string
+	Cmd        string
+	Persistent bool
+	Source     string // make, npm, procfile, just, bin, rake
+}
+
+// importer parses one kind of runner config.
+type importer struct {
+	source string
+	match  func(base string) bool
+	parse  func(path string) ([]detectedTask, error)
+}
+
+var importers = []importer{
+	{"make", func(base string) bool { return base == "Makefile" || base == "makefile" || base == "GNUmakefile" }, parseMakefile},
+	{"npm", func(base string) bool { return base == "package.json" }, parsePackageJSON},
+	{"procfile", func(base string) bool { return base == "Procfile" || strings.HasPrefix(base, "Procfile.") }, parseProcfile},
+	{"just", func(base string) bool { return strings.EqualFold(base, "justfile") || base == ".justfile" }, parseJustfile},
+	{"rake", func(base string) bool { return base == "Rakefile" || base == "rakefile" }, parseRakefile},
+	{"bin", func(base string) bool { return base == "bin" }, parseBinDir},
+}
+
+// detectOrder is the order init --detect looks for files in.
+var detectOrder = []string{"Procfile", "package.json", "Makefile", "GNUmakefile", "makefile", "justfile", "Justfile", ".justfile", "Rakefile", "bin"}
+
+func importTasksFrom(path string) ([]detectedTask, error) {
+	base := filepath.Base(path)
+	for _, imp := range importers {
+		if imp.match(base) {
+			return imp.parse(path)
+		}
+	}
+	return nil, fmt.Errorf("don't know how to import %s (supported: Makefile, package.json, Procfile, justfile, Rakefile, bin/)", path)
+}
+
+// detectTasks imports every supported file found in dir.
+func detectTasks(dir string) ([]detectedTask, error) {
+	var all []detectedTask
+	seen := map[string]bool{}
+	for _, name := range detectOrder {
+		path := filepath.Join(dir, name)
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		if real, err := filepath.EvalSymlinks(path); err == nil {
+			if seen[real] {
+				continue
+			}
+			seen[real] = true
+		}
+		if name == "bin" != info.IsDir() {
+			continue
+		}
+		tasks, err := importTasksFrom(path)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, tasks...)
+	}
+	return all, nil
+}
+
+var makeTargetPattern = regexp.MustCompile(`^([A-Za-z0-9][A-Za-z0-9_./-]*)\s*:([^=]|$)`)
+
+func parseMakefile(path string) ([]detectedTask, error) {
+	lines, err := readLines(path)
+	if err != nil {
+		return nil, err
+	}
+	var tasks []detectedTask
+	seen := map[string]bool{}
+	for _, line := range lines {
+		match := makeTargetPattern.FindStringSubmatch(line)
+		if match == nil || seen[match[1]] {
+			continue
+		}
+		seen[match[1]] = true
+		tasks = append(tasks, detectedTask{Name: match[1], Cmd: "make " + match[1], Source: "make"})
+	}
+	return tasks, nil
+}
+
+func parsePackageJSON(path string) ([]detectedTask, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var pkg struct {
+		Scripts json.RawMessage `json:"scripts"`
+	}
+	if err := json.Unmarshal(data, &pkg); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	names, err := orderedJSONKeys(pkg.Scripts)
+	if err != nil {
+		return nil, fmt.Errorf("%s scripts: %w", path, err)
+	}
+
+	runner := packageRunner(filepath.Dir(path))
+	has := map[string]bool{}
+	for _, name := range names {
+		has[name] = true
+	}
+	var tasks []detectedTask
+	for _, name := range names {
+		// npm runs pre/post scripts around their main script by itself.
+		if base, ok := strings.CutPrefix(name, "pre"); ok && has[base] {
+			continue
+		}
+		if base, ok := strings.CutPrefix(name, "post"); ok && has[base] {
+			continue
+		}
+		tasks = append(tasks, detectedTask{Name: name, Cmd: runner + " " + name, Source: "npm"})
+	}
+	return tasks, nil
+}
+
+// orderedJSONKeys returns an object's keys in file order.
+func orderedJSONKeys(raw json.RawMessage) ([]string, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	dec := json.NewDecoder(strings.NewReader(string(raw)))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return nil, errors.New("expected an object")
+	}
+	var keys []string
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, tok.(string))
+		var skip json.RawMessage
+		if err := dec.Decode(&skip); err != nil {
+			return nil, err
+		}
+	}
+	return keys, nil
+}
+
+// packageRunner picks the package manager from the lockfile next to
+// package.json.
+func packageRunner(dir string) string {
+	for _, candidate := range []struct{ lockfile, runner string }{
+		{"bun.lockb", "bun run"},
+		{"bun.lock", "bun run"},
+		{"pnpm-lock.yaml", "pnpm run"},
+		{"yarn.lock", "yarn run"},
+	} {
+		if _, err := os.Stat(filepath.Join(dir, candidate.lockfile)); err == nil {
+			return candidate.runner
+		}
+	}
+	return "npm run"
+}
+
+func parseProcfile(path string) ([]detectedTask, error) {
+	lines, err := readLines(path)
+	if err != nil {
+		return nil, err
+	}
+	var tasks []detectedTask
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, cmd, ok := strings.Cut(line, ":")
+		if !ok || strings.TrimSpace(cmd) == "" {
+			continue
+		}
+		tasks = append(tasks, detectedTask{Name: strings.TrimSpace(name), Cmd: strings.TrimSpace(cmd), Persistent: true, Source: "procfile"})
+	}
+	return tasks, nil
+}
+
+var justRecipePattern = regexp.MustCompile(`^@?([A-Za-z_][A-Za-z0-9_-]*)[^:=]*:([^=]|$)`)
+
+func parseJustfile(path string) ([]detectedTask, error) {
+	lines, err := readLines(path)
+	if err != nil {
+		return nil, err
+	}
+	var tasks []detectedTask
+	for _, line := range lines {
+		if strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t") || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) > 0 && (fields[0] == "alias" || fields[0] == "set" || fields[0] == "export" || fields[0] == "import" || fields[0] == "mod") {
+			continue
+		}
+		match := justRecipePattern.FindStringSubmatch(line)
+		if match == nil || strings.HasPrefix(match[1], "_") {
+			continue
+		}
+		tasks = append(tasks, detectedTask{Name: match[1], Cmd: "just " + match[1], Source: "just"})
+	}
+	return tasks, nil
+}
+
+var rakeTaskPattern = regexp.MustCompile(`^\s*task\s*\(?\s*(?::([A-Za-z0-9_]+)|["']([^"']+)["']|([A-Za-z0-9_]+):)`)
+
+func parseRakefile(path string) ([]detectedTask, error) {
+	lines, err := readLines(path)
+	if err != nil {
+		return nil, err
+	}
+	rake := "rake"
+	if _, err := os.Stat(filepath.Join(filepath.Dir(path), "Gemfile")); err == nil {
+		rake = "bundle exec rake"
+	}
+	var tasks []detectedTask
+	seen := map[string]bool{}
+	for _, line := range lines {
+		match := rakeTaskPattern.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		name := match[1] + match[2] + match[3]
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		tasks = append(tasks, detectedTask{Name: name, Cmd: rake + " " + name, Source: "rake"})
+	}
+	return tasks, nil
+}
+
+func parseBinDir(path string) ([]detectedTask, error) {
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	var tasks []detectedTask
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || !info.Mode().IsRegular() || info.Mode().Perm()&0o111 == 0 || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		tasks = append(tasks, detectedTask{Name: entry.Name(), Cmd: filepath.ToSlash(filepath.Join(path, entry.Name())), Source: "bin"})
+	}
+	return tasks, nil
+}
+
+func readLines(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var lines []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return lines, scanner.Err()
+}
+
+// assignNamesAndKeys renames tasks that clash with taken names (or each
+// other) to "<source>-<name>" and gives each task a free single-character
+// key, preferring letters from its name.
+func assignNamesAndKeys(tasks []detectedTask, takenNames, takenKeys map[string]bool) []detectedTask {
+	names := copySet(takenNames)
+	keys := copySet(takenKeys)
+	out := make([]detectedTask, 0, len(tasks))
+	for _, task := range tasks {
+		name := task.Name
+		if names[name] {
+			name = task.Source + "-" + task.Name
+		}
+		for i := 2; names[name]; i++ {
+			name = fmt.Sprintf("%s-%s-%d", task.Source, task.Name, i)
+		}
+		names[name] = true
+		task.Name = name
+		task.Key = freeKey(task.Name, keys)
+		if task.Key != "" {
+			keys[task.Key] = true
+		}
+		out = append(out, task)
+	}
+	return out
+}
+
+func freeKey(name string, taken map[string]bool) string {
+	for _, r := range strings.ToLower(name) {
+		key := string(r)
+		if (r >= 'a' && r <= 'z' || r >= '0' && r <= '9') && !taken[key] && builtinKeys[key] == "" {
+			return key
+		}
+	}
+	for _, r := range "abcdefghijklmnopqrstuvwxyz0123456789" {
+		if key := string(r); !taken[key] && builtinKeys[key] == "" {
+			return key
+		}
+	}
+	return ""
+}
+
+func copySet(in map[string]bool) map[string]bool {
+	out := make(map[string]bool, len(in))
+	for k, v := range in {
+		out[k] = v
+	}
+	return out
+}
+
+// renderDetectedTasks renders tasks as entries of a tasks: list, in the
+// same layout as the init template.
+func renderDetectedTasks(w io.Writer, tasks []detectedTask) {
+	for _, task := range tasks {
+		fmt.Fprintf(w, "  - name: %s\n", yamlScalar(task.Name))
+		if task.Key != "" {
+			fmt.Fprintf(w, "    key: %s\n", yamlScalar(task.Key))
+		}
+		if task.Persistent {
+			fmt.Fprintln(w, "    persistent: true")
+		}
+		fmt.Fprintf(w, "    cmd: %s\n\n", yamlScalar(task.Cmd))
+	}
+}
+
+func yamlScalar(value string) string {
+	out, err := yaml.Marshal(value)
+	text := strings.TrimSuffix(string(out), "\n")
+	if err != nil || strings.Contains(text, "\n") {
+		return strconv.Quote(value)
+	}
+	return text
+}
+
+func detectedConfigTemplate(title string, tasks []detectedTask) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "title: %q\nsidebar_width: 32\n\ntasks:\n", title)
+	renderDetectedTasks(&b, tasks)
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// runInitDetect writes a config built from the runners found in the current
+// directory. It writes nothing and returns 0 when none are found.
+func runInitDetect(path string) (int, error) {
+	if _, err := os.Stat(path); err == nil {
+		return 0, fmt.Errorf("config already exists at %s", path)
+	}
+	tasks, err := detectTasks(filepath.Dir(path))
+	if err != nil {
+		return 0, err
+	}
+	if len(tasks) == 0 {
+		return 0, nil
+	}
+	tasks = assignNamesAndKeys(tasks, nil, nil)
+	return len(tasks), os.WriteFile(path, []byte(detectedConfigTemplate(initTitle(), tasks)), 0o644)
+}
+
+// runImport adds the tasks from source to the config at path, creating it if
+// needed. Existing content is kept as is; new tasks go at the end of the
+// tasks list.
+func runImport(path, source string) (int, error) {
+	tasks, err := importTasksFrom(source)
+	if err != nil {
+		return 0, err
+	}
+	if len(tasks) == 0 {
+		return 0, fmt.Errorf("no tasks found in %s", source)
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		tasks = assignNamesAndKeys(tasks, nil, nil)
+		return len(tasks), os.WriteFile(path, []byte(detectedConfigTemplate(initTitle(), tasks)), 0o644)
+	}
+	if err != nil {
+		return 0, err
+	}
+	// Parse without validating so a config with no tasks yet (tasks: [])
+	// can be filled; the result is validated below.
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return 0, err
+	}
+	cfg.normalize(path)
+	names, keys := map[string]bool{}, map[string]bool{}
+	for _, def := range cfg.Tasks {
+		names[def.Name] = true
+		if def.Key != "" {
+			keys[def.Key] = true
+		}
+	}
+	for _, cb := range cfg.Combos {
+		names[cb.Name] = true
+		if cb.Key != "" {
+			keys[cb.Key] = true
+		}
+	}
+	tasks = assignNamesAndKeys(tasks, names, keys)
+
+	var rendered strings.Builder
+	renderDetectedTasks(&rendered, tasks)
+	updated, err := insertTasks(string(data), rendered.String())
+	if err != nil {
+		return 0, err
+	}
+	// The splice is textual, so make sure the result still loads before
+	// replacing a working config.
+	if _, err := parseConfig([]byte(updated), path); err != nil {
+		return 0, fmt.Errorf("imported config would not load: %w", err)
+	}
+	return len(tasks), os.WriteFile(path, []byte(updated), 0o644)
+}
+
+// insertTasks places rendered task entries at the end of the top-level
+// tasks: block, adding the block if the config has none. Entries are
+// indented like the existing ones.
+func insertTasks(config, rendered string) (string, error) {
+	lines := strings.SplitAfter(config, "\n")
+	start := -1
+	for i, line := range lines {
+		if strings.HasPrefix(line, "tasks:") {
+			start = i
+			break
+		}
+	}
+	if start < 0 {
+		if !strings.HasSuffix(config, "\n") && config != "" {
+			config += "\n"
+		}
+		return config + "\ntasks:\n" + strings.TrimSuffix(rendered, "\n"), nil
+	}
+	inline, _, _ := strings.Cut(strings.TrimPrefix(lines[start], "tasks:"), "#")
+	switch strings.TrimSpace(inline) {
+	case "":
+	case "[]":
+		lines[start] = "tasks:\n"
+	default:
+		return "", fmt.Errorf("can't add to an inline tasks list; write it as a block list first")
+	}
+	end := len(lines)
+	for i := start + 1; i < len(lines); i++ {
+		line := lines[i]
+		if strings.TrimSpace(line) != "" && !strings.HasPrefix(line, " ") && !strings.HasPrefix(line, "\t") && !strings.HasPrefix(line, "#") && !strings.HasPrefix(line, "-") {
+			end = i
+			break
+		}
+	}
+	// Keep trailing blank lines after the inserted tasks.
+	for end > start+1 && strings.TrimSpace(lines[end-1]) == "" {
+		end--
+	}
+	head := strings.Join(lines[:end], "")
+	if !strings.HasSuffix(head, "\n") {
+		head += "\n"
+	}
+	if strings.TrimSpace(lines[end-1]) != "tasks:" {
+		head += "\n"
+	}
+	rendered = reindentEntries(rendered, entryIndent(lines[start+1:end]))
+	return head + strings.TrimSuffix(rendered, "\n") + strings.Join(lines[end:], ""), nil
+}
+
+// entryIndent returns the indentation of the first list entry in lines,
+// or the two spaces renderDetectedTasks uses when there is none.
+func entryIndent(lines []string) string {
+	for _, line := range lines {
+		trimmed := strings.TrimLeft(line, " ")
+		if strings.HasPrefix(trimmed, "-") {
+			return line[:len(line)-len(trimmed)]
+		}
+	}
+	return "  "
+}
+
+// reindentEntries moves rendered entries from two spaces to indent.
+func reindentEntries(rendered, indent string) string {
+	if indent == "  " {
+		return rendered
+	}
+	lines := strings.SplitAfter(rendered, "\n")
+	for i, line := range lines {
+		if strings.TrimSpace(line) != "" {
+			lines[i] = indent + strings.TrimPrefix(line, "  ")
+		}
+	}
+	return strings.Join(lines, "")
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeTestFile(t *testing.T, dir, name, content string, mode os.FileMode) {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), mode); err != nil {
+		t.Fatalf("write %s: %v", name, err)
+	}
+}
+
+func detectedSummary(tasks []detectedTask) string {
+	parts := []string{}
+	for _, task := range tasks {
+		entry := task.Name + "=" + task.Cmd
+		if task.Key != "" {
+			entry = task.Key + ":" + entry
+		}
+		if task.Persistent {
+			entry += " (persistent)"
+		}
+		parts = append(parts, entry)
+	}
+	return strings.Join(parts, "\n")
+}
+
+func TestDetectTasks(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, dir, "Makefile", ".PHONY: test\ntest:\n\tgo test ./...\nbuild: deps\n\tgo build\nVAR := x\n%.o: %.c\n\tcc\n", 0o644)
+	writeTestFile(t, dir, "package.json", `{"scripts":{"dev":"vite","pretest":"lint","test":"vitest"}}`, 0o644)
+	writeTestFile(t, dir, "yarn.lock", "", 0o644)
+	writeTestFile(t, dir, "Procfile", "# dev\nweb: bin/rails s\n", 0o644)
+	writeTestFile(t, dir, "justfile", "set shell := [\"bash\"]\nalias t := test\n\ntest *args:\n  cargo test\n_private:\n  x\n", 0o644)
+	writeTestFile(t, dir, "Rakefile", "task :seed do\nend\ntask default: [:seed]\ntask \"db:reset\" do\nend\n", 0o644)
+	writeTestFile(t, dir, "Gemfile", "", 0o644)
+	writeTestFile(t, dir, "bin/setup", "#!/bin/sh\n", 0o755)
+	writeTestFile(t, dir, "bin/README", "", 0o644)
+
+	tasks, err := detectTasks(dir)
+	if err != nil {
+		t.Fatalf("detect: %v", err)
+	}
+	for i := range tasks {
+		tasks[i].Cmd = strings.TrimPrefix(tasks[i].Cmd, dir+"/")
+	}
+	tasks = assignNamesAndKeys(tasks, map[string]bool{"seed": true}, map[string]bool{"w": true})
+
+	want := strings.Join([]string{
+		"e:web=bin/rails s (persistent)",
+		"d:dev=yarn run dev",
+		"t:test=yarn run test",
+		"m:make-test=make test",
+		"b:build=make build",
+		"j:just-test=just test",
+		"r:rake-seed=bundle exec rake seed",
+		"f:default=bundle exec rake default",
+		"s:db:reset=bundle exec rake db:reset",
+		"u:setup=bin/setup",
+	}, "\n")
+	if got := detectedSummary(tasks); got != want {
+		t.Fatalf("unexpected tasks:\n%s", got)
+	}
+}
+
+func TestRunImportAppendsToTasks(t *testing.T) {
+	dir := t.TempDir()
+	config := "title: x\ntasks:\n  - name: web\n    key: w\n    cmd: bin/dev\n\ntheme: dark\n"
+	writeTestFile(t, dir, ".suite.yml", config, 0o644)
+	writeTestFile(t, dir, "Procfile", "web: bin/rails s\nworker: sidekiq\n", 0o644)
+
+	path := filepath.Join(dir, ".suite.yml")
+	count, err := runImport(path, filepath.Join(dir, "Procfile"))
+	if err != nil || count != 2 {
+		t.Fatalf("import: %d %v", count, err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	want := "title: x\ntasks:\n  - name: web\n    key: w\n    cmd: bin/dev\n\n" +
+		"  - name: procfile-web\n    key: p\n    persistent: true\n    cmd: bin/rails s\n\n" +
+		"  - name: worker\n    key: o\n    persistent: true\n    cmd: sidekiq\n\ntheme: dark\n"
+	if string(data) != want {
+		t.Fatalf("unexpected config:\n%s", data)
+	}
+	if _, err := LoadConfig(path); err != nil {
+		t.Fatalf("imported config should load: %v", err)
+	}
+}
+
+func TestRunImportListLayouts(t *testing.T) {
+	cases := []struct {
+		name   string
+		config string
+		want   string
+	}{
+		{
+			name:   "empty flow list",
+			config: "tasks: []\ntheme: dark\n",
+			want:   "tasks:\n  - name: worker\n    key: w\n    persistent: true\n    cmd: sidekiq\ntheme: dark\n",
+		},
+		{
+			name:   "zero indent",
+			config: "tasks:\n- name: web\n  key: w\n  cmd: bin/dev\n",
+			want:   "tasks:\n- name: web\n  key: w\n  cmd: bin/dev\n\n- name: worker\n  key: o\n  persistent: true\n  cmd: sidekiq\n",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeTestFile(t, dir, ".suite.yml", tc.config, 0o644)
+			writeTestFile(t, dir, "Procfile", "worker: sidekiq\n", 0o644)
+
+			path := filepath.Join(dir, ".suite.yml")
+			if _, err := runImport(path, filepath.Join(dir, "Procfile")); err != nil {
+				t.Fatalf("import: %v", err)
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("read: %v", err)
+			}
+			if string(data) != tc.want {
+				t.Fatalf("unexpected config:\n%s", data)
+			}
+		})
+	}
+}
+
+func TestRunImportAvoidsCombosAndBuiltinKeys(t *testing.T) {
+	dir := t.TempDir()
+	config := "tasks:\n  - name: api\n    key: a\n    cmd: bin/api\ncombos:\n  - name: worker\n    key: w\n    run: [api]\n"
+	writeTestFile(t, dir, ".suite.yml", config, 0o644)
+	writeTestFile(t, dir, "Procfile", "worker: sidekiq\nqueue: bin/queue\n", 0o644)
+
+	path := filepath.Join(dir, ".suite.yml")
+	if _, err := runImport(path, filepath.Join(dir, "Procfile")); err != nil {
+		t.Fatalf("import: %v", err)
+	}
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("imported config should load: %v", err)
+	}
+	keys := map[string]string{}
+	for _, def := range cfg.Tasks {
+		keys[def.Name] = def.Key
+	}
+	if keys["procfile-worker"] == "" || keys["queue"] != "u" {
+		t.Fatalf("unexpected tasks: %v", keys)
+	}
+}
+
+func TestRunImportKeepsConfigItCannotExtend(t *testing.T) {
+	dir := t.TempDir()
+	config := "tasks: [{name: api, key: a, cmd: bin/api}]\n"
+	writeTestFile(t, dir, ".suite.yml", config, 0o644)
+	writeTestFile(t, dir, "Procfile", "worker: sidekiq\n", 0o644)
+
+	path := filepath.Join(dir, ".suite.yml")
+	if _, err := runImport(path, filepath.Join(dir, "Procfile")); err == nil {
+		t.Fatalf("expected an error for an inline tasks list")
+	}
+	data, _ := os.ReadFile(path)
+	if string(data) != config {
+		t.Fatalf("expected config to be left alone, got:\n%s", data)
+	}
+}
+
+func TestImportUnknownFile(t *testing.T) {
+	if _, err := importTasksFrom("build.gradle"); err == nil {
+		t.Fatalf("expected error for unsupported file")
+	}
+}
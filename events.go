@@ -0,0 +1,201 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// eventLog writes runner messages as newline-delimited JSON. A nil log
+// discards everything, so callers don't need to check whether --events is set.
+type eventLog struct {
+	mu     sync.Mutex
+	enc    *json.Encoder
+	closer io.Closer
+	now    func() time.Time
+}
+
+type event struct {
+	Time     string   `json:"time"`
+	Type     string   `json:"type"`
+	Run      int      `json:"run"`
+	Task     string   `json:"task,omitempty"`
+	Step     string   `json:"step,omitempty"`
+	Line     *string  `json:"line,omitempty"`
+	Status   string   `json:"status,omitempty"`
+	ExitCode *int     `json:"exit_code,omitempty"`
+	Error    string   `json:"error,omitempty"`
+	Waiting  *bool    `json:"waiting,omitempty"`
+	CPU      *float64 `json:"cpu,omitempty"`
+	RSS      *uint64  `json:"rss,omitempty"`
+}
+
+// openEventLog opens path for appending; "-" writes to stdout.
+func openEventLog(path string) (*eventLog, error) {
+	if path == "-" {
+		return newEventLog(os.Stdout, nil), nil
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return newEventLog(file, file), nil
+}
+
+func newEventLog(w io.Writer, closer io.Closer) *eventLog {
+	return &eventLog{enc: json.NewEncoder(w), closer: closer, now: time.Now}
+}
+
+func (l *eventLog) Close() error {
+	if l == nil || l.closer == nil {
+		return nil
+	}
+	return l.closer.Close()
+}
+
+func (l *eventLog) write(run int, msg tea.Msg) {
+	if l == nil {
+		return
+	}
+	ev, ok := eventFor(msg)
+	if !ok {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	ev.Time = l.now().UTC().Format(time.RFC3339Nano)
+	ev.Run = run
+	_ = l.enc.Encode(ev)
+}
+
+func eventFor(msg tea.Msg) (event, bool) {
+	switch msg := msg.(type) {
+	case TaskStartedMsg:
+		return event{Type: "task_started", Task: msg.TaskName}, true
+	case TaskOutputMsg:
+		ev := eventTarget("output", msg.Target)
+		line := msg.Line
+		ev.Line = &line
+		return ev, true
+	case StepStartedMsg:
+		return eventTarget("step_started", msg.StepID), true
+	case StepFinishedMsg:
+		ev := eventTarget("step_finished", msg.StepID)
+		finishEvent(&ev, msg.ExitCode, msg.Err, msg.Canceled, msg.Skipped, msg.Warned)
+		return ev, true
+	case TaskFinishedMsg:
+		ev := event{Type: "task_finished", Task: msg.TaskID}
+		finishEvent(&ev, msg.ExitCode, msg.Err, msg.Canceled, msg.Skipped, msg.Warned)
+		return ev, true
+	case JobSlotMsg:
+		ev := eventTarget("job_slot", msg.Target)
+		waiting := msg.Waiting
+		ev.Waiting = &waiting
+		return ev, true
+	case TaskUsageMsg:
+		cpu, rss := msg.CPU, msg.RSS
+		return event{Type: "usage", Task: msg.TaskID, CPU: &cpu, RSS: &rss}, true
+	}
+	return event{}, false
+}
+
+// eventMsg turns an event back into the runner message it was written
+// from, for UIs attached to a daemon.
+func eventMsg(ev event) (tea.Msg, bool) {
+	target := ev.Task
+	if ev.Step != "" {
+		target = ev.Step
+	}
+	switch ev.Type {
+	case "task_started":
+		return TaskStartedMsg{TaskName: ev.Task}, true
+	case "output":
+		line := ""
+		if ev.Line != nil {
+			line = *ev.Line
+		}
+		return TaskOutputMsg{Target: target, Line: line}, true
+	case "step_started":
+		return StepStartedMsg{StepID: ev.Step}, true
+	case "step_finished":
+		return finishedMsg(ev), true
+	case "task_finished":
+		f := finishedMsg(ev)
+		return TaskFinishedMsg{TaskID: ev.Task, ExitCode: f.ExitCode, Err: f.Err, Canceled: f.Canceled, Skipped: f.Skipped, Warned: f.Warned}, true
+	case "job_slot":
+		return JobSlotMsg{Target: target, Waiting: ev.Waiting != nil && *ev.Waiting}, true
+	case "usage":
+		msg := TaskUsageMsg{TaskID: ev.Task}
+		if ev.CPU != nil {
+			msg.CPU = *ev.CPU
+		}
+		if ev.RSS != nil {
+			msg.RSS = *ev.RSS
+		}
+		return msg, true
+	}
+	return nil, false
+}
+
+// finishedMsg reverses finishEvent.
+func finishedMsg(ev event) StepFinishedMsg {
+	code := 0
+	if ev.ExitCode != nil {
+		code = *ev.ExitCode
+	}
+	var err error
+	if ev.Error != "" {
+		err = errors.New(ev.Error)
+	}
+	if ev.Status == "failed" && err == nil {
+		err = fmt.Errorf("exit status %d", code)
+	}
+	return StepFinishedMsg{
+		StepID:   ev.Step,
+		ExitCode: code,
+		Err:      err,
+		Canceled: ev.Status == "canceled",
+		Skipped:  ev.Status == "skipped",
+		Warned:   ev.Status == "warning",
+	}
+}
+
+// eventTarget fills task and step from an output target, which is either a
+// task name or a step ID.
+func eventTarget(kind, target string) event {
+	if taskName, ok := stepTaskFromID(target); ok {
+		return event{Type: kind, Task: taskName, Step: target}
+	}
+	return event{Type: kind, Task: target}
+}
+
+func finishEvent(ev *event, exitCode int, err error, canceled, skipped, warned bool) {
+	code := exitCode
+	ev.ExitCode = &code
+	ev.Status = statusKey(finishedStatus(err, canceled, skipped, warned))
+	if err != nil {
+		ev.Error = err.Error()
+	}
+}
+
+// finishedStatus maps the flags on a finished message to a TaskStatus.
+func finishedStatus(err error, canceled, skipped, warned bool) TaskStatus {
+	switch {
+	case skipped:
+		return StatusSkipped
+	case warned:
+		return StatusWarning
+	case canceled:
+		return StatusCanceled
+	case err != nil:
+		return StatusFailed
+	default:
+		return StatusSuccess
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEventLogWritesNDJSON(t *testing.T) {
+	var buf bytes.Buffer
+	log := newEventLog(&buf, nil)
+	log.now = func() time.Time { return time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC) }
+
+	log.write(1, TaskStartedMsg{TaskName: "test"})
+	log.write(1, TaskOutputMsg{Target: "test::seq::0", Line: ""})
+	log.write(1, StepFinishedMsg{StepID: "test::seq::0", ExitCode: 2, Err: errors.New("exit status 2")})
+	log.write(1, TaskFinishedMsg{TaskID: "test", Warned: true})
+	log.write(1, autostartMsg{})
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("expected 4 events, got %d: %q", len(lines), buf.String())
+	}
+
+	var ev map[string]any
+	if err := json.Unmarshal([]byte(lines[1]), &ev); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if ev["type"] != "output" || ev["task"] != "test" || ev["step"] != "test::seq::0" || ev["line"] != "" || ev["run"] != float64(1) {
+		t.Fatalf("unexpected output event: %v", ev)
+	}
+	if ev["time"] != "2026-01-02T03:04:05Z" {
+		t.Fatalf("unexpected time: %v", ev["time"])
+	}
+
+	ev = nil
+	if err := json.Unmarshal([]byte(lines[2]), &ev); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if ev["status"] != "failed" || ev["exit_code"] != float64(2) || ev["error"] != "exit status 2" {
+		t.Fatalf("unexpected step event: %v", ev)
+	}
+
+	ev = nil
+	if err := json.Unmarshal([]byte(lines[3]), &ev); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if ev["type"] != "task_finished" || ev["status"] != "warning" {
+		t.Fatalf("unexpected task event: %v", ev)
+	}
+}
+
+func TestNilEventLogDiscards(t *testing.T) {
+	var log *eventLog
+	log.write(1, TaskStartedMsg{TaskName: "test"})
+	if err := log.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+}
@@ -0,0 +1,291 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// taskGraph is the task/step structure reachable from a set of roots, with
+// task references resolved the same way the runner resolves them.
+type taskGraph struct {
+	nodes   []graphNode
+	index   map[string]int
+	edges   []graphEdge
+	repeats map[string]string // task name -> "runs 2× in full"
+}
+
+type graphNode struct {
+	ID      string
+	Label   string
+	Task    bool
+	Missing bool
+}
+
+type graphEdge struct {
+	From  string
+	To    string
+	Label string
+}
+
+func runGraphCommand(args []string, out io.Writer) int {
+	fs, configPath := commandFlags("graph")
+	format := fs.String("format", "tree", "output format: tree, dot, or mermaid")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	cfg, err := LoadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "config error: %v\n", err)
+		return 1
+	}
+	resolve := configResolver(cfg)
+
+	roots := fs.Args()
+	for _, name := range roots {
+		if _, ok := resolve(name); !ok {
+			fmt.Fprintf(os.Stderr, "unknown task %q\n", name)
+			return 2
+		}
+	}
+	if len(roots) == 0 {
+		for _, def := range cfg.Tasks {
+			if !def.Hidden {
+				roots = append(roots, def.Name)
+			}
+		}
+	}
+
+	switch *format {
+	case "tree":
+		for i, name := range roots {
+			if i > 0 {
+				fmt.Fprintln(out)
+			}
+			writeTaskTree(out, name, resolve)
+		}
+	case "dot":
+		buildTaskGraph(roots, resolve).writeDOT(out)
+	case "mermaid":
+		buildTaskGraph(roots, resolve).writeMermaid(out)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown format %q (use tree, dot, or mermaid)\n", *format)
+		return 2
+	}
+	return 0
+}
+
+func buildTaskGraph(roots []string, resolve TaskResolver) *taskGraph {
+	g := &taskGraph{index: map[string]int{}, repeats: map[string]string{}}
+	for _, root := range roots {
+		g.addTask(root, resolve, map[string]bool{})
+		counts := map[string]int{}
+		countTaskRuns(root, resolve, counts, map[string]bool{})
+		for name, count := range counts {
+			if count > 1 && g.repeats[name] == "" {
+				g.repeats[name] = fmt.Sprintf("runs %d× in %s", count, root)
+			}
+		}
+	}
+	return g
+}
+
+func graphTaskID(name string) string {
+	return "task:" + name
+}
+
+func (g *taskGraph) addNode(node graphNode) bool {
+	if _, ok := g.index[node.ID]; ok {
+		return false
+	}
+	g.index[node.ID] = len(g.nodes)
+	g.nodes = append(g.nodes, node)
+	return true
+}
+
+func (g *taskGraph) addTask(name string, resolve TaskResolver, stack map[string]bool) {
+	def, ok := resolve(name)
+	id := graphTaskID(name)
+	if !ok {
+		g.addNode(graphNode{ID: id, Label: name, Task: true, Missing: true})
+		return
+	}
+	mode, steps, multi := taskSteps(name, def, resolve)
+	label := fmt.Sprintf("%s (%s)", name, listModeName(mode, multi))
+	if !multi && len(steps) == 1 {
+		label = fmt.Sprintf("%s: %s", name, firstLine(stepDisplayName(steps[0])))
+	}
+	if !g.addNode(graphNode{ID: id, Label: label, Task: true}) || stack[name] || !multi {
+		return
+	}
+	stack[name] = true
+	defer delete(stack, name)
+
+	for idx, step := range steps {
+		edgeLabel := ""
+		if mode != StepModeParallel {
+			edgeLabel = fmt.Sprintf("%d", idx+1)
+		}
+		kind, value := resolveStepKind(step, resolve)
+		if kind == StepTask {
+			g.addTask(value, resolve, stack)
+			g.edges = append(g.edges, graphEdge{From: id, To: graphTaskID(value), Label: edgeLabel})
+			continue
+		}
+		stepNode := stepID(name, mode, idx)
+		g.addNode(graphNode{ID: stepNode, Label: firstLine(stepDisplayName(step))})
+		g.edges = append(g.edges, graphEdge{From: id, To: stepNode, Label: edgeLabel})
+	}
+}
+
+// countTaskRuns counts how often each task runs when name runs once.
+func countTaskRuns(name string, resolve TaskResolver, counts map[string]int, stack map[string]bool) {
+	counts[name]++
+	def, ok := resolve(name)
+	if !ok || stack[name] {
+		return
+	}
+	stack[name] = true
+	defer delete(stack, name)
+	_, steps, multi := taskSteps(name, def, resolve)
+	if !multi {
+		return
+	}
+	for _, step := range steps {
+		if kind, value := resolveStepKind(step, resolve); kind == StepTask {
+			countTaskRuns(value, resolve, counts, stack)
+		}
+	}
+}
+
+func (g *taskGraph) nodeLabel(node graphNode) string {
+	label := node.Label
+	if node.Missing {
+		label += " (unknown task)"
+	}
+	if note := g.repeats[strings.TrimPrefix(node.ID, "task:")]; node.Task && note != "" {
+		label += " [" + note + "]"
+	}
+	return label
+}
+
+func (g *taskGraph) writeDOT(w io.Writer) {
+	fmt.Fprintln(w, "digraph suite {")
+	fmt.Fprintln(w, "  rankdir=LR;")
+	fmt.Fprintln(w, "  node [shape=box, fontname=\"Helvetica\"];")
+	for _, node := range g.nodes {
+		attrs := []string{fmt.Sprintf("label=%s", dotQuote(g.nodeLabel(node)))}
+		if node.Task {
+			attrs = append(attrs, `style="rounded,bold"`)
+		} else {
+			attrs = append(attrs, "shape=note")
+		}
+		if node.Missing || g.repeats[strings.TrimPrefix(node.ID, "task:")] != "" && node.Task {
+			attrs = append(attrs, "color=red")
+		}
+		fmt.Fprintf(w, "  %s [%s];\n", dotQuote(node.ID), strings.Join(attrs, ", "))
+	}
+	for _, edge := range g.edges {
+		if edge.Label != "" {
+			fmt.Fprintf(w, "  %s -> %s [label=%s];\n", dotQuote(edge.From), dotQuote(edge.To), dotQuote(edge.Label))
+		} else {
+			fmt.Fprintf(w, "  %s -> %s [style=dashed];\n", dotQuote(edge.From), dotQuote(edge.To))
+		}
+	}
+	fmt.Fprintln(w, "}")
+}
+
+func dotQuote(text string) string {
+	text = strings.ReplaceAll(text, `\`, `\\`)
+	return `"` + strings.ReplaceAll(text, `"`, `\"`) + `"`
+}
+
+func (g *taskGraph) writeMermaid(w io.Writer) {
+	fmt.Fprintln(w, "flowchart LR")
+	ids := make(map[string]string, len(g.nodes))
+	for i, node := range g.nodes {
+		ids[node.ID] = fmt.Sprintf("n%d", i)
+		label := mermaidQuote(g.nodeLabel(node))
+		if node.Task {
+			fmt.Fprintf(w, "  %s([%s])\n", ids[node.ID], label)
+		} else {
+			fmt.Fprintf(w, "  %s[%s]\n", ids[node.ID], label)
+		}
+	}
+	for _, edge := range g.edges {
+		if edge.Label != "" {
+			fmt.Fprintf(w, "  %s -->|%s| %s\n", ids[edge.From], edge.Label, ids[edge.To])
+		} else {
+			fmt.Fprintf(w, "  %s -.-> %s\n", ids[edge.From], ids[edge.To])
+		}
+	}
+	repeated := make([]string, 0, len(g.repeats))
+	for name := range g.repeats {
+		if id, ok := ids[graphTaskID(name)]; ok {
+			repeated = append(repeated, id)
+		}
+	}
+	sort.Strings(repeated)
+	if len(repeated) > 0 {
+		fmt.Fprintln(w, "  classDef repeated stroke:#d33,stroke-width:2px")
+		fmt.Fprintf(w, "  class %s repeated\n", strings.Join(repeated, ","))
+	}
+}
+
+func mermaidQuote(text string) string {
+	return `"` + strings.ReplaceAll(text, `"`, "#quot;") + `"`
+}
+
+// writeTaskTree prints the full expansion of a task. Seq steps are numbered,
+// parallel steps are marked ∥, and tasks that run more than once are flagged.
+func writeTaskTree(w io.Writer, root string, resolve TaskResolver) {
+	counts := map[string]int{}
+	countTaskRuns(root, resolve, counts, map[string]bool{})
+	seen := map[string]int{}
+	var walk func(name, prefix, lead string, stack map[string]bool)
+	walk = func(name, prefix, lead string, stack map[string]bool) {
+		seen[name]++
+		def, ok := resolve(name)
+		if !ok {
+			fmt.Fprintf(w, "%s%s (unknown task)\n", lead, name)
+			return
+		}
+		mode, steps, multi := taskSteps(name, def, resolve)
+		line := fmt.Sprintf("%s (%s)", name, listModeName(mode, multi))
+		if !multi && len(steps) == 1 {
+			line = fmt.Sprintf("%s: %s", name, firstLine(stepDisplayName(steps[0])))
+		}
+		if counts[name] > 1 {
+			line += fmt.Sprintf(" [run %d of %d]", seen[name], counts[name])
+		}
+		if stack[name] {
+			fmt.Fprintf(w, "%s%s (cycle)\n", lead, line)
+			return
+		}
+		fmt.Fprintf(w, "%s%s\n", lead, line)
+		if !multi {
+			return
+		}
+		stack[name] = true
+		defer delete(stack, name)
+		for idx, step := range steps {
+			branch, next := "├── ", "│   "
+			if idx == len(steps)-1 {
+				branch, next = "└── ", "    "
+			}
+			marker := "∥ "
+			if mode != StepModeParallel {
+				marker = fmt.Sprintf("%d. ", idx+1)
+			}
+			kind, value := resolveStepKind(step, resolve)
+			if kind == StepTask {
+				walk(value, prefix+next, prefix+branch+marker, stack)
+				continue
+			}
+			fmt.Fprintf(w, "%s%s%s%s\n", prefix, branch, marker, firstLine(stepDisplayName(step)))
+		}
+	}
+	walk(root, "", "", map[string]bool{})
+}
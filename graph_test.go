@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func graphTestConfig() Config {
+	return Config{Tasks: []TaskDef{
+		{Name: "format", Parallel: StepList{
+			{Value: "rubocop -a", Name: "rubocop", Kind: StepCommand},
+			{Value: "prettier --write .", Kind: StepAuto},
+		}},
+		{Name: "test", Cmd: StepList{{Value: "bin/test", Kind: StepAuto}}},
+		{Name: "check", Seq: StepList{{Value: "format", Kind: StepAuto}, {Value: "test", Kind: StepAuto}}},
+		{Name: "full", Seq: StepList{{Value: "format", Kind: StepAuto}, {Value: "check", Kind: StepTask}, {Value: "git push", Kind: StepCommand}}},
+	}}
+}
+
+func TestWriteTaskTree(t *testing.T) {
+	var buf bytes.Buffer
+	writeTaskTree(&buf, "full", configResolver(graphTestConfig()))
+
+	want := strings.Join([]string{
+		"full (seq)",
+		"├── 1. format (parallel) [run 1 of 2]",
+		"│   ├── ∥ rubocop",
+		"│   └── ∥ prettier --write .",
+		"├── 2. check (seq)",
+		"│   ├── 1. format (parallel) [run 2 of 2]",
+		"│   │   ├── ∥ rubocop",
+		"│   │   └── ∥ prettier --write .",
+		"│   └── 2. test: bin/test",
+		"└── 3. git push",
+		"",
+	}, "\n")
+	if buf.String() != want {
+		t.Fatalf("unexpected tree:\n%s", buf.String())
+	}
+}
+
+func TestTaskGraphFormats(t *testing.T) {
+	g := buildTaskGraph([]string{"full"}, configResolver(graphTestConfig()))
+	if g.repeats["format"] != "runs 2× in full" {
+		t.Fatalf("expected format to be flagged as repeated, got %v", g.repeats)
+	}
+
+	var dot bytes.Buffer
+	g.writeDOT(&dot)
+	for _, want := range []string{
+		`"task:check" -> "task:format" [label="1"];`,
+		`"task:format" -> "format::par::1" [style=dashed];`,
+		`"full::seq::2" [label="git push", shape=note];`,
+		"color=red",
+	} {
+		if !strings.Contains(dot.String(), want) {
+			t.Fatalf("expected %s in DOT output:\n%s", want, dot.String())
+		}
+	}
+
+	var mermaid bytes.Buffer
+	g.writeMermaid(&mermaid)
+	if !strings.HasPrefix(mermaid.String(), "flowchart LR\n") || !strings.Contains(mermaid.String(), "class n1 repeated") {
+		t.Fatalf("unexpected Mermaid output:\n%s", mermaid.String())
+	}
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// headlessMain runs taskNames without the UI, wrapped in the suite hooks,
+// and writes events and reports. Processes are tracked in configPath's
+// state file. It returns the process exit code.
+func headlessMain(cfg Config, configPath string, taskNames []string, eventsPath string, reportPaths []string) int {
+	if len(taskNames) == 0 {
+		fmt.Fprintln(os.Stderr, "headless mode needs at least one task name")
+		return 2
+	}
+	offerLeftoverCleanup(configPath)
+	defer startProcessTracking(configPath)()
+	var events *eventLog
+	if eventsPath != "" {
+		var err error
+		events, err = openEventLog(eventsPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "events error: %v\n", err)
+			return 1
+		}
+		defer events.Close()
+	}
+	if err := runSuiteHook("on_startup", cfg.OnStartup, cfg.Shell, cfg.Init); err != nil {
+		fmt.Fprintf(os.Stderr, "hook error: %v\n", err)
+		return 1
+	}
+
+	m := newModel(cfg)
+	m.events = events
+	var out io.Writer = os.Stdout
+	if eventsPath == "-" {
+		out = io.Discard
+	}
+	code := runHeadless(&m, taskNames, out)
+	if err := writeReports(reportPaths, &m); err != nil {
+		fmt.Fprintf(os.Stderr, "report error: %v\n", err)
+		code = 1
+	}
+	if err := runSuiteHook("on_shutdown", cfg.OnShutdown, cfg.Shell, cfg.Init); err != nil {
+		fmt.Fprintf(os.Stderr, "hook error: %v\n", err)
+		code = 1
+	}
+	return code
+}
+
+// runHeadless runs the named tasks one after another without the TUI,
+// printing output to out and stopping at the first failure. The model runs
+// under a tea.Program without a renderer, like the daemon, so commands its
+// handlers return (notifications, queued reruns) run as they do in the UI.
+// It returns the process exit code and leaves the final state in m.
+func runHeadless(m *model, taskNames []string, out io.Writer) int {
+	for _, name := range taskNames {
+		if m.taskByName[name] == nil {
+			fmt.Fprintf(os.Stderr, "unknown task %q\n", name)
+			return 2
+		}
+	}
+
+	m.headless = &headlessRun{names: taskNames, out: out}
+	p := tea.NewProgram(*m, tea.WithInput(nil), tea.WithOutput(io.Discard), tea.WithoutRenderer(), tea.WithoutSignalHandler())
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	go func() {
+		if _, ok := <-signals; ok {
+			p.Send(headlessStopMsg{})
+		}
+	}()
+
+	finalModel, err := p.Run()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "run error: %v\n", err)
+		return 1
+	}
+	if final, ok := finalModel.(model); ok {
+		*m = final
+	}
+	m.killAllTasks()
+	m.waitForTasks(10 * time.Second)
+	return m.headless.code
+}
+
+// headlessRun is the state of a headless session inside the model.
+type headlessRun struct {
+	names   []string
+	next    int
+	current string
+	out     io.Writer
+	code    int
+}
+
+type headlessNextMsg struct{}
+
+type headlessStopMsg struct{}
+
+// startHeadlessTask starts the next headless task, or quits after the last.
+func (m *model) startHeadlessTask() tea.Cmd {
+	h := m.headless
+	if h.next >= len(h.names) {
+		return tea.Quit
+	}
+	h.current = h.names[h.next]
+	h.next++
+	cmd := m.startTask(h.current, true)
+	if cmd == nil {
+		fmt.Fprintf(os.Stderr, "%s is already running\n", h.current)
+		h.code = 1
+		return tea.Quit
+	}
+	return cmd
+}
+
+// headlessTaskFinished moves on once the current headless task is done.
+func (m *model) headlessTaskFinished(taskName string) tea.Cmd {
+	h := m.headless
+	if h == nil || taskName != h.current {
+		return nil
+	}
+	task := m.taskByName[taskName]
+	if task.Running {
+		// Restarted by its on_retrigger policy.
+		return nil
+	}
+	if task.Status == StatusFailed || task.Status == StatusCanceled {
+		fmt.Fprintf(os.Stderr, "%s %s\n", taskName, statusName(task.Status))
+		h.code = 1
+		return tea.Quit
+	}
+	return m.startHeadlessTask()
+}
+
+// printHeadless writes a line of source's output in a headless session.
+func (m *model) printHeadless(source string, msg TaskOutputMsg) {
+	if m.headless != nil {
+		fmt.Fprint(m.headless.out, rawLine(m.headlessLine(source, msg)))
+	}
+}
+
+// headlessLine prefixes step output with the step label.
+func (m *model) headlessLine(taskName string, msg TaskOutputMsg) string {
+	if m.stripANSI(msg.Target) {
+		msg.Line = ansi.Strip(msg.Line)
+	}
+	if msg.Target == taskName {
+		return msg.Line
+	}
+	if step := m.stepByID[msg.Target]; step != nil {
+		return fmt.Sprintf("[%s] %s", step.Label, msg.Line)
+	}
+	return fmt.Sprintf("[%s] %s", msg.Target, msg.Line)
+}
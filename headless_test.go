@@ -0,0 +1,44 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestHeadlessRunsQueuedReruns(t *testing.T) {
+	cfg := Config{
+		Shell: "/bin/sh",
+		Tasks: []TaskDef{
+			{Name: "gen", OnRetrigger: "queue", Cmd: StepList{{Value: "echo gen", Kind: StepCommand}}},
+			{Name: "test", Cmd: StepList{{Value: "echo test", Kind: StepCommand}}},
+		},
+	}
+	m := newModel(cfg)
+	m.queuePending["gen"] = true
+
+	var out bytes.Buffer
+	if code := runHeadless(&m, []string{"gen", "test"}, &out); code != 0 {
+		t.Fatalf("expected exit code 0, got %d", code)
+	}
+	if out.String() != "gen\ngen\ntest\n" {
+		t.Fatalf("expected the queued rerun before moving on, got %q", out.String())
+	}
+}
+
+func TestHeadlessKeepsEveryLine(t *testing.T) {
+	cfg := Config{
+		Shell: "/bin/sh",
+		Tasks: []TaskDef{{Name: "flood", Cmd: StepList{{Value: "seq 1 20000", Kind: StepCommand}}}},
+	}
+	m := newModel(cfg)
+
+	var out bytes.Buffer
+	if code := runHeadless(&m, []string{"flood"}, &out); code != 0 {
+		t.Fatalf("expected exit code 0, got %d", code)
+	}
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(lines) != 20000 || lines[0] != "1" || lines[19999] != "20000" {
+		t.Fatalf("expected all 20000 lines in order, got %d", len(lines))
+	}
+}
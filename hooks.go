@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// TaskHooks are commands run around a task. They never change the task's
+// result; a failing hook is reported in the task output.
+type TaskHooks struct {
+	OnStart   CommandList `yaml:"on_start"`
+	OnSuccess CommandList `yaml:"on_success"`
+	OnFailure CommandList `yaml:"on_failure"`
+	OnExit    CommandList `yaml:"on_exit"`
+}
+
+func (h TaskHooks) IsZero() bool {
+	return len(h.OnStart) == 0 && len(h.OnSuccess) == 0 && len(h.OnFailure) == 0 && len(h.OnExit) == 0
+}
+
+func (h TaskHooks) validate() error {
+	if hasEmptyCommand(h.OnStart) || hasEmptyCommand(h.OnSuccess) || hasEmptyCommand(h.OnFailure) || hasEmptyCommand(h.OnExit) {
+		return fmt.Errorf("hook commands must be non-empty")
+	}
+	return nil
+}
+
+// taskLog tees a task's output into a temp file so finish hooks can read it
+// through SUITE_LOG. The file is removed once the hooks have run.
+type taskLog struct {
+	file *os.File
+	in   chan tea.Msg
+	done chan struct{}
+}
+
+func startTaskLog(taskName string, out chan<- tea.Msg) (*taskLog, chan<- tea.Msg) {
+	file, err := os.CreateTemp("", "suite-"+sanitizeFileName(taskName)+"-*.log")
+	if err != nil {
+		return nil, out
+	}
+	log := &taskLog{file: file, in: make(chan tea.Msg, 128), done: make(chan struct{})}
+	go func() {
+		defer close(log.done)
+		for msg := range log.in {
+			if line, ok := msg.(TaskOutputMsg); ok {
+				fmt.Fprint(file, rawLine(line.Line))
+			}
+			out <- msg
+		}
+	}()
+	return log, log.in
+}
+
+// finish flushes everything sent so far and returns the log path.
+func (l *taskLog) finish() string {
+	if l == nil {
+		return ""
+	}
+	close(l.in)
+	<-l.done
+	_ = l.file.Close()
+	return l.file.Name()
+}
+
+func (l *taskLog) remove() {
+	if l != nil {
+		_ = os.Remove(l.file.Name())
+	}
+}
+
+func sanitizeFileName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == ' ' || r == ':' {
+			return '_'
+		}
+		return r
+	}, name)
+}
+
+func hookEnv(taskName string, exitCode int, status string, elapsed time.Duration, logPath string) map[string]string {
+	env := map[string]string{"SUITE_TASK": taskName}
+	if status != "" {
+		env["SUITE_STATUS"] = status
+		env["SUITE_EXIT_CODE"] = strconv.Itoa(exitCode)
+		env["SUITE_DURATION"] = strconv.FormatFloat(elapsed.Seconds(), 'f', 3, 64)
+	}
+	if logPath != "" {
+		env["SUITE_LOG"] = logPath
+	}
+	return env
+}
+
+// hookTimeout bounds how long one hook command may run.
+const hookTimeout = 5 * time.Minute
+
+type hookKillKey struct{}
+
+// withHookKill returns a context whose hooks stop once kill is called. Finish
+// hooks outlive the task's own cancellation, so they need a separate stop.
+func withHookKill(ctx context.Context) (context.Context, context.CancelFunc) {
+	kill, cancel := context.WithCancel(context.Background())
+	return context.WithValue(ctx, hookKillKey{}, kill), cancel
+}
+
+// hookContext derives the context one hook command runs under. It keeps
+// ctx's values but not its cancellation, except for on_start, which belongs
+// to the run; a hook kill or hookTimeout stops any hook.
+func hookContext(ctx context.Context, name string) (context.Context, context.CancelFunc) {
+	hctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), hookTimeout)
+	stops := []func() bool{}
+	if kill, ok := ctx.Value(hookKillKey{}).(context.Context); ok {
+		stops = append(stops, context.AfterFunc(kill, cancel))
+	}
+	if name == "on_start" {
+		stops = append(stops, context.AfterFunc(ctx, cancel))
+	}
+	return hctx, func() {
+		for _, stop := range stops {
+			stop()
+		}
+		cancel()
+	}
+}
+
+// runHooks runs commands in order, streaming their output into the task.
+// Finish hooks run even when the task was canceled; see hookContext.
+func runHooks(ctx context.Context, name string, commands CommandList, env map[string]string, taskName, shell string, init CommandList, msgCh chan<- tea.Msg) {
+	if len(commands) == 0 {
+		return
+	}
+	ctx = withStepEnv(withoutJobSlot(ctx), env)
+	if isDryRun(ctx) {
+		msgCh <- planNoteMsg{Target: taskName, Text: name + " hook:"}
+	}
+	for _, command := range commands {
+		hctx, cancel := hookContext(ctx, name)
+		exitCode, err := runSingle(hctx, command, shell, init, msgCh, taskName)
+		timedOut := errors.Is(hctx.Err(), context.DeadlineExceeded)
+		cancel()
+		switch {
+		case timedOut:
+			msgCh <- TaskOutputMsg{Target: taskName, Line: fmt.Sprintf("%s hook timed out after %s: %s", name, hookTimeout, command)}
+		case err != nil:
+			msgCh <- TaskOutputMsg{Target: taskName, Line: fmt.Sprintf("%s hook failed (exit %d): %s", name, exitCode, command)}
+		}
+	}
+}
+
+// runSuiteHook runs a suite-level hook (on_startup/on_shutdown) in the
+// foreground, outside the TUI.
+func runSuiteHook(name string, commands CommandList, shell string, init CommandList) error {
+	if shell == "" {
+		shell = "/bin/sh"
+	}
+	for _, command := range commands {
+		cmd := exec.Command(shell, "-c", buildShellCommand(init, command))
+		cmd.Env = append(envWithShell(shell), "SUITE_HOOK="+name)
+		cmd.Stdin = os.Stdin
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("%s hook %q: %w", name, command, err)
+		}
+	}
+	return nil
+}
@@ -25,16 +25,19 @@ func runInit(path string) error {
 		return err
 	}
 
-	title := "suite"
+	content := defaultConfigTemplate(initTitle())
+	return os.WriteFile(path, []byte(content), 0o644)
+}
+
+// initTitle names a new config after the current directory.
+func initTitle() string {
 	if cwd, err := os.Getwd(); err == nil {
 		base := filepath.Base(cwd)
 		if base != "" && base != "." && base != string(filepath.Separator) {
-			title = base
+			return base
 		}
 	}
-
-	content := defaultConfigTemplate(title)
-	return os.WriteFile(path, []byte(content), 0o644)
+	return "suite"
 }
 
 func defaultConfigTemplate(title string) string {
@@ -90,6 +93,24 @@ func offerInit(path string) error {
 	return nil
 }
 
+// offerTemplateInit tells the user init --detect found no task runners and
+// asks whether to write the starter template instead.
+func offerTemplateInit(path string) error {
+	fmt.Fprintln(os.Stderr, "No task runners detected in this directory (Makefile, package.json, Procfile, justfile, Rakefile, bin/).")
+	if !isTerminalFn(os.Stdin) {
+		fmt.Fprintln(os.Stderr, "Run `suite init` to start from the template instead.")
+		return errors.New("nothing detected")
+	}
+	ok, err := promptYesNo(fmt.Sprintf("Create %s from the starter template instead?", path))
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errors.New("nothing detected")
+	}
+	return runInit(path)
+}
+
 func promptYesNo(question string) (bool, error) {
 	fmt.Fprintf(os.Stderr, "%s [y/N]: ", question)
 	reader := bufio.NewReader(os.Stdin)
@@ -170,6 +170,42 @@ func TestOfferInitNo(t *testing.T) {
 	}
 }
 
+func TestInitDetectFindsNothing(t *testing.T) {
+	dir := t.TempDir()
+	t.Cleanup(silenceOutput(t))
+	path := filepath.Join(dir, defaultConfigName)
+	if count, err := runInitDetect(path); count != 0 || err != nil {
+		t.Fatalf("expected nothing detected, got %d %v", count, err)
+	}
+	if _, err := os.Stat(path); err == nil {
+		t.Fatalf("did not expect a config without asking")
+	}
+
+	origTerminal := isTerminalFn
+	isTerminalFn = func(*os.File) bool { return true }
+	t.Cleanup(func() { isTerminalFn = origTerminal })
+
+	orig := os.Stdin
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("pipe: %v", err)
+	}
+	_, _ = w.Write([]byte("y\n"))
+	_ = w.Close()
+	os.Stdin = r
+	t.Cleanup(func() {
+		os.Stdin = orig
+		_ = r.Close()
+	})
+
+	if err := offerTemplateInit(path); err != nil {
+		t.Fatalf("offerTemplateInit: %v", err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("expected the template after a yes: %v", err)
+	}
+}
+
 func silenceOutput(t *testing.T) func() {
 	t.Helper()
 
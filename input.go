@@ -0,0 +1,292 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// inputs holds the stdin of every command started while the UI owns the
+// terminal, so typed lines can be sent to them. Nil (headless runs, tests)
+// hands a terminal stdin to commands directly, as before.
+var inputs *inputTable
+
+type inputTable struct {
+	mu    sync.Mutex
+	pipes map[string]io.WriteCloser
+}
+
+var errNoInput = errors.New("not running")
+
+func newInputTable() *inputTable {
+	return &inputTable{pipes: make(map[string]io.WriteCloser)}
+}
+
+// connect gives cmd a stdin. With a table the pipe is registered under
+// target once the returned func is called after Start; that func returns
+// another that unregisters it.
+func (t *inputTable) connect(cmd *exec.Cmd, target string) (func() func(), error) {
+	if t == nil {
+		if isTerminal(os.Stdin) {
+			cmd.Stdin = os.Stdin
+		}
+		return func() func() { return func() {} }, nil
+	}
+	pipe, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	return func() func() {
+		t.mu.Lock()
+		t.pipes[target] = pipe
+		t.mu.Unlock()
+		return func() {
+			t.mu.Lock()
+			if t.pipes[target] == pipe {
+				delete(t.pipes, target)
+			}
+			t.mu.Unlock()
+		}
+	}, nil
+}
+
+// resolve finds the running command for a sidebar target: the target
+// itself, or for a task the one step of it that is running.
+func (t *inputTable) resolve(target string) (string, bool) {
+	if t == nil {
+		return "", false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.pipes[target]; ok {
+		return target, true
+	}
+	found := ""
+	for candidate := range t.pipes {
+		if taskName, ok := stepTaskFromID(candidate); ok && taskName == target {
+			if found != "" {
+				return "", false
+			}
+			found = candidate
+		}
+	}
+	return found, found != ""
+}
+
+func (t *inputTable) write(target, data string) error {
+	if t == nil {
+		return errNoInput
+	}
+	t.mu.Lock()
+	pipe, ok := t.pipes[target]
+	t.mu.Unlock()
+	if !ok {
+		return errNoInput
+	}
+	_, err := io.WriteString(pipe, data)
+	return err
+}
+
+// close sends end of file to target's stdin.
+func (t *inputTable) close(target string) error {
+	if t == nil {
+		return errNoInput
+	}
+	t.mu.Lock()
+	pipe, ok := t.pipes[target]
+	delete(t.pipes, target)
+	t.mu.Unlock()
+	if !ok {
+		return errNoInput
+	}
+	return pipe.Close()
+}
+
+// terminalIO is the terminal an interactive task runs on.
+type terminalIO struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+}
+
+type foregroundKey struct{}
+
+// withForeground makes runSingle attach commands to term instead of
+// capturing their output.
+func withForeground(ctx context.Context, term terminalIO) context.Context {
+	return context.WithValue(ctx, foregroundKey{}, term)
+}
+
+func foregroundTerminal(ctx context.Context) (terminalIO, bool) {
+	term, ok := ctx.Value(foregroundKey{}).(terminalIO)
+	return term, ok
+}
+
+// foregroundRun runs an interactive: true task through tea.Exec. The UI
+// releases the terminal, the task's commands use it directly, and the UI
+// comes back when the task finishes.
+type foregroundRun struct {
+	ctx      context.Context
+	taskName string
+	def      TaskDef
+	shell    string
+	init     CommandList
+	resolve  TaskResolver
+	msgCh    chan tea.Msg
+	term     terminalIO
+}
+
+func (r *foregroundRun) SetStdin(in io.Reader)   { r.term.stdin = in }
+func (r *foregroundRun) SetStdout(out io.Writer) { r.term.stdout = out }
+func (r *foregroundRun) SetStderr(out io.Writer) { r.term.stderr = out }
+
+func (r *foregroundRun) Run() error {
+	runTask(withForeground(r.ctx, r.term), r.taskName, r.def, r.shell, r.init, r.resolve, r.msgCh)
+	return nil
+}
+
+// runForeground runs cmd on the terminal, as the terminal's foreground
+// process group when it is one so keys like ctrl+c reach it.
+func runForeground(ctx context.Context, cmd *exec.Cmd, term terminalIO, scope *cgroupScope, target, command string) (int, error) {
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = term.stdin, term.stdout, term.stderr
+	restore := func() {}
+	if tty, ok := term.stdin.(*os.File); ok && isTerminal(tty) {
+		restore = foregroundCommand(cmd, tty)
+	}
+	if err := cmd.Start(); err != nil {
+		return -1, err
+	}
+	defer restore()
+	processes.add(cmd.Process.Pid, target, command, scope.Path())
+	defer processes.remove(cmd.Process.Pid)
+	defer trackRunProcess(ctx, cmd.Process.Pid, target, scope.Path())()
+
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			scope.terminate()
+			killProcess(cmd)
+			scope.kill()
+		case <-done:
+		}
+	}()
+	err := cmd.Wait()
+	close(done)
+	return exitCode(err), err
+}
+
+// inputLine is the line being typed while input is attached to a task.
+// Commands read stdin through a pipe, so there is no terminal to edit it;
+// it is edited here and sent on enter. It only shows in the status bar:
+// echoing it into the output would keep whatever was typed (passwords
+// included) in scrollback and spill files.
+type inputLine struct {
+	target string
+	text   []rune
+}
+
+// handleInputKey edits the attached input line, sending it on enter and
+// end of file on ctrl+d. ctrl+] detaches.
+func (m *model) handleInputKey(msg tea.KeyMsg) {
+	line := m.input
+	switch msg.String() {
+	case "ctrl+]":
+		m.input = nil
+		return
+	case "enter":
+		text := string(line.text)
+		line.text = nil
+		m.sendInput(line.target, text+"\n", false)
+	case "ctrl+d":
+		if len(line.text) > 0 {
+			m.sendInput(line.target, string(line.text), false)
+			line.text = nil
+			return
+		}
+		m.sendInput(line.target, "", true)
+		m.input = nil
+	case "ctrl+c", "ctrl+u":
+		line.text = nil
+	case "backspace", "ctrl+h":
+		if len(line.text) > 0 {
+			line.text = line.text[:len(line.text)-1]
+		}
+	case "ctrl+w":
+		end := len(line.text)
+		for end > 0 && line.text[end-1] == ' ' {
+			end--
+		}
+		for end > 0 && line.text[end-1] != ' ' {
+			end--
+		}
+		line.text = line.text[:end]
+	case " ":
+		line.text = append(line.text, ' ')
+	case "tab":
+		line.text = append(line.text, '\t')
+	default:
+		if msg.Type == tea.KeyRunes {
+			line.text = append(line.text, msg.Runes...)
+		}
+	}
+}
+
+// inputStatus is the status bar while input is attached.
+func (m model) inputStatus() string {
+	return "input › " + string(m.input.text) + "▏  enter send · ctrl+d eof · ctrl+] detach"
+}
+
+// attachInput starts sending typed lines to the selected entry's running
+// command.
+func (m *model) attachInput() {
+	entry := m.selectedEntry()
+	if entry == nil {
+		return
+	}
+	target := entry.Target
+	if m.remote == nil {
+		resolved, ok := inputs.resolve(target)
+		if !ok {
+			return
+		}
+		target = resolved
+	} else if !m.isRunningTarget(target) {
+		return
+	}
+	m.input = &inputLine{target: target}
+}
+
+func (m *model) sendInput(target, data string, eof bool) {
+	if m.remote != nil {
+		op := "input"
+		if eof {
+			op = "close_input"
+		}
+		m.remote.send(daemonRequest{Op: op, Target: target, Command: data})
+		return
+	}
+	var err error
+	if eof {
+		err = inputs.close(target)
+	} else {
+		err = inputs.write(target, data)
+	}
+	if err != nil {
+		// The command has exited or closed its stdin.
+		m.input = nil
+	}
+}
+
+func (m *model) isRunningTarget(target string) bool {
+	if task := m.taskByName[target]; task != nil {
+		return task.Running
+	}
+	step := m.stepByID[target]
+	return step != nil && step.Running
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestInputTableFeedsRunningCommand(t *testing.T) {
+	inputs = newInputTable()
+	t.Cleanup(func() { inputs = nil })
+
+	msgCh := make(chan tea.Msg, 8)
+	done := make(chan int, 1)
+	go func() {
+		code, _ := runSingle(context.Background(), "read line; echo \"got $line\"; cat; echo eof", "/bin/sh", nil, msgCh, "full::seq::0")
+		done <- code
+	}()
+
+	deadline := time.Now().Add(2 * time.Second)
+	target, ok := inputs.resolve("full")
+	for !ok {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the step's stdin to be registered")
+		}
+		time.Sleep(10 * time.Millisecond)
+		target, ok = inputs.resolve("full")
+	}
+	if target != "full::seq::0" {
+		t.Fatalf("expected the task to resolve to its running step, got %q", target)
+	}
+	if err := inputs.write(target, "hello\n"); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if err := inputs.close(target); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	var lines []string
+	for len(lines) < 2 {
+		select {
+		case msg := <-msgCh:
+			lines = append(lines, msg.(TaskOutputMsg).Line)
+		case <-time.After(2 * time.Second):
+			t.Fatalf("expected output, got %v", lines)
+		}
+	}
+	if lines[0] != "got hello" || lines[1] != "eof" {
+		t.Fatalf("unexpected output %v", lines)
+	}
+	if code := <-done; code != 0 {
+		t.Fatalf("expected exit 0, got %d", code)
+	}
+	if _, ok := inputs.resolve("full"); ok {
+		t.Fatalf("expected the stdin to be unregistered after exit")
+	}
+}
+
+func TestInputLineEditing(t *testing.T) {
+	cfg := Config{
+		Tasks:        []TaskDef{{Name: "repl", Key: "r", Cmd: StepList{{Value: "irb", Kind: StepCommand}}}},
+		SidebarWidth: 32,
+	}
+	m := newModel(cfg)
+	m.handleTaskStarted("repl")
+	m.input = &inputLine{target: "repl"}
+
+	for _, key := range []tea.KeyMsg{
+		{Type: tea.KeyRunes, Runes: []rune("puts 1")},
+		{Type: tea.KeySpace, Runes: []rune(" ")},
+		{Type: tea.KeyRunes, Runes: []rune("+ 2x")},
+		{Type: tea.KeyBackspace},
+	} {
+		m.handleInputKey(key)
+	}
+	if got := string(m.input.text); got != "puts 1 + 2" {
+		t.Fatalf("unexpected line %q", got)
+	}
+	m.handleInputKey(tea.KeyMsg{Type: tea.KeyCtrlW})
+	if got := string(m.input.text); got != "puts 1 + " {
+		t.Fatalf("expected ctrl+w to delete a word, got %q", got)
+	}
+
+	// Nothing is reading: sending detaches. Typed text never goes into the
+	// output, where it would end up in scrollback and spill files.
+	m.handleInputKey(tea.KeyMsg{Type: tea.KeyEnter})
+	if m.input != nil {
+		t.Fatalf("expected input to detach when the command can't be reached")
+	}
+	if task := m.taskByName["repl"]; task.Output.Len() != 0 {
+		t.Fatalf("expected the line not to be echoed, got %v", task.Output.All())
+	}
+}
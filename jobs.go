@@ -0,0 +1,61 @@
+package main
+
+import "context"
+
+// semaphore bounds concurrent work. A nil semaphore never blocks.
+type semaphore chan struct{}
+
+func newSemaphore(limit int) semaphore {
+	if limit <= 0 {
+		return nil
+	}
+	return make(semaphore, limit)
+}
+
+// acquire takes a slot, calling onWait first when none is free.
+func (s semaphore) acquire(ctx context.Context, onWait func()) error {
+	if s == nil {
+		return nil
+	}
+	select {
+	case s <- struct{}{}:
+		return nil
+	default:
+	}
+	if onWait != nil {
+		onWait()
+	}
+	select {
+	case s <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s semaphore) release() {
+	if s == nil {
+		return
+	}
+	<-s
+}
+
+// jobSlots is the global jobs: limit shared by every running command.
+var jobSlots semaphore
+
+func setJobLimit(limit int) {
+	jobSlots = newSemaphore(limit)
+}
+
+type jobExemptKey struct{}
+
+// withoutJobSlot marks commands run under ctx as exempt from jobs:, so
+// persistent tasks like dev servers never hold a slot.
+func withoutJobSlot(ctx context.Context) context.Context {
+	return context.WithValue(ctx, jobExemptKey{}, true)
+}
+
+func jobExempt(ctx context.Context) bool {
+	exempt, _ := ctx.Value(jobExemptKey{}).(bool)
+	return exempt
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSemaphoreAcquire(t *testing.T) {
+	sem := newSemaphore(1)
+	waited := false
+	if err := sem.acquire(context.Background(), func() { waited = true }); err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+	if waited {
+		t.Fatalf("did not expect to wait for a free slot")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := sem.acquire(ctx, func() { waited = true }); err == nil {
+		t.Fatalf("expected canceled acquire to fail")
+	}
+	if !waited {
+		t.Fatalf("expected onWait when no slot is free")
+	}
+
+	sem.release()
+	if err := sem.acquire(context.Background(), nil); err != nil {
+		t.Fatalf("acquire after release: %v", err)
+	}
+}
+
+func TestNilSemaphoreNeverBlocks(t *testing.T) {
+	var sem semaphore
+	for i := 0; i < 3; i++ {
+		if err := sem.acquire(context.Background(), func() { t.Fatalf("unexpected wait") }); err != nil {
+			t.Fatalf("acquire: %v", err)
+		}
+	}
+	sem.release()
+}
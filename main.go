@@ -7,6 +7,7 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
@@ -14,20 +15,51 @@ import (
 
 func main() {
 	if len(os.Args) > 1 && os.Args[1] == "init" {
-		if err := runInit(defaultConfigName); err != nil {
+		if len(os.Args) > 2 && os.Args[2] == "--detect" {
+			count, err := runInitDetect(defaultConfigName)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "init error: %v\n", err)
+				os.Exit(1)
+			}
+			if count > 0 {
+				fmt.Fprintf(os.Stdout, "Created %s with %d detected tasks. Edit it, then re-run suite.\n", defaultConfigName, count)
+				return
+			}
+			if err := offerTemplateInit(defaultConfigName); err != nil {
+				os.Exit(1)
+			}
+		} else if err := runInit(defaultConfigName); err != nil {
 			fmt.Fprintf(os.Stderr, "init error: %v\n", err)
 			os.Exit(1)
 		}
 		fmt.Fprintf(os.Stdout, "Created %s. Edit it, then re-run suite.\n", defaultConfigName)
 		return
 	}
+	args := append([]string(nil), os.Args[1:]...)
+	up := len(os.Args) > 1 && os.Args[1] == "up"
+	if up {
+		os.Args = append(os.Args[:1], os.Args[2:]...)
+	}
+	if len(os.Args) > 1 && !up {
+		if code, ok := runSubcommand(os.Args[1], os.Args[2:]); ok {
+			os.Exit(code)
+		}
+	}
 
 	var configPath string
 	var themeFlag string
+	var eventsPath string
+	var headless bool
+	var daemon bool
+	var reportPaths stringList
 	flag.StringVar(&configPath, "config", defaultConfigName, "path to config file")
 	flag.StringVar(&configPath, "c", defaultConfigName, "path to config file (shorthand)")
 	flag.StringVar(&themeFlag, "theme", "", "theme override: auto, light, or dark")
 	flag.StringVar(&themeFlag, "t", "", "theme override: auto, light, or dark (shorthand)")
+	flag.StringVar(&eventsPath, "events", "", "write task events as NDJSON to path (- for stdout)")
+	flag.BoolVar(&headless, "headless", false, "run the given tasks without the UI and exit")
+	flag.BoolVar(&daemon, "daemon", false, "run tasks in a background process; see suite attach and suite stop")
+	flag.Var(&reportPaths, "report", "write a JUnit XML (or .md summary) report on exit; repeatable")
 	flag.Parse()
 
 	cfg, err := LoadConfig(configPath)
@@ -43,23 +75,111 @@ func main() {
 		os.Exit(1)
 	}
 
+	if up {
+		if err := cfg.enableProcfile(); err != nil {
+			fmt.Fprintf(os.Stderr, "config error: %v\n", err)
+			os.Exit(1)
+		}
+	}
 	if strings.TrimSpace(themeFlag) != "" {
 		cfg.Theme = strings.TrimSpace(themeFlag)
 	}
 	applyTheme(cfg.Theme)
+	if daemon {
+		if os.Getenv(daemonEnv) != "" {
+			os.Exit(runDaemon(cfg, configPath))
+		}
+		os.Exit(startDaemon(configPath, args))
+	}
+	if headless {
+		os.Exit(headlessMain(cfg, configPath, flag.Args(), eventsPath, reportPaths))
+	}
+	if eventsPath == "-" {
+		fmt.Fprintln(os.Stderr, "--events - needs --headless (the UI owns stdout)")
+		os.Exit(2)
+	}
+	os.Exit(uiMain(cfg, configPath, eventsPath, reportPaths))
+}
+
+// uiMain runs the TUI and returns the exit code, so its deferred cleanup
+// runs before the process exits.
+func uiMain(cfg Config, configPath string, eventsPath string, reportPaths []string) int {
+	var events *eventLog
+	if eventsPath != "" {
+		var err error
+		events, err = openEventLog(eventsPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "events error: %v\n", err)
+			return 1
+		}
+		defer events.Close()
+	}
+	offerLeftoverCleanup(configPath)
+	stopTracking := startProcessTracking(configPath)
+	defer stopTracking()
+	if err := runSuiteHook("on_startup", cfg.OnStartup, cfg.Shell, cfg.Init); err != nil {
+		fmt.Fprintf(os.Stderr, "hook error: %v\n", err)
+		return 1
+	}
+	// The UI reads the terminal, so commands get a pipe to type into.
+	inputs = newInputTable()
 	m := newModel(cfg)
+	m.events = events
 	p := tea.NewProgram(m, tea.WithAltScreen(), tea.WithMouseCellMotion())
 	finalModel, err := p.Run()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "run error: %v\n", err)
-		os.Exit(1)
+		return 1
 	}
-	if final, ok := finalModel.(model); ok {
-		final.killAllTasks()
+	var final *model
+	if fm, ok := finalModel.(model); ok {
+		final = &fm
+	}
+	if fm, ok := finalModel.(*model); ok {
+		final = fm
 	}
-	if final, ok := finalModel.(*model); ok {
+	if final != nil {
 		final.killAllTasks()
+		final.waitForTasks(10 * time.Second)
+		stopTracking()
+		if err := writeReports(reportPaths, final); err != nil {
+			fmt.Fprintf(os.Stderr, "report error: %v\n", err)
+		}
+	}
+	if err := runSuiteHook("on_shutdown", cfg.OnShutdown, cfg.Shell, cfg.Init); err != nil {
+		fmt.Fprintf(os.Stderr, "hook error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// stringList collects a repeatable string flag.
+type stringList []string
+
+func (s *stringList) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringList) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
+func writeReports(paths []string, m *model) error {
+	if len(paths) == 0 {
+		return nil
+	}
+	title := m.cfg.Title
+	if title == "" {
+		title = "suite"
+	}
+	suites := m.reportSuites()
+	for _, path := range paths {
+		if err := writeReport(path, title, suites); err != nil {
+			return err
+		}
 	}
+	return nil
 }
 
 func applyTheme(theme string) {
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Matrix maps variable names to the values a task fans out over. Keys keep
+// their YAML order so combinations expand predictably.
+type Matrix struct {
+	Keys   []string
+	Values map[string][]string
+}
+
+func (mx *Matrix) UnmarshalYAML(value *yaml.Node) error {
+	switch value.Kind {
+	case yaml.MappingNode:
+		out := Matrix{Values: make(map[string][]string)}
+		for i := 0; i < len(value.Content); i += 2 {
+			key := strings.TrimSpace(value.Content[i].Value)
+			var values CommandList
+			if err := values.UnmarshalYAML(value.Content[i+1]); err != nil {
+				return fmt.Errorf("matrix %q must be a value or list", key)
+			}
+			if _, ok := out.Values[key]; ok {
+				return fmt.Errorf("duplicate matrix key %q", key)
+			}
+			out.Keys = append(out.Keys, key)
+			out.Values[key] = normalizeCommandList(values)
+		}
+		*mx = out
+		return nil
+	case yaml.ScalarNode:
+		if value.Tag == "!!null" {
+			return nil
+		}
+		return fmt.Errorf("matrix must be a map of lists")
+	case 0:
+		return nil
+	default:
+		return fmt.Errorf("matrix must be a map of lists")
+	}
+}
+
+func (mx Matrix) IsZero() bool {
+	return len(mx.Keys) == 0
+}
+
+var matrixKeyPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]*$`)
+
+func (mx Matrix) validate() error {
+	envKeys := make(map[string]string, len(mx.Keys))
+	for _, key := range mx.Keys {
+		if !matrixKeyPattern.MatchString(key) {
+			return fmt.Errorf("matrix key %q must be a letter followed by letters, digits, _ or -", key)
+		}
+		name := matrixEnvName(key)
+		if other, ok := envKeys[name]; ok {
+			return fmt.Errorf("matrix keys %q and %q both set $%s", other, key, name)
+		}
+		envKeys[name] = key
+		if len(mx.Values[key]) == 0 || hasEmptyCommand(mx.Values[key]) {
+			return fmt.Errorf("matrix %q needs at least one non-empty value", key)
+		}
+	}
+	return nil
+}
+
+// combinations lists every assignment of values, first key outermost.
+func (mx Matrix) combinations() []map[string]string {
+	combos := []map[string]string{{}}
+	for _, key := range mx.Keys {
+		next := make([]map[string]string, 0, len(combos)*len(mx.Values[key]))
+		for _, combo := range combos {
+			for _, value := range mx.Values[key] {
+				vars := make(map[string]string, len(combo)+1)
+				for k, v := range combo {
+					vars[k] = v
+				}
+				vars[key] = value
+				next = append(next, vars)
+			}
+		}
+		combos = next
+	}
+	return combos
+}
+
+// expandMatrix turns the single template step of a matrix task into one
+// step per combination.
+func expandMatrix(mx Matrix, template Step) StepList {
+	combos := mx.combinations()
+	steps := make(StepList, 0, len(combos))
+	for _, vars := range combos {
+		step := template
+		step.Kind = StepCommand
+		step.Value = renderMatrixTemplate(template.Value, vars)
+		step.Name = renderMatrixTemplate(template.Name, vars)
+		if step.Name == "" {
+			step.Name = matrixLabel(mx.Keys, vars)
+		}
+		env := make(map[string]string, len(template.Env)+len(vars))
+		for k, v := range template.Env {
+			env[k] = v
+		}
+		for k, v := range vars {
+			env[matrixEnvName(k)] = v
+		}
+		step.Env = env
+		steps = append(steps, step)
+	}
+	return steps
+}
+
+var matrixTemplatePattern = regexp.MustCompile(`\{\{\s*(?:matrix\.)?([A-Za-z_][A-Za-z0-9_-]*)\s*\}\}`)
+
+// renderMatrixTemplate replaces {{key}} and {{matrix.key}}. Unknown keys are
+// left untouched.
+func renderMatrixTemplate(text string, vars map[string]string) string {
+	if text == "" || len(vars) == 0 {
+		return text
+	}
+	return matrixTemplatePattern.ReplaceAllStringFunc(text, func(match string) string {
+		name := matrixTemplatePattern.FindStringSubmatch(match)[1]
+		if value, ok := vars[name]; ok {
+			return value
+		}
+		return match
+	})
+}
+
+func matrixLabel(keys []string, vars map[string]string) string {
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		parts = append(parts, fmt.Sprintf("%s=%s", key, vars[key]))
+	}
+	return strings.Join(parts, " ")
+}
+
+func matrixEnvName(key string) string {
+	return strings.ToUpper(strings.ReplaceAll(key, "-", "_"))
+}
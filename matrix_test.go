@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"gopkg.in/yaml.v3"
+)
+
+func TestMatrixUnmarshalKeepsOrder(t *testing.T) {
+	var cfg struct {
+		Matrix Matrix `yaml:"matrix"`
+	}
+
+	if err := yaml.Unmarshal([]byte("matrix: {ruby: [3.2, '3.10'], db: pg}"), &cfg); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if len(cfg.Matrix.Keys) != 2 || cfg.Matrix.Keys[0] != "ruby" || cfg.Matrix.Keys[1] != "db" {
+		t.Fatalf("unexpected keys: %v", cfg.Matrix.Keys)
+	}
+	if got := cfg.Matrix.Values["ruby"]; len(got) != 2 || got[1] != "3.10" {
+		t.Fatalf("unexpected values: %v", got)
+	}
+	if got := cfg.Matrix.Values["db"]; len(got) != 1 || got[0] != "pg" {
+		t.Fatalf("unexpected values: %v", got)
+	}
+}
+
+func TestExpandMatrix(t *testing.T) {
+	mx := Matrix{
+		Keys:   []string{"ruby", "db"},
+		Values: map[string][]string{"ruby": {"3.2", "3.3"}, "db": {"pg", "mysql"}},
+	}
+	steps := expandMatrix(mx, Step{Value: "bin/test --db {{db}} # {{ matrix.ruby }} {{other}}", Kind: StepAuto})
+
+	if len(steps) != 4 {
+		t.Fatalf("expected 4 steps, got %d", len(steps))
+	}
+	first := steps[0]
+	if first.Value != "bin/test --db pg # 3.2 {{other}}" {
+		t.Fatalf("unexpected command: %q", first.Value)
+	}
+	if first.Name != "ruby=3.2 db=pg" {
+		t.Fatalf("unexpected label: %q", first.Name)
+	}
+	if first.Env["RUBY"] != "3.2" || first.Env["DB"] != "pg" || first.Kind != StepCommand {
+		t.Fatalf("unexpected step: %#v", first)
+	}
+	if steps[3].Name != "ruby=3.3 db=mysql" {
+		t.Fatalf("unexpected last label: %q", steps[3].Name)
+	}
+}
+
+func TestMatrixTaskRunsWithEnv(t *testing.T) {
+	def := TaskDef{
+		Matrix:   Matrix{Keys: []string{"db"}, Values: map[string][]string{"db": {"pg", "mysql"}}},
+		Parallel: StepList{{Value: "printf '%s-{{db}}\n' \"$DB\"", Kind: StepCommand}},
+	}
+
+	mode, steps, multi := taskSteps("task", def, nil)
+	if !multi || mode != StepModeParallel || len(steps) != 2 {
+		t.Fatalf("unexpected expansion: mode=%v multi=%v steps=%d", mode, multi, len(steps))
+	}
+
+	msgCh := make(chan tea.Msg, 32)
+	go runTask(context.Background(), "task", def, "/bin/sh", nil, nil, msgCh)
+
+	lines := []string{}
+	for msg := range msgCh {
+		if out, ok := msg.(TaskOutputMsg); ok {
+			lines = append(lines, out.Line)
+		}
+	}
+	sort.Strings(lines)
+	if len(lines) != 2 || lines[0] != "mysql-mysql" || lines[1] != "pg-pg" {
+		t.Fatalf("unexpected output: %v", lines)
+	}
+}
+
+func TestMatrixTaskChildrenInSidebar(t *testing.T) {
+	cfg := Config{
+		Tasks: []TaskDef{{
+			Name:   "test",
+			Matrix: Matrix{Keys: []string{"ruby"}, Values: map[string][]string{"ruby": {"3.2", "3.3"}}},
+			Cmd:    StepList{{Value: "bin/test", Kind: StepAuto}},
+		}},
+		SidebarWidth: 32,
+	}
+
+	m := newModel(cfg)
+	m.expanded["task:test"] = true
+	m.rebuildEntries()
+
+	if len(m.entries) != 3 {
+		t.Fatalf("expected task plus 2 matrix children, got %d", len(m.entries))
+	}
+	if m.entries[1].Label != "ruby=3.2" || m.entries[2].Env["RUBY"] != "3.3" {
+		t.Fatalf("unexpected children: %#v", m.entries[1:])
+	}
+}
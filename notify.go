@@ -0,0 +1,246 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"gopkg.in/yaml.v3"
+)
+
+const defaultNotifyAfter = 10 * time.Second
+
+// NotifyConfig controls completion notifications. It can be set globally and
+// overridden per task; unset fields fall back to the global value.
+type NotifyConfig struct {
+	When    string         // always | failure | change | never
+	Via     []string       // osc9 | osc777 | bell | command
+	Command string         // run with SUITE_TITLE, SUITE_MESSAGE, ...
+	After   *time.Duration // only notify for runs at least this long; nil = 10s
+	set     bool
+}
+
+func (n *NotifyConfig) UnmarshalYAML(value *yaml.Node) error {
+	switch value.Kind {
+	case yaml.ScalarNode:
+		if value.Tag == "!!null" {
+			return nil
+		}
+		if value.Tag == "!!bool" {
+			var on bool
+			if err := value.Decode(&on); err != nil {
+				return err
+			}
+			// true is the same as any other setting left at its default.
+			*n = NotifyConfig{When: "never", set: true}
+			if on {
+				n.When = ""
+			}
+			return nil
+		}
+		*n = NotifyConfig{When: strings.TrimSpace(value.Value), set: true}
+		return nil
+	case yaml.MappingNode:
+		out := NotifyConfig{set: true}
+		for i := 0; i < len(value.Content); i += 2 {
+			key := strings.TrimSpace(value.Content[i].Value)
+			val := value.Content[i+1]
+			switch key {
+			case "when":
+				out.When = strings.TrimSpace(val.Value)
+			case "via":
+				var list CommandList
+				if err := list.UnmarshalYAML(val); err != nil {
+					return fmt.Errorf("notify via must be a string or list")
+				}
+				out.Via = normalizeCommandList(list)
+			case "command":
+				out.Command = strings.TrimSpace(val.Value)
+			case "after":
+				after, err := time.ParseDuration(strings.TrimSpace(val.Value))
+				if err != nil {
+					return fmt.Errorf("notify after must be a duration like 30s")
+				}
+				out.After = &after
+			default:
+				return fmt.Errorf("unknown notify key %q", key)
+			}
+		}
+		*n = out
+		return nil
+	case 0:
+		return nil
+	default:
+		return fmt.Errorf("notify must be a string or map")
+	}
+}
+
+func (n NotifyConfig) validate() error {
+	switch strings.ToLower(n.When) {
+	case "", "always", "failure", "change", "never":
+	default:
+		return fmt.Errorf("notify when must be one of always, failure, change, or never")
+	}
+	for _, via := range n.Via {
+		switch strings.ToLower(via) {
+		case "osc9", "osc777", "bell", "command":
+		default:
+			return fmt.Errorf("notify via %q must be osc9, osc777, bell, or command", via)
+		}
+		if strings.EqualFold(via, "command") && n.Command == "" {
+			return fmt.Errorf("notify via command needs a command")
+		}
+	}
+	if n.After != nil && *n.After < 0 {
+		return fmt.Errorf("notify after must not be negative")
+	}
+	return nil
+}
+
+// mergeNotify layers a task's notify settings over the global ones.
+func mergeNotify(global, task NotifyConfig) NotifyConfig {
+	out := global
+	if !task.set {
+		return out
+	}
+	out.set = true
+	if task.When != "" {
+		out.When = task.When
+	}
+	if len(task.Via) > 0 {
+		out.Via = task.Via
+	}
+	if task.Command != "" {
+		out.Command = task.Command
+	}
+	if task.After != nil {
+		out.After = task.After
+	}
+	return out
+}
+
+func (n NotifyConfig) after() time.Duration {
+	if n.After == nil {
+		return defaultNotifyAfter
+	}
+	return *n.After
+}
+
+func (n NotifyConfig) channels() []string {
+	if len(n.Via) > 0 {
+		return n.Via
+	}
+	if n.Command != "" {
+		return []string{"command"}
+	}
+	return []string{"osc9"}
+}
+
+func shouldNotify(n NotifyConfig, status, previous TaskStatus, elapsed time.Duration) bool {
+	if !n.set || elapsed < n.after() {
+		return false
+	}
+	if status == StatusIdle || status == StatusRunning || status == StatusQueued {
+		return false
+	}
+	switch strings.ToLower(n.When) {
+	case "always":
+		return true
+	case "change":
+		return previous != StatusIdle && previous != status
+	case "never":
+		return false
+	default:
+		return status == StatusFailed
+	}
+}
+
+type notification struct {
+	Title   string
+	Message string
+	Task    string
+	Status  string
+}
+
+func notifyCmd(n NotifyConfig, note notification, shell string) tea.Cmd {
+	channels := n.channels()
+	return func() tea.Msg {
+		for _, via := range channels {
+			switch strings.ToLower(via) {
+			case "osc9":
+				writeTerminalSequence(fmt.Sprintf("\x1b]9;%s\x07", sanitizeNotification(note.Title+": "+note.Message)))
+			case "osc777":
+				writeTerminalSequence(fmt.Sprintf("\x1b]777;notify;%s;%s\x07", sanitizeNotification(note.Title), sanitizeNotification(note.Message)))
+			case "bell":
+				writeTerminalSequence("\a")
+			case "command":
+				runNotifyCommand(n.Command, note, shell)
+			}
+		}
+		return nil
+	}
+}
+
+func writeTerminalSequence(seq string) {
+	if os.Getenv("TMUX") != "" && seq != "\a" {
+		seq = "\x1bPtmux;" + strings.ReplaceAll(seq, "\x1b", "\x1b\x1b") + "\x1b\\"
+	}
+	fmt.Fprint(os.Stderr, seq)
+}
+
+func runNotifyCommand(command string, note notification, shell string) {
+	if shell == "" {
+		shell = "/bin/sh"
+	}
+	cmd := exec.Command(shell, "-c", command)
+	cmd.Env = append(envWithShell(shell),
+		"SUITE_TITLE="+note.Title,
+		"SUITE_MESSAGE="+note.Message,
+		"SUITE_TASK="+note.Task,
+		"SUITE_STATUS="+note.Status,
+	)
+	_ = cmd.Run()
+}
+
+// sanitizeNotification drops characters that would end or break an OSC
+// sequence.
+func sanitizeNotification(text string) string {
+	return strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f || r == ';' {
+			return ' '
+		}
+		return r
+	}, text)
+}
+
+// statusKey is the stable, machine-readable name used in events and hook env.
+func statusKey(status TaskStatus) string {
+	if status == StatusWarning {
+		return "warning"
+	}
+	return statusName(status)
+}
+
+func statusName(status TaskStatus) string {
+	switch status {
+	case StatusRunning:
+		return "running"
+	case StatusSuccess:
+		return "passed"
+	case StatusFailed:
+		return "failed"
+	case StatusCanceled:
+		return "canceled"
+	case StatusSkipped:
+		return "skipped"
+	case StatusWarning:
+		return "passed with warnings"
+	case StatusQueued:
+		return "queued"
+	default:
+		return "idle"
+	}
+}
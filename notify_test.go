@@ -0,0 +1,109 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestNotifyConfigYAML(t *testing.T) {
+	var cfg struct {
+		Global NotifyConfig `yaml:"global"`
+		Task   NotifyConfig `yaml:"task"`
+		Off    NotifyConfig `yaml:"off"`
+		On     NotifyConfig `yaml:"on"`
+		Now    NotifyConfig `yaml:"now"`
+	}
+	data := `
+global: failure
+task:
+  when: change
+  via: [osc777, bell]
+  after: 1m
+off: false
+on: true
+now:
+  after: 0s
+`
+	if err := yaml.Unmarshal([]byte(data), &cfg); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if cfg.Global.When != "failure" || !cfg.Global.set {
+		t.Fatalf("unexpected global: %+v", cfg.Global)
+	}
+	if cfg.Task.When != "change" || len(cfg.Task.Via) != 2 || cfg.Task.after() != time.Minute {
+		t.Fatalf("unexpected task: %+v", cfg.Task)
+	}
+	if cfg.Off.When != "never" {
+		t.Fatalf("expected false to disable notifications, got %+v", cfg.Off)
+	}
+
+	if !cfg.On.set || cfg.On.When != "" {
+		t.Fatalf("expected true to turn on the default, got %+v", cfg.On)
+	}
+	if merged := mergeNotify(cfg.Task, cfg.Now); merged.after() != 0 || merged.When != "change" {
+		t.Fatalf("expected after: 0s to turn off the threshold, got %+v", merged)
+	}
+
+	merged := mergeNotify(cfg.Global, cfg.Task)
+	if merged.When != "change" || merged.after() != time.Minute {
+		t.Fatalf("unexpected merge: %+v", merged)
+	}
+}
+
+func TestNotifyConfigValidate(t *testing.T) {
+	bad := []NotifyConfig{
+		{When: "sometimes"},
+		{Via: []string{"pager"}},
+		{Via: []string{"command"}},
+	}
+	for _, cfg := range bad {
+		if err := cfg.validate(); err == nil {
+			t.Fatalf("expected error for %+v", cfg)
+		}
+	}
+	if err := (NotifyConfig{Via: []string{"command"}, Command: "notify-send hi"}).validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestShouldNotify(t *testing.T) {
+	failure := NotifyConfig{When: "failure", set: true}
+	change := NotifyConfig{When: "change", set: true}
+	second := time.Second
+	always := NotifyConfig{When: "always", set: true, After: &second}
+	on := NotifyConfig{set: true}
+	long := time.Minute
+
+	cases := []struct {
+		name     string
+		cfg      NotifyConfig
+		status   TaskStatus
+		previous TaskStatus
+		elapsed  time.Duration
+		want     bool
+	}{
+		{"unset", NotifyConfig{}, StatusFailed, StatusIdle, long, false},
+		{"failure", failure, StatusFailed, StatusIdle, long, true},
+		{"failure passes", failure, StatusSuccess, StatusIdle, long, false},
+		{"too short", failure, StatusFailed, StatusIdle, time.Second, false},
+		{"change first run", change, StatusSuccess, StatusIdle, long, false},
+		{"change same", change, StatusFailed, StatusFailed, long, false},
+		{"change differs", change, StatusSuccess, StatusFailed, long, true},
+		{"always", always, StatusCanceled, StatusIdle, 2 * time.Second, true},
+		{"true fails", on, StatusFailed, StatusIdle, long, true},
+		{"true passes", on, StatusSuccess, StatusIdle, long, false},
+	}
+	for _, tc := range cases {
+		if got := shouldNotify(tc.cfg, tc.status, tc.previous, tc.elapsed); got != tc.want {
+			t.Fatalf("%s: expected %v, got %v", tc.name, tc.want, got)
+		}
+	}
+}
+
+func TestSanitizeNotification(t *testing.T) {
+	if got := sanitizeNotification("a;b\x07c\x1bd"); got != "a b c d" {
+		t.Fatalf("unexpected sanitized text: %q", got)
+	}
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"os"
+	"strings"
+	"sync/atomic"
+
+	"github.com/charmbracelet/x/ansi"
+)
+
+// defaultMaxLines is how many lines of each output stay in memory unless
+// max_lines says otherwise.
+const defaultMaxLines = 10000
+
+// outputMaxLines is the max_lines setting; 0 or less keeps everything in
+// memory.
+var outputMaxLines atomic.Int64
+
+func init() {
+	outputMaxLines.Store(defaultMaxLines)
+}
+
+func setOutputLimit(maxLines int) {
+	if maxLines == 0 {
+		maxLines = defaultMaxLines
+	}
+	outputMaxLines.Store(int64(maxLines))
+}
+
+// spillBlock is how many spilled lines share one offset in the spill index.
+const spillBlock = 256
+
+// outputBuffer is the output of one task or step. The newest max_lines
+// lines are kept in memory in a ring; older lines spill to a temp file, so
+// the whole run can still be scrolled and searched while memory stays flat.
+// The zero value is empty and ready to use.
+type outputBuffer struct {
+	lines []string
+	head  int // index of the oldest line in lines once the ring is full
+	spill *spillFile
+	// resets counts Reset calls and edits counts Set calls, so a view of
+	// the buffer can tell it changed other than by new lines at the end.
+	resets int
+	edits  int
+}
+
+// spillFile holds the lines that left the ring, oldest first, each stored
+// as a uvarint length followed by the bytes.
+type spillFile struct {
+	file  *os.File
+	size  int64
+	count int
+	index []int64 // offset of every spillBlock-th line
+}
+
+func (b *outputBuffer) Len() int {
+	return b.spilled() + len(b.lines)
+}
+
+func (b *outputBuffer) spilled() int {
+	if b.spill == nil {
+		return 0
+	}
+	return b.spill.count
+}
+
+func (b *outputBuffer) Append(line string) {
+	limit := int(outputMaxLines.Load())
+	// A ring that has wrapped stays its size even if the limit grows.
+	if limit <= 0 || (len(b.lines) < limit && b.head == 0) {
+		b.lines = append(b.lines, line)
+		return
+	}
+	if b.spill == nil {
+		spill, err := newSpillFile()
+		if err != nil {
+			// Without a temp file the oldest lines are dropped.
+			b.lines[b.head] = line
+			b.head = (b.head + 1) % len(b.lines)
+			return
+		}
+		b.spill = spill
+	}
+	if err := b.spill.write(b.lines[b.head]); err != nil {
+		b.spill.close()
+		b.spill = nil
+	}
+	b.lines[b.head] = line
+	b.head = (b.head + 1) % len(b.lines)
+}
+
+// Reset empties the buffer and removes its spill file.
+func (b *outputBuffer) Reset() {
+	b.spill.close()
+	*b = outputBuffer{resets: b.resets + 1}
+}
+
+// Set replaces line i if it is still in memory, for terminal output that
+// redraws earlier lines.
+func (b *outputBuffer) Set(i int, line string) {
+	spilled := b.spilled()
+	if i < spilled || i >= b.Len() {
+		return
+	}
+	b.lines[(b.head+i-spilled)%len(b.lines)] = line
+	b.edits++
+}
+
+// Lines returns lines [from, to).
+func (b *outputBuffer) Lines(from, to int) []string {
+	from = max(from, 0)
+	to = min(to, b.Len())
+	if from >= to {
+		return nil
+	}
+	out := make([]string, 0, to-from)
+	spilled := b.spilled()
+	if from < spilled {
+		out = b.spill.read(from, min(to, spilled), out)
+		from = spilled
+	}
+	for i := from; i < to; i++ {
+		out = append(out, b.memoryLine(i-spilled))
+	}
+	return out
+}
+
+// All returns every line, reading the spill file.
+func (b *outputBuffer) All() []string {
+	return b.Lines(0, b.Len())
+}
+
+func (b *outputBuffer) Tail(n int) []string {
+	return b.Lines(b.Len()-n, b.Len())
+}
+
+// Last is the plain text of the last line, trimmed.
+func (b *outputBuffer) Last() string {
+	if len(b.lines) == 0 {
+		return ""
+	}
+	return strings.TrimSpace(ansi.Strip(b.memoryLine(len(b.lines) - 1)))
+}
+
+func (b *outputBuffer) memoryLine(i int) string {
+	return b.lines[(b.head+i)%len(b.lines)]
+}
+
+// Find returns the first line at or after from (before from when backward)
+// whose plain text contains query. A query without upper case letters
+// matches any case.
+func (b *outputBuffer) Find(query string, from int, backward bool) (int, bool) {
+	if query == "" {
+		return 0, false
+	}
+	fold := strings.ToLower(query) == query
+	match := func(line string) bool {
+		line = ansi.Strip(line)
+		if fold {
+			line = strings.ToLower(line)
+		}
+		return strings.Contains(line, query)
+	}
+	if backward {
+		// Scan in chunks from the end so spilled lines are read in order.
+		for end := min(from, b.Len()); end > 0; {
+			start := max(end-spillBlock*4, 0)
+			chunk := b.Lines(start, end)
+			for i := len(chunk) - 1; i >= 0; i-- {
+				if match(chunk[i]) {
+					return start + i, true
+				}
+			}
+			end = start
+		}
+		return 0, false
+	}
+	for start := max(from, 0); start < b.Len(); start += spillBlock * 4 {
+		for i, line := range b.Lines(start, start+spillBlock*4) {
+			if match(line) {
+				return start + i, true
+			}
+		}
+	}
+	return 0, false
+}
+
+func newSpillFile() (*spillFile, error) {
+	file, err := os.CreateTemp("", "suite-output-*")
+	if err != nil {
+		return nil, err
+	}
+	// Unlinked right away where the OS allows it, so nothing is left
+	// behind if suite dies.
+	_ = os.Remove(file.Name())
+	return &spillFile{file: file}, nil
+}
+
+func (s *spillFile) write(line string) error {
+	if s.count%spillBlock == 0 {
+		s.index = append(s.index, s.size)
+	}
+	record := binary.AppendUvarint(make([]byte, 0, len(line)+binary.MaxVarintLen64), uint64(len(line)))
+	record = append(record, line...)
+	n, err := s.file.WriteAt(record, s.size)
+	s.size += int64(n)
+	if err != nil {
+		return err
+	}
+	s.count++
+	return nil
+}
+
+// read appends spilled lines [from, to) to out.
+func (s *spillFile) read(from, to int, out []string) []string {
+	block := from / spillBlock
+	reader := bufio.NewReader(io.NewSectionReader(s.file, s.index[block], s.size-s.index[block]))
+	for i := block * spillBlock; i < to; i++ {
+		length, err := binary.ReadUvarint(reader)
+		if err != nil {
+			break
+		}
+		if i < from {
+			if _, err := reader.Discard(int(length)); err != nil {
+				break
+			}
+			continue
+		}
+		data := make([]byte, length)
+		if _, err := io.ReadFull(reader, data); err != nil {
+			break
+		}
+		out = append(out, string(data))
+	}
+	return out
+}
+
+func (s *spillFile) close() {
+	if s == nil {
+		return
+	}
+	_ = s.file.Close()
+	_ = os.Remove(s.file.Name())
+}
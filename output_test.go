@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestOutputBufferSpillsOldLines(t *testing.T) {
+	setOutputLimit(4)
+	t.Cleanup(func() { setOutputLimit(0) })
+
+	var buf outputBuffer
+	var want []string
+	for i := 0; i < 1000; i++ {
+		line := fmt.Sprintf("line %d", i)
+		buf.Append(line)
+		want = append(want, line)
+	}
+	if buf.Len() != 1000 || len(buf.lines) != 4 {
+		t.Fatalf("expected 1000 lines with 4 in memory, got %d with %d", buf.Len(), len(buf.lines))
+	}
+	if got := buf.All(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected every line back in order, got %d lines", len(got))
+	}
+	// Across a spill index block and into the ring.
+	if got := buf.Lines(250, 260); !reflect.DeepEqual(got, want[250:260]) {
+		t.Fatalf("unexpected lines %v", got)
+	}
+	if got := buf.Lines(994, 2000); !reflect.DeepEqual(got, want[994:]) {
+		t.Fatalf("unexpected lines %v", got)
+	}
+	if got := buf.Tail(2); !reflect.DeepEqual(got, want[998:]) || buf.Last() != "line 999" {
+		t.Fatalf("unexpected tail %v / %q", got, buf.Last())
+	}
+
+	buf.Reset()
+	if buf.Len() != 0 || buf.spill != nil || buf.Last() != "" {
+		t.Fatalf("expected an empty buffer after reset")
+	}
+}
+
+func TestOutputBufferUnbounded(t *testing.T) {
+	setOutputLimit(-1)
+	t.Cleanup(func() { setOutputLimit(0) })
+
+	var buf outputBuffer
+	for i := 0; i < 20000; i++ {
+		buf.Append("x")
+	}
+	if buf.spill != nil || len(buf.lines) != 20000 {
+		t.Fatalf("expected everything in memory, got %d", len(buf.lines))
+	}
+}
+
+func TestOutputBufferFind(t *testing.T) {
+	setOutputLimit(2)
+	t.Cleanup(func() { setOutputLimit(0) })
+
+	var buf outputBuffer
+	for _, line := range []string{"\x1b[31mError\x1b[0m: one", "ok", "error: two", "ok", "ERROR: three"} {
+		buf.Append(line)
+	}
+	if line, ok := buf.Find("error", 0, false); !ok || line != 0 {
+		t.Fatalf("expected the colored spilled line to match, got %d %v", line, ok)
+	}
+	if line, ok := buf.Find("error", 1, false); !ok || line != 2 {
+		t.Fatalf("expected the next match at 2, got %d %v", line, ok)
+	}
+	if line, ok := buf.Find("ERROR", 0, false); !ok || line != 4 {
+		t.Fatalf("expected upper case to match exactly, got %d %v", line, ok)
+	}
+	if line, ok := buf.Find("error", 4, true); !ok || line != 2 {
+		t.Fatalf("expected the previous match at 2, got %d %v", line, ok)
+	}
+	if _, ok := buf.Find("missing", 0, false); ok {
+		t.Fatalf("expected no match")
+	}
+}
+
+func TestSearchJumpsIntoSpilledOutput(t *testing.T) {
+	cfg := Config{
+		MaxLines: 50,
+		Tasks: []TaskDef{
+			{Name: "web", Key: "w", Persistent: true, Cmd: StepList{{Value: "bin/server", Kind: StepCommand}}},
+		},
+		SidebarWidth: 32,
+	}
+	m := newModel(cfg)
+	t.Cleanup(func() { setOutputLimit(0) })
+	updated, _ := m.Update(tea.WindowSizeMsg{Width: 100, Height: 20})
+	m = updated.(model)
+	m.handleTaskStarted("web")
+	for i := 0; i < 3000; i++ {
+		m.handleOutput(TaskOutputMsg{Target: "web", Line: fmt.Sprintf("request %d", i)})
+	}
+	m.flushOutput()
+	if m.outputStart != 3000-outputWindowLines || m.view.end != 3000 {
+		t.Fatalf("expected the window to follow the end, got [%d, %d)", m.outputStart, m.view.end)
+	}
+
+	m.focus = focusOutput
+	for _, key := range []tea.KeyMsg{
+		{Type: tea.KeyRunes, Runes: []rune("/")},
+		{Type: tea.KeyRunes, Runes: []rune("request 12")},
+		{Type: tea.KeyEnter},
+	} {
+		updated, _ = m.Update(key)
+		m = updated.(model)
+	}
+	if top := m.outputStart + m.viewport.YOffset; top != 12 || m.autoScroll {
+		t.Fatalf("expected line 12 at the top, got %d", top)
+	}
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("n")})
+	m = updated.(model)
+	if top := m.outputStart + m.viewport.YOffset; top != 120 {
+		t.Fatalf("expected the next match at 120, got %d", top)
+	}
+	if got := m.statusBarLine(m.selectedEntry()); got != "/request 12  line 121 · n next · N previous · esc clear" {
+		t.Fatalf("unexpected status line %q", got)
+	}
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// planCommandMsg reports the invocation a dry run would start for target.
+type planCommandMsg struct {
+	Target string
+	Args   []string
+	Dir    string
+	Env    []string // variables added to or changed from the inherited env
+}
+
+// planNoteMsg annotates a dry-run plan with conditions and hooks. Condition
+// notes arrive before the header of the task or step they gate.
+type planNoteMsg struct {
+	Target    string
+	Text      string
+	Condition bool
+}
+
+type dryRunKey struct{}
+
+// withDryRun makes the runner walk a task without starting anything: command
+// steps report planCommandMsg instead of running and conditions pass.
+func withDryRun(ctx context.Context) context.Context {
+	return context.WithValue(ctx, dryRunKey{}, true)
+}
+
+func isDryRun(ctx context.Context) bool {
+	dry, _ := ctx.Value(dryRunKey{}).(bool)
+	return dry
+}
+
+// planCommand describes the exec.Cmd runSingle would start.
+func planCommand(ctx context.Context, command, shell string, init CommandList, target string) planCommandMsg {
+	cmd := shellCommand(ctx, command, shell, init)
+	inherited := make(map[string]string)
+	for _, entry := range os.Environ() {
+		if key, value, ok := strings.Cut(entry, "="); ok {
+			inherited[key] = value
+		}
+	}
+	changed := []string{}
+	for _, entry := range cmd.Env {
+		key, value, _ := strings.Cut(entry, "=")
+		if prev, ok := inherited[key]; !ok || prev != value {
+			changed = append(changed, entry)
+		}
+	}
+	sort.Strings(changed)
+	dir := cmd.Dir
+	if dir == "" {
+		dir, _ = os.Getwd()
+	}
+	return planCommandMsg{Target: target, Args: cmd.Args, Dir: dir, Env: changed}
+}
+
+// explainTask dry-runs taskName through runTask and renders the plan.
+func explainTask(cfg Config, taskName string, resolve TaskResolver) []string {
+	def, ok := resolve(taskName)
+	if !ok {
+		return []string{fmt.Sprintf("unknown task %q", taskName)}
+	}
+	msgCh := make(chan tea.Msg, 128)
+	go runTask(withDryRun(context.Background()), taskName, def, cfg.Shell, cfg.Init, resolve, msgCh)
+
+	p := newPlanPrinter(cfg, taskName, resolve)
+	for msg := range msgCh {
+		p.handle(msg)
+	}
+	return p.lines
+}
+
+func newPlanPrinter(cfg Config, title string, resolve TaskResolver) *planPrinter {
+	cwd, _ := os.Getwd()
+	p := &planPrinter{resolve: resolve, cwd: cwd, steps: map[string]planStep{}, indents: map[string]string{}}
+	p.lines = append(p.lines, fmt.Sprintf("plan for %s (nothing is run)", title), "cwd: "+cwd)
+	if cfg.Jobs > 0 {
+		p.lines = append(p.lines, fmt.Sprintf("jobs: at most %d commands at once", cfg.Jobs))
+	}
+	p.lines = append(p.lines, "")
+	return p
+}
+
+type planStep struct {
+	marker string
+	label  string
+	kind   StepKind
+	allow  bool
+}
+
+type planPrinter struct {
+	resolve TaskResolver
+	cwd     string
+	lines   []string
+	steps   map[string]planStep
+	depth   int
+	prefix  string            // marker for the task header a task step is about to print
+	indents map[string]string // target -> indent for its commands and notes
+	notes   []string
+}
+
+func (p *planPrinter) handle(msg tea.Msg) {
+	switch msg := msg.(type) {
+	case planNoteMsg:
+		if msg.Condition {
+			p.notes = append(p.notes, msg.Text)
+			return
+		}
+		p.lines = append(p.lines, p.indents[msg.Target]+msg.Text)
+	case TaskStartedMsg:
+		def, _ := p.resolve(msg.TaskName)
+		mode, steps, multi := taskSteps(msg.TaskName, def, p.resolve)
+		for idx, step := range steps {
+			marker := "∥ "
+			if mode != StepModeParallel {
+				marker = fmt.Sprintf("%d. ", idx+1)
+			}
+			kind, _ := resolveStepKind(step, p.resolve)
+			p.steps[stepID(msg.TaskName, mode, idx)] = planStep{
+				marker: marker,
+				label:  firstLine(stepDisplayName(step)),
+				kind:   kind,
+				allow:  step.AllowFailure || multi && !taskStopOnFail(def),
+			}
+		}
+		p.header(msg.TaskName, p.prefix+msg.TaskName+": "+planTaskDetails(def, mode, multi, len(steps)))
+		p.prefix = ""
+		p.depth++
+	case TaskFinishedMsg:
+		if p.depth > 0 {
+			p.depth--
+		}
+	case StepStartedMsg:
+		step := p.steps[msg.StepID]
+		if step.kind == StepTask {
+			p.prefix = step.marker
+			return
+		}
+		label := step.marker + step.label
+		if step.allow {
+			label += " (failure allowed)"
+		}
+		p.header(msg.StepID, label)
+	case planCommandMsg:
+		indent := p.indents[msg.Target]
+		args := make([]string, len(msg.Args))
+		for i, arg := range msg.Args {
+			args[i] = shellQuote(arg)
+		}
+		for i, line := range strings.Split("$ "+strings.Join(args, " "), "\n") {
+			if i > 0 {
+				line = "  " + line
+			}
+			p.lines = append(p.lines, indent+line)
+		}
+		if msg.Dir != p.cwd {
+			p.lines = append(p.lines, indent+"  cwd: "+msg.Dir)
+		}
+		if len(msg.Env) > 0 {
+			p.lines = append(p.lines, indent+"  env: "+strings.Join(msg.Env, " "))
+		}
+	}
+}
+
+// header prints the line for a task or step, followed by any conditions
+// that gate it.
+func (p *planPrinter) header(target, text string) {
+	indent := strings.Repeat("  ", p.depth)
+	p.lines = append(p.lines, indent+text)
+	p.indents[target] = indent + "  "
+	for _, note := range p.notes {
+		p.lines = append(p.lines, indent+"  "+note)
+	}
+	p.notes = nil
+}
+
+func planTaskDetails(def TaskDef, mode StepMode, multi bool, steps int) string {
+	parts := []string{listModeName(mode, multi)}
+	if multi {
+		parts[0] = fmt.Sprintf("%s, %d steps", parts[0], steps)
+	}
+	if !def.Matrix.IsZero() {
+		parts = append(parts, "matrix")
+	}
+	switch {
+	case mode == StepModeParallel && def.FailFast:
+		parts = append(parts, "cancels the rest on first failure")
+	case mode == StepModeParallel:
+		parts = append(parts, "waits for all steps")
+	case multi && taskStopOnFail(def):
+		parts = append(parts, "stops on first failure")
+	case multi:
+		parts = append(parts, "continues past failures")
+	}
+	if def.MaxParallel > 0 {
+		parts = append(parts, fmt.Sprintf("at most %d at once", def.MaxParallel))
+	}
+	if def.Persistent {
+		parts = append(parts, "persistent")
+	}
+	return strings.Join(parts, ", ")
+}
+
+func conditionText(cond Condition) string {
+	parts := []string{}
+	if cond.Cmd != "" {
+		parts = append(parts, "cmd "+firstLine(cond.Cmd))
+	}
+	for _, item := range []struct {
+		name   string
+		values []string
+	}{{"env", cond.Env}, {"os", cond.OS}, {"exists", cond.Exists}, {"changed", cond.Changed}} {
+		if len(item.values) > 0 {
+			parts = append(parts, item.name+" "+strings.Join(item.values, ", "))
+		}
+	}
+	return "if: " + strings.Join(parts, "; ") + " (not evaluated)"
+}
+
+func shellQuote(arg string) string {
+	if arg != "" && !strings.ContainsAny(arg, " \t\n'\"\\$`;&|<>()*?[]{}!#~") {
+		return arg
+	}
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
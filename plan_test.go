@@ -0,0 +1,75 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestExplainTaskPlan(t *testing.T) {
+	t.Setenv("SHELL", "/bin/sh")
+	marker := filepath.Join(t.TempDir(), "ran")
+	cfg := Config{
+		Shell: "/bin/sh",
+		Init:  CommandList{"export A=1"},
+		Tasks: []TaskDef{
+			{Name: "lint", Parallel: StepList{
+				{Value: "touch " + marker, Name: "touch", Kind: StepCommand},
+				{Value: "echo b", Kind: StepCommand},
+			}},
+			{Name: "full", Seq: StepList{
+				{Value: "lint", Kind: StepAuto},
+				{Value: "bundle install", Kind: StepCommand, If: Condition{Exists: []string{"Gemfile"}}},
+				{Value: "rubocop", Kind: StepCommand, AllowFailure: true, Env: map[string]string{"RUBY": "3.3"}},
+			}},
+		},
+	}
+
+	lines := explainTask(cfg, "full", configResolver(cfg))
+	got := strings.Join(lines[3:], "\n")
+	want := strings.Join([]string{
+		"full: seq, 3 steps, stops on first failure",
+		"  1. lint: parallel, 2 steps, waits for all steps",
+		"    ∥ touch",
+		"      $ /bin/sh -c 'export A=1; touch " + marker + "'",
+		"    ∥ echo b",
+		"      $ /bin/sh -c 'export A=1; echo b'",
+		"  2. bundle install",
+		"    if: exists Gemfile (not evaluated)",
+		"    $ /bin/sh -c 'export A=1; bundle install'",
+		"  3. rubocop (failure allowed)",
+		"    $ /bin/sh -c 'export A=1; rubocop'",
+		"      env: RUBY=3.3",
+	}, "\n")
+	if got != want {
+		t.Fatalf("unexpected plan:\n%s", got)
+	}
+	if _, err := os.Stat(marker); err == nil {
+		t.Fatalf("dry run executed a command")
+	}
+}
+
+func TestExplainKeyTogglesPlan(t *testing.T) {
+	cfg := Config{
+		Shell:        "/bin/sh",
+		SidebarWidth: 32,
+		Tasks:        []TaskDef{{Name: "test", Cmd: StepList{{Value: "bin/test", Kind: StepCommand}}}},
+	}
+	m := newModel(cfg)
+	m.setSize(120, 40)
+
+	updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyCtrlE})
+	m = updated.(model)
+	if m.explainID == "" || !strings.Contains(m.viewport.View(), "$ /bin/sh -c bin/test") {
+		t.Fatalf("expected plan in output, got:\n%s", m.viewport.View())
+	}
+
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyCtrlE})
+	m = updated.(model)
+	if m.explainID != "" {
+		t.Fatalf("expected explain to toggle off")
+	}
+}
@@ -0,0 +1,130 @@
+//go:build linux
+
+package main
+
+import (
+	"bytes"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// listProcesses reads the process table from /proc, falling back to ps when
+// /proc isn't mounted.
+func listProcesses() ([]procInfo, error) {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return psProcesses()
+	}
+	procs := make([]procInfo, 0, len(entries))
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		data, err := os.ReadFile("/proc/" + entry.Name() + "/stat")
+		if err != nil {
+			// Exited while we were reading.
+			continue
+		}
+		if proc, ok := parseProcStat(pid, data); ok {
+			procs = append(procs, proc)
+		}
+	}
+	if len(procs) == 0 {
+		return psProcesses()
+	}
+	return procs, nil
+}
+
+// clockTicks is USER_HZ, the unit of the CPU times in /proc/<pid>/stat. It
+// is 100 on every architecture Linux supports.
+const clockTicks = 100
+
+// parseProcStat reads ppid, pgrp, CPU time and RSS from a /proc/<pid>/stat
+// line. The command name in parentheses may contain spaces and parentheses,
+// so fields are counted from the last ')'.
+func parseProcStat(pid int, data []byte) (procInfo, bool) {
+	end := bytes.LastIndexByte(data, ')')
+	if end < 0 {
+		return procInfo{}, false
+	}
+	// state ppid pgrp session tty tpgid flags minflt cminflt majflt cmajflt
+	// utime stime cutime cstime priority nice threads itrealvalue starttime
+	// vsize rss ...
+	fields := strings.Fields(string(data[end+1:]))
+	if len(fields) < 3 {
+		return procInfo{}, false
+	}
+	ppid, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return procInfo{}, false
+	}
+	pgid, err := strconv.Atoi(fields[2])
+	if err != nil {
+		return procInfo{}, false
+	}
+	proc := procInfo{PID: pid, PPID: ppid, PGID: pgid}
+	if len(fields) > 21 {
+		utime, _ := strconv.ParseUint(fields[11], 10, 64)
+		stime, _ := strconv.ParseUint(fields[12], 10, 64)
+		rss, _ := strconv.ParseInt(fields[21], 10, 64)
+		proc.CPU = time.Duration(utime+stime) * time.Second / clockTicks
+		if rss > 0 {
+			proc.RSS = uint64(rss) * uint64(os.Getpagesize())
+		}
+	}
+	return proc, true
+}
+
+// processStartTime reads when pid started from the starttime field of
+// /proc/<pid>/stat (clock ticks since boot) and the boot time in /proc/stat.
+func processStartTime(pid int) (time.Time, bool) {
+	data, err := os.ReadFile("/proc/" + strconv.Itoa(pid) + "/stat")
+	if err != nil {
+		return psStartTime(pid)
+	}
+	end := bytes.LastIndexByte(data, ')')
+	if end < 0 {
+		return time.Time{}, false
+	}
+	fields := strings.Fields(string(data[end+1:]))
+	if len(fields) < 20 {
+		return time.Time{}, false
+	}
+	ticks, err := strconv.ParseInt(fields[19], 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	boot, ok := bootTime()
+	if !ok {
+		return time.Time{}, false
+	}
+	return boot.Add(time.Duration(ticks) * time.Second / clockTicks), true
+}
+
+func bootTime() (time.Time, bool) {
+	data, err := os.ReadFile("/proc/stat")
+	if err != nil {
+		return time.Time{}, false
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if value, ok := strings.CutPrefix(line, "btime "); ok {
+			secs, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+			if err != nil {
+				return time.Time{}, false
+			}
+			return time.Unix(secs, 0), true
+		}
+	}
+	return time.Time{}, false
+}
+
+func processArgs(pid int) string {
+	data, err := os.ReadFile("/proc/" + strconv.Itoa(pid) + "/cmdline")
+	if err != nil || len(data) == 0 {
+		return psArgs(pid)
+	}
+	return strings.TrimSpace(strings.ReplaceAll(string(data), "\x00", " "))
+}
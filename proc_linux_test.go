@@ -0,0 +1,56 @@
+//go:build linux
+
+package main
+
+import (
+	"os/exec"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestParseProcStat(t *testing.T) {
+	proc, ok := parseProcStat(42, []byte("42 (tmux: server (1)) S 7 42 42 0 -1 4194560 0 0"))
+	if !ok || proc.PPID != 7 || proc.PGID != 42 {
+		t.Fatalf("unexpected parse: %#v %v", proc, ok)
+	}
+	if _, ok := parseProcStat(1, []byte("garbage")); ok {
+		t.Fatalf("expected malformed stat to be rejected")
+	}
+}
+
+func TestDescendantPIDsFromProc(t *testing.T) {
+	cmd := exec.Command("/bin/sh", "-c", "sleep 30 & echo started; wait")
+	prepareCommand(cmd)
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { killProcess(cmd); _ = cmd.Wait() })
+	buf := make([]byte, 8)
+	if _, err := out.Read(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		pids, err := descendantPIDs(cmd.Process.Pid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		procs, _ := listProcesses()
+		found := slices.ContainsFunc(procs, func(p procInfo) bool {
+			return slices.Contains(pids, p.PID) && p.PGID == cmd.Process.Pid
+		})
+		if found {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected sleep among descendants of %d, got %v", cmd.Process.Pid, pids)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
@@ -0,0 +1,17 @@
+//go:build !linux && !windows
+
+package main
+
+import "time"
+
+func listProcesses() ([]procInfo, error) {
+	return psProcesses()
+}
+
+func processArgs(pid int) string {
+	return psArgs(pid)
+}
+
+func processStartTime(pid int) (time.Time, bool) {
+	return psStartTime(pid)
+}
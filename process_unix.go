@@ -3,11 +3,15 @@
 package main
 
 import (
+	"fmt"
+	"os"
 	"os/exec"
+	"os/signal"
 	"strconv"
 	"strings"
 	"syscall"
 	"time"
+	"unsafe"
 )
 
 func prepareCommand(cmd *exec.Cmd) {
@@ -27,6 +31,13 @@ func killProcess(cmd *exec.Cmd) {
 	_ = cmd.Process.Kill()
 }
 
+// userPrivate reports whether info belongs to the current user and nobody
+// else can read or write it.
+func userPrivate(info os.FileInfo) bool {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	return ok && int(stat.Uid) == os.Getuid() && info.Mode().Perm()&0o077 == 0
+}
+
 func killProcessGroup(pid int, sig syscall.Signal) {
 	pgid, err := syscall.Getpgid(pid)
 	if err != nil || pgid <= 0 {
@@ -35,6 +46,26 @@ func killProcessGroup(pid int, sig syscall.Signal) {
 	_ = syscall.Kill(-pgid, sig)
 }
 
+var signalsByName = map[string]syscall.Signal{
+	"HUP": syscall.SIGHUP, "INT": syscall.SIGINT, "QUIT": syscall.SIGQUIT, "TERM": syscall.SIGTERM,
+	"USR1": syscall.SIGUSR1, "USR2": syscall.SIGUSR2, "TTIN": syscall.SIGTTIN, "TTOU": syscall.SIGTTOU,
+	"WINCH": syscall.SIGWINCH, "CONT": syscall.SIGCONT, "STOP": syscall.SIGSTOP, "KILL": syscall.SIGKILL,
+}
+
+// signalProcessGroup sends the named signal (see menuSignals) to the
+// process group of pid.
+func signalProcessGroup(pid int, name string) error {
+	sig, ok := signalsByName[name]
+	if !ok {
+		return fmt.Errorf("unknown signal %q", name)
+	}
+	pgid, err := syscall.Getpgid(pid)
+	if err != nil {
+		return err
+	}
+	return syscall.Kill(-pgid, sig)
+}
+
 func killDescendants(root int, sig syscall.Signal) {
 	pids, err := descendantPIDs(root)
 	if err != nil {
@@ -46,25 +77,18 @@ func killDescendants(root int, sig syscall.Signal) {
 }
 
 func descendantPIDs(root int) ([]int, error) {
-	out, err := exec.Command("ps", "-axo", "pid=,ppid=").Output()
+	procs, err := listProcesses()
 	if err != nil {
 		return nil, err
 	}
+	return descendantsOf(procs, root), nil
+}
+
+// descendantsOf walks procs from root, returning every process below it.
+func descendantsOf(procs []procInfo, root int) []int {
 	children := make(map[int][]int)
-	for _, line := range strings.Split(string(out), "\n") {
-		fields := strings.Fields(line)
-		if len(fields) < 2 {
-			continue
-		}
-		pid, err := strconv.Atoi(fields[0])
-		if err != nil {
-			continue
-		}
-		ppid, err := strconv.Atoi(fields[1])
-		if err != nil {
-			continue
-		}
-		children[ppid] = append(children[ppid], pid)
+	for _, proc := range procs {
+		children[proc.PPID] = append(children[proc.PPID], proc.PID)
 	}
 
 	descendants := []int{}
@@ -82,5 +106,201 @@ func descendantPIDs(root int) ([]int, error) {
 			stack = append(stack, child)
 		}
 	}
-	return descendants, nil
+	return descendants
+}
+
+// processTree lists roots, their descendants and the extra pids (cgroup
+// members that may have left the tree), each once.
+func processTree(roots, extra []int) []procInfo {
+	procs, err := listProcesses()
+	if err != nil {
+		return nil
+	}
+	want := make(map[int]bool)
+	for _, root := range roots {
+		want[root] = true
+		for _, pid := range descendantsOf(procs, root) {
+			want[pid] = true
+		}
+	}
+	for _, pid := range extra {
+		want[pid] = true
+	}
+	var tree []procInfo
+	for _, proc := range procs {
+		if want[proc.PID] {
+			tree = append(tree, proc)
+		}
+	}
+	return tree
+}
+
+// psProcesses lists processes with ps, for systems without /proc.
+func psProcesses() ([]procInfo, error) {
+	out, err := exec.Command("ps", "-axo", "pid=,ppid=,pgid=,rss=,time=").Output()
+	if err != nil {
+		return nil, err
+	}
+	var procs []procInfo
+	for _, line := range strings.Split(string(out), "\n") {
+		if proc, ok := parsePSLine(line); ok {
+			procs = append(procs, proc)
+		}
+	}
+	return procs, nil
+}
+
+// parsePSLine reads "pid ppid pgid rss time", with rss in KiB.
+func parsePSLine(line string) (procInfo, bool) {
+	fields := strings.Fields(line)
+	if len(fields) < 5 {
+		return procInfo{}, false
+	}
+	var ids [4]int
+	for i := range ids {
+		n, err := strconv.Atoi(fields[i])
+		if err != nil {
+			return procInfo{}, false
+		}
+		ids[i] = n
+	}
+	cpu, _ := parseCPUTime(fields[4])
+	return procInfo{PID: ids[0], PPID: ids[1], PGID: ids[2], RSS: uint64(ids[3]) * 1024, CPU: cpu}, true
+}
+
+// parseCPUTime reads ps's cumulative CPU time, "[[dd-]hh:]mm:ss[.ff]".
+func parseCPUTime(value string) (time.Duration, bool) {
+	var days int
+	if d, rest, ok := strings.Cut(value, "-"); ok {
+		n, err := strconv.Atoi(d)
+		if err != nil {
+			return 0, false
+		}
+		days, value = n, rest
+	}
+	parts := strings.Split(value, ":")
+	if len(parts) > 3 {
+		return 0, false
+	}
+	seconds, err := strconv.ParseFloat(parts[len(parts)-1], 64)
+	if err != nil {
+		return 0, false
+	}
+	total := time.Duration(seconds * float64(time.Second))
+	unit := time.Minute
+	for i := len(parts) - 2; i >= 0; i-- {
+		n, err := strconv.Atoi(parts[i])
+		if err != nil {
+			return 0, false
+		}
+		total += time.Duration(n) * unit
+		unit *= 60
+	}
+	return total + time.Duration(days)*24*time.Hour, true
+}
+
+// psArgs returns the command line of pid as ps shows it.
+// psStartTime asks ps when pid started. lstart has whole seconds.
+func psStartTime(pid int) (time.Time, bool) {
+	out, err := exec.Command("ps", "-o", "lstart=", "-p", strconv.Itoa(pid)).Output()
+	if err != nil {
+		return time.Time{}, false
+	}
+	started, err := time.ParseInLocation("Mon Jan _2 15:04:05 2006", strings.Join(strings.Fields(string(out)), " "), time.Local)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return started, true
+}
+
+func psArgs(pid int) string {
+	out, err := exec.Command("ps", "-o", "args=", "-p", strconv.Itoa(pid)).Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// foregroundCommand makes cmd the foreground process group of tty, so it
+// reads the keyboard and ctrl+c reaches it rather than suite. The returned
+// func takes the terminal back after cmd exits.
+func foregroundCommand(cmd *exec.Cmd, tty *os.File) func() {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setpgid = true
+	cmd.SysProcAttr.Foreground = true
+	// Foreground takes the descriptor as numbered in suite, not the child.
+	cmd.SysProcAttr.Ctty = int(tty.Fd())
+	return func() {
+		// suite is a background process group until this succeeds, and
+		// those get SIGTTOU for changing the terminal.
+		signal.Ignore(syscall.SIGTTOU)
+		defer signal.Reset(syscall.SIGTTOU)
+		pgrp := syscall.Getpgrp()
+		_, _, _ = syscall.Syscall(syscall.SYS_IOCTL, tty.Fd(), uintptr(syscall.TIOCSPGRP), uintptr(unsafe.Pointer(&pgrp)))
+	}
+}
+
+// detachCommand starts cmd in its own session so it outlives the terminal.
+func detachCommand(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+}
+
+func processAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	err := syscall.Kill(pid, 0)
+	return err == nil || err == syscall.EPERM
+}
+
+// processGroupMembers lists "pid command" for every process in pgid.
+func processGroupMembers(pgid int) []string {
+	if pgid <= 0 {
+		return nil
+	}
+	if err := syscall.Kill(-pgid, 0); err != nil && err != syscall.EPERM {
+		return nil
+	}
+	procs, err := listProcesses()
+	if err != nil {
+		return []string{strconv.Itoa(pgid)}
+	}
+	var members []string
+	for _, proc := range procs {
+		if proc.PGID == pgid {
+			members = append(members, strings.TrimSpace(strconv.Itoa(proc.PID)+" "+processArgs(proc.PID)))
+		}
+	}
+	return members
+}
+
+// stopProcessGroup stops a leftover group like killProcess stops a running
+// command: the group itself and whatever its members started elsewhere. The
+// leader may be gone already, so the group is signaled directly.
+func stopProcessGroup(pgid int) {
+	for _, sig := range []syscall.Signal{syscall.SIGTERM, syscall.SIGKILL} {
+		killGroupDescendants(pgid, sig)
+		_ = syscall.Kill(-pgid, sig)
+		if sig == syscall.SIGTERM {
+			time.Sleep(200 * time.Millisecond)
+		}
+	}
+}
+
+// killGroupDescendants signals the descendants of every member of pgid.
+func killGroupDescendants(pgid int, sig syscall.Signal) {
+	procs, err := listProcesses()
+	if err != nil {
+		return
+	}
+	for _, proc := range procs {
+		if proc.PGID != pgid {
+			continue
+		}
+		for _, pid := range descendantsOf(procs, proc.PID) {
+			_ = syscall.Kill(pid, sig)
+		}
+	}
 }
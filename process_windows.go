@@ -2,7 +2,13 @@
 
 package main
 
-import "os/exec"
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"syscall"
+	"time"
+)
 
 func prepareCommand(cmd *exec.Cmd) {}
 
@@ -12,3 +18,36 @@ func killProcess(cmd *exec.Cmd) {
 	}
 	_ = cmd.Process.Kill()
 }
+
+// foregroundCommand has nothing to hand over on Windows, where the console
+// is shared.
+func foregroundCommand(cmd *exec.Cmd, tty *os.File) func() { return func() {} }
+
+// detachCommand starts cmd without a console so it outlives the terminal.
+func detachCommand(cmd *exec.Cmd) {
+	const detachedProcess = 0x00000008
+	cmd.SysProcAttr = &syscall.SysProcAttr{CreationFlags: detachedProcess | syscall.CREATE_NEW_PROCESS_GROUP}
+}
+
+// userPrivate has no owner to check on Windows, where the session directory
+// is already under the user's profile.
+func userPrivate(info os.FileInfo) bool { return true }
+
+func signalProcessGroup(pid int, name string) error {
+	return errors.New("signals aren't supported on Windows")
+}
+
+// Leftover tracking relies on Unix process groups; on Windows nothing is
+// reported as left over.
+func processAlive(pid int) bool { return false }
+
+func processGroupMembers(pgid int) []string { return nil }
+
+func stopProcessGroup(pgid int) {}
+
+func processArgs(pid int) string { return "" }
+
+func processStartTime(pid int) (time.Time, bool) { return time.Time{}, false }
+
+// Usage sampling walks the Unix process table; Windows reports nothing.
+func processTree(roots, extra []int) []procInfo { return nil }
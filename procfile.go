@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const (
+	modeProcfile     = "procfile"
+	procfileTaskName = "up"
+	defaultPortBase  = 5000
+	procfilePortStep = 100
+)
+
+// enableProcfile adds the "up" task that runs every persistent task side by
+// side, each with its own PORT, and starts it when suite opens. The tasks
+// themselves stop autostarting so they don't run twice.
+func (c *Config) enableProcfile() error {
+	c.Mode = modeProcfile
+	base := c.PortBase
+	if base == 0 {
+		base = defaultPortBase
+	}
+	var steps StepList
+	for i := range c.Tasks {
+		t := &c.Tasks[i]
+		if t.Procfile {
+			return nil
+		}
+		if t.Name == procfileTaskName {
+			return fmt.Errorf("mode procfile: task %q is reserved", procfileTaskName)
+		}
+		if !t.Persistent {
+			continue
+		}
+		t.Autostart = false
+		port := base + len(steps)*procfilePortStep
+		steps = append(steps, Step{
+			Value: t.Name,
+			Kind:  StepTask,
+			Env:   map[string]string{"PORT": strconv.Itoa(port)},
+		})
+	}
+	if len(steps) == 0 {
+		return errors.New("mode procfile needs at least one persistent task")
+	}
+	up := TaskDef{
+		Name:       procfileTaskName,
+		Persistent: true,
+		Autostart:  true,
+		Parallel:   steps,
+		Procfile:   true,
+	}
+	c.Tasks = append([]TaskDef{up}, c.Tasks...)
+	return nil
+}
+
+// runProcfile runs steps side by side until they all exit or ctx is
+// canceled. On cancel the steps are stopped one at a time in reverse start
+// order, so a web process goes down before the database it talks to.
+func runProcfile(ctx context.Context, taskName string, steps StepList, mode StepMode, shell string, init CommandList, resolve TaskResolver, msgCh chan<- tea.Msg, stack map[string]bool) (int, error) {
+	if len(steps) == 0 {
+		return -1, fmt.Errorf("no commands to run")
+	}
+	if isDryRun(ctx) {
+		return runSequential(ctx, taskName, steps, mode, shell, init, resolve, msgCh, stack)
+	}
+
+	var (
+		mu       sync.Mutex
+		exitCode int
+		firstErr error
+		wg       sync.WaitGroup
+	)
+	cancels := make([]context.CancelFunc, len(steps))
+	done := make([]chan struct{}, len(steps))
+	for idx, step := range steps {
+		step := step
+		idx := idx
+		stepCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		cancels[idx] = cancel
+		done[idx] = make(chan struct{})
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer close(done[idx])
+			code, err := runStep(stepCtx, taskName, step, mode, idx, shell, init, resolve, msgCh, cloneStack(stack))
+			if errors.Is(err, errWarned) {
+				return
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil && firstErr == nil {
+				firstErr = err
+				exitCode = code
+			}
+		}()
+	}
+
+	finished := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(finished)
+	}()
+	select {
+	case <-finished:
+	case <-ctx.Done():
+		for idx := len(steps) - 1; idx >= 0; idx-- {
+			cancels[idx]()
+			<-done[idx]
+		}
+		<-finished
+	}
+	for _, cancel := range cancels {
+		cancel()
+	}
+	return exitCode, firstErr
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestEnableProcfile(t *testing.T) {
+	cfg := Config{
+		PortBase: 3000,
+		Tasks: []TaskDef{
+			{Name: "web", Persistent: true, Autostart: true, Cmd: StepList{{Value: "bin/rails s", Kind: StepCommand}}},
+			{Name: "test", Cmd: StepList{{Value: "bin/rails test", Kind: StepCommand}}},
+			{Name: "css", Persistent: true, Cmd: StepList{{Value: "bin/rails tailwindcss:watch", Kind: StepCommand}}},
+		},
+	}
+	if err := cfg.enableProcfile(); err != nil {
+		t.Fatalf("enable procfile: %v", err)
+	}
+	if err := cfg.enableProcfile(); err != nil {
+		t.Fatalf("expected enabling twice to be a no-op: %v", err)
+	}
+
+	up := cfg.Tasks[0]
+	if len(cfg.Tasks) != 4 || up.Name != "up" || !up.Procfile || !up.Autostart || !up.Persistent {
+		t.Fatalf("expected up task first, got %#v", cfg.Tasks)
+	}
+	if len(up.Parallel) != 2 {
+		t.Fatalf("expected one step per persistent task, got %#v", up.Parallel)
+	}
+	if up.Parallel[0].Value != "web" || up.Parallel[0].Env["PORT"] != "3000" {
+		t.Fatalf("unexpected web step: %#v", up.Parallel[0])
+	}
+	if up.Parallel[1].Value != "css" || up.Parallel[1].Env["PORT"] != "3100" {
+		t.Fatalf("unexpected css step: %#v", up.Parallel[1])
+	}
+	if cfg.Tasks[1].Autostart {
+		t.Fatalf("expected web to stop autostarting on its own")
+	}
+}
+
+func TestEnableProcfileErrors(t *testing.T) {
+	cfg := Config{Tasks: []TaskDef{{Name: "test", Cmd: StepList{{Value: "true", Kind: StepCommand}}}}}
+	if err := cfg.enableProcfile(); err == nil {
+		t.Fatalf("expected error without persistent tasks")
+	}
+
+	cfg = Config{Tasks: []TaskDef{{Name: "up", Persistent: true, Cmd: StepList{{Value: "true", Kind: StepCommand}}}}}
+	if err := cfg.enableProcfile(); err == nil || !strings.Contains(err.Error(), "reserved") {
+		t.Fatalf("expected reserved name error, got %v", err)
+	}
+}
+
+func TestRunProcfileStopsInReverseOrder(t *testing.T) {
+	log := filepath.Join(t.TempDir(), "stopped")
+	process := func(name string) TaskDef {
+		cmd := "trap 'echo " + name + " >> " + log + "; exit 0' TERM; echo ready; while :; do sleep 0.05; done"
+		return TaskDef{Name: name, Persistent: true, Cmd: StepList{{Value: cmd, Kind: StepCommand}}}
+	}
+	cfg := Config{Tasks: []TaskDef{process("db"), process("web")}}
+	if err := cfg.enableProcfile(); err != nil {
+		t.Fatalf("enable procfile: %v", err)
+	}
+	resolve := func(name string) (TaskDef, bool) {
+		for _, def := range cfg.Tasks {
+			if def.Name == name {
+				return def, true
+			}
+		}
+		return TaskDef{}, false
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	msgCh := make(chan tea.Msg, 32)
+	go runTask(ctx, "up", cfg.Tasks[0], "/bin/sh", nil, resolve, msgCh)
+
+	ready := 0
+	var done TaskFinishedMsg
+	for msg := range msgCh {
+		switch msg := msg.(type) {
+		case TaskOutputMsg:
+			if msg.Line == "ready" {
+				ready++
+				if ready == 2 {
+					cancel()
+				}
+			}
+		case TaskFinishedMsg:
+			if msg.TaskID == "up" {
+				done = msg
+			}
+		}
+	}
+	if !done.Canceled {
+		t.Fatalf("expected up to finish canceled, got %#v", done)
+	}
+	data, err := os.ReadFile(log)
+	if err != nil {
+		t.Fatalf("read stop log: %v", err)
+	}
+	if got := strings.Fields(string(data)); strings.Join(got, ",") != "web,db" {
+		t.Fatalf("expected web to stop before db, got %v", got)
+	}
+}
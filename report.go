@@ -0,0 +1,274 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/x/ansi"
+)
+
+const reportTailLines = 20
+
+// reportSuite is one top-level run: the task and its steps as test cases.
+type reportSuite struct {
+	Name     string
+	Status   TaskStatus
+	Started  time.Time
+	Duration time.Duration
+	Cases    []reportCase
+}
+
+type reportCase struct {
+	Name     string
+	Status   TaskStatus
+	ExitCode int
+	Duration time.Duration
+	Output   []string
+}
+
+// reportSuites collects the tasks started from the UI or headless run, in
+// run order.
+func (m *model) reportSuites() []reportSuite {
+	names := make([]string, 0, len(m.runIDs))
+	for source := range m.runIDs {
+		if task := m.taskByName[source]; task != nil && task.RunSeq != 0 {
+			names = append(names, source)
+		}
+	}
+	sort.Slice(names, func(i, j int) bool { return m.runIDs[names[i]] < m.runIDs[names[j]] })
+
+	suites := make([]reportSuite, 0, len(names))
+	for _, name := range names {
+		task := m.taskByName[name]
+		suite := reportSuite{
+			Name:     name,
+			Status:   task.Status,
+			Started:  task.StartedAt,
+			Duration: elapsed(task.StartedAt, task.FinishedAt),
+		}
+		for _, step := range task.Steps {
+			run := task.StepRuns[step.ID]
+			if run == nil || run.RunSeq != task.RunSeq {
+				suite.Cases = append(suite.Cases, reportCase{Name: step.Label, Status: StatusIdle})
+				continue
+			}
+			rc := reportCase{
+				Name:     run.Label,
+				Status:   run.Status,
+				ExitCode: run.ExitCode,
+				Duration: elapsed(run.StartedAt, run.FinishedAt),
+				Output:   run.Output.Tail(reportTailLines),
+			}
+			if step.Kind == StepTask {
+				if child := m.taskByName[step.TaskName]; child != nil {
+					rc.Output = child.Output.Tail(reportTailLines)
+				}
+			}
+			suite.Cases = append(suite.Cases, rc)
+		}
+		if len(suite.Cases) == 0 {
+			suite.Cases = []reportCase{{
+				Name:     name,
+				Status:   task.Status,
+				ExitCode: task.ExitCode,
+				Duration: suite.Duration,
+				Output:   task.Output.Tail(reportTailLines),
+			}}
+		}
+		suites = append(suites, suite)
+	}
+	return suites
+}
+
+func elapsed(start, end time.Time) time.Duration {
+	if start.IsZero() || end.IsZero() || end.Before(start) {
+		return 0
+	}
+	return end.Sub(start)
+}
+
+// writeReport writes a Markdown summary for .md paths and JUnit XML otherwise.
+func writeReport(path, title string, suites []reportSuite) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".md", ".markdown":
+		err = writeMarkdownReport(file, title, suites)
+	default:
+		err = writeJUnitReport(file, title, suites)
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+type junitSuites struct {
+	XMLName  xml.Name     `xml:"testsuites"`
+	Name     string       `xml:"name,attr"`
+	Tests    int          `xml:"tests,attr"`
+	Failures int          `xml:"failures,attr"`
+	Skipped  int          `xml:"skipped,attr"`
+	Time     string       `xml:"time,attr"`
+	Suites   []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name      string      `xml:"name,attr"`
+	Tests     int         `xml:"tests,attr"`
+	Failures  int         `xml:"failures,attr"`
+	Skipped   int         `xml:"skipped,attr"`
+	Time      string      `xml:"time,attr"`
+	Timestamp string      `xml:"timestamp,attr,omitempty"`
+	Cases     []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr,omitempty"`
+	Body    string `xml:",chardata"`
+}
+
+func writeJUnitReport(w io.Writer, title string, suites []reportSuite) error {
+	out := junitSuites{Name: title}
+	var total time.Duration
+	for _, suite := range suites {
+		js := junitSuite{Name: suite.Name, Time: junitSeconds(suite.Duration)}
+		if !suite.Started.IsZero() {
+			js.Timestamp = suite.Started.UTC().Format("2006-01-02T15:04:05")
+		}
+		for _, rc := range suite.Cases {
+			jc := junitCase{Name: rc.Name, Classname: suite.Name, Time: junitSeconds(rc.Duration)}
+			tail := strings.Join(tailLines(rc.Output, reportTailLines), "\n")
+			switch rc.Status {
+			case StatusFailed:
+				jc.Failure = &junitMessage{Message: fmt.Sprintf("exit code %d", rc.ExitCode), Body: tail}
+				js.Failures++
+			case StatusCanceled:
+				jc.Failure = &junitMessage{Message: "canceled", Body: tail}
+				js.Failures++
+			case StatusSkipped:
+				jc.Skipped = &junitMessage{Message: "condition not met"}
+				js.Skipped++
+			case StatusIdle, StatusQueued, StatusRunning:
+				jc.Skipped = &junitMessage{Message: "not run"}
+				js.Skipped++
+			case StatusWarning:
+				jc.SystemOut = tail
+			}
+			js.Cases = append(js.Cases, jc)
+		}
+		js.Tests = len(js.Cases)
+		out.Tests += js.Tests
+		out.Failures += js.Failures
+		out.Skipped += js.Skipped
+		total += suite.Duration
+		out.Suites = append(out.Suites, js)
+	}
+	out.Time = junitSeconds(total)
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(out); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func junitSeconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
+
+func writeMarkdownReport(w io.Writer, title string, suites []reportSuite) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "## %s\n\n", title)
+	fmt.Fprintf(&b, "| Task | Step | Status | Duration |\n")
+	fmt.Fprintf(&b, "| --- | --- | --- | --- |\n")
+	var failed []reportCase
+	for _, suite := range suites {
+		for _, rc := range suite.Cases {
+			fmt.Fprintf(&b, "| %s | %s | %s %s | %s |\n",
+				markdownCell(suite.Name), markdownCell(rc.Name), reportIcon(rc.Status), statusName(rc.Status), rc.Duration.Round(time.Millisecond))
+			if rc.Status == StatusFailed {
+				failed = append(failed, rc)
+			}
+		}
+	}
+	for _, rc := range failed {
+		tail := strings.Join(tailLines(rc.Output, reportTailLines), "\n")
+		fence := markdownFence(tail)
+		fmt.Fprintf(&b, "\n<details><summary>%s output</summary>\n\n%s\n%s\n%s\n\n</details>\n",
+			html.EscapeString(rc.Name), fence, tail, fence)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// markdownFence returns a code fence longer than any backtick run in text,
+// so output can't close it early.
+func markdownFence(text string) string {
+	longest, run := 0, 0
+	for _, r := range text {
+		if r != '`' {
+			run = 0
+			continue
+		}
+		run++
+		longest = max(longest, run)
+	}
+	return strings.Repeat("`", max(3, longest+1))
+}
+
+func markdownCell(text string) string {
+	text = strings.ReplaceAll(text, "\n", " ")
+	return strings.ReplaceAll(text, "|", `\|`)
+}
+
+func reportIcon(status TaskStatus) string {
+	switch status {
+	case StatusSuccess:
+		return "✅"
+	case StatusFailed:
+		return "❌"
+	case StatusWarning:
+		return "⚠️"
+	case StatusCanceled:
+		return "⏹"
+	default:
+		return "➖"
+	}
+}
+
+// tailLines is the plain text of the last n lines; colors would be noise in
+// a report and aren't valid XML.
+func tailLines(lines []string, n int) []string {
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	plain := make([]string, len(lines))
+	for i, line := range lines {
+		plain[i] = ansi.Strip(line)
+	}
+	return plain
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+)
+
+func headlessReportModel(t *testing.T) *model {
+	t.Helper()
+	cfg := Config{
+		Shell: "/bin/sh",
+		Tasks: []TaskDef{
+			{Name: "lint", Cmd: StepList{{Value: "echo lint ok", Kind: StepCommand}}},
+			{Name: "ci", Seq: StepList{
+				{Value: "lint", Kind: StepTask},
+				{Value: "echo one; echo two; exit 3", Name: "test", Kind: StepCommand},
+				{Value: "echo never", Name: "deploy", Kind: StepCommand},
+			}},
+		},
+	}
+	m := newModel(cfg)
+	if code := runHeadless(&m, []string{"ci"}, io.Discard); code != 1 {
+		t.Fatalf("expected exit code 1, got %d", code)
+	}
+	return &m
+}
+
+func TestJUnitReport(t *testing.T) {
+	m := headlessReportModel(t)
+
+	var buf bytes.Buffer
+	if err := writeJUnitReport(&buf, "suite", m.reportSuites()); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	var report junitSuites
+	if err := xml.Unmarshal(buf.Bytes(), &report); err != nil {
+		t.Fatalf("decode: %v\n%s", err, buf.String())
+	}
+	if report.Tests != 3 || report.Failures != 1 || report.Skipped != 1 {
+		t.Fatalf("unexpected totals: %+v", report)
+	}
+	cases := report.Suites[0].Cases
+	if cases[0].Name != "lint" || cases[0].Failure != nil {
+		t.Fatalf("unexpected lint case: %+v", cases[0])
+	}
+	if cases[1].Failure == nil || cases[1].Failure.Message != "exit code 3" || cases[1].Failure.Body != "one\ntwo" {
+		t.Fatalf("unexpected test case: %+v", cases[1])
+	}
+	if cases[2].Skipped == nil {
+		t.Fatalf("expected deploy to be reported as not run")
+	}
+}
+
+func TestMarkdownReport(t *testing.T) {
+	m := headlessReportModel(t)
+
+	var buf bytes.Buffer
+	if err := writeMarkdownReport(&buf, "suite", m.reportSuites()); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{"| ci | lint | ✅ passed |", "| ci | test | ❌ failed |", "<details><summary>test output</summary>"} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expected %q in report:\n%s", want, out)
+		}
+	}
+}
+
+func TestReportsKeepNoisyFailureTail(t *testing.T) {
+	cfg := Config{
+		Shell: "/bin/sh",
+		Tasks: []TaskDef{
+			{Name: "ci", Seq: StepList{
+				{Value: "seq 1 50000; echo 'expected ``` got <nil>'; exit 1", Name: "test <unit>", Kind: StepCommand},
+			}},
+		},
+	}
+	m := newModel(cfg)
+	if code := runHeadless(&m, []string{"ci"}, io.Discard); code != 1 {
+		t.Fatalf("expected exit code 1, got %d", code)
+	}
+
+	var junit bytes.Buffer
+	if err := writeJUnitReport(&junit, "suite", m.reportSuites()); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	var report junitSuites
+	if err := xml.Unmarshal(junit.Bytes(), &report); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	body := report.Suites[0].Cases[0].Failure.Body
+	if !strings.HasPrefix(body, "49982\n") || !strings.HasSuffix(body, "50000\nexpected ``` got <nil>") {
+		t.Fatalf("expected the last lines of the failure, got %q", body)
+	}
+
+	var md bytes.Buffer
+	if err := writeMarkdownReport(&md, "suite", m.reportSuites()); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	want := "<details><summary>test &lt;unit&gt; output</summary>\n\n````\n49982\n"
+	if out := md.String(); !strings.Contains(out, want) || !strings.Contains(out, "got <nil>\n````\n") {
+		t.Fatalf("expected an escaped name and a longer fence:\n%s", out)
+	}
+}
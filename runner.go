@@ -3,12 +3,15 @@ package main
 import (
 	"bufio"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
+	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 )
@@ -27,34 +30,100 @@ type TaskFinishedMsg struct {
 	ExitCode int
 	Err      error
 	Canceled bool
+	Skipped  bool
+	Warned   bool
 }
 
 type StepStartedMsg struct {
 	StepID string
 }
 
+// JobSlotMsg reports a task or step target waiting for (or leaving the wait
+// for) a max_parallel or jobs: slot.
+type JobSlotMsg struct {
+	Target  string
+	Waiting bool
+}
+
 type StepFinishedMsg struct {
 	StepID   string
 	ExitCode int
 	Err      error
 	Canceled bool
+	Skipped  bool
+	Warned   bool
 }
 
 type TaskResolver func(name string) (TaskDef, bool)
 
+// errSkipped is returned by runTaskInternal when the task's if: condition
+// did not hold. Callers treat it as a pass.
+var errSkipped = errors.New("skipped")
+
+// errWarned is returned when a run completed but some steps were allowed to
+// fail. Callers treat it as a pass with warnings.
+var errWarned = errors.New("passed with warnings")
+
+// outputWaitDelay is how long a finished command's output may stay open
+// before the step ends anyway; background children can hold it forever.
+const outputWaitDelay = 500 * time.Millisecond
+
+// frameInterval is how long a listener keeps collecting after the first
+// message, so a burst of output is applied and rendered once per frame.
+const frameInterval = time.Second / 60
+
+// maxFrameMsgs caps one frame so a flood can't hold up the UI.
+const maxFrameMsgs = 8192
+
 func listenTaskMsgs(source string, ch <-chan tea.Msg) tea.Cmd {
 	return func() tea.Msg {
-		msg, ok := <-ch
-		if !ok {
+		msgs := collectFrame(ch)
+		if msgs == nil {
 			return nil
 		}
-		return taskStreamMsg{Source: source, Msg: msg}
+		return taskStreamBatchMsg{Source: source, Msgs: msgs}
+	}
+}
+
+// collectFrame waits for a message on ch and returns it along with whatever
+// else arrives within frameInterval. It returns nil once ch is closed.
+func collectFrame(ch <-chan tea.Msg) []tea.Msg {
+	msg, ok := <-ch
+	if !ok {
+		return nil
+	}
+	msgs := []tea.Msg{msg}
+	timer := time.NewTimer(frameInterval)
+	defer timer.Stop()
+	for len(msgs) < maxFrameMsgs {
+		select {
+		case msg, ok := <-ch:
+			if !ok {
+				return msgs
+			}
+			msgs = append(msgs, msg)
+		case <-timer.C:
+			return msgs
+		}
 	}
+	return msgs
 }
 
 func runTask(ctx context.Context, taskName string, def TaskDef, shell string, init CommandList, resolve TaskResolver, msgCh chan<- tea.Msg) {
 	defer close(msgCh)
 	stack := map[string]bool{taskName: true}
+	if !isDryRun(ctx) {
+		limit, _ := parseByteSize(def.MaxMemory)
+		var cancel context.CancelCauseFunc
+		ctx, cancel = context.WithCancelCause(ctx)
+		defer cancel(nil)
+		var procs *runProcesses
+		ctx, procs = withRunProcesses(ctx)
+		// A foreground run's messages wait for the UI to get the terminal
+		// back, so it only enforces the limit.
+		_, foreground := foregroundTerminal(ctx)
+		defer watchUsage(ctx, taskName, limit, !foreground, procs, cancel, msgCh)()
+	}
 	_, _ = runTaskInternal(ctx, taskName, def, shell, init, resolve, msgCh, stack)
 }
 
@@ -62,14 +131,56 @@ func runTaskInternal(ctx context.Context, taskName string, def TaskDef, shell st
 	if resolve == nil {
 		resolve = func(string) (TaskDef, bool) { return TaskDef{}, false }
 	}
+	ok, seen, err := checkCondition(ctx, def.If, shell, init, msgCh, taskName)
+	if err != nil {
+		msgCh <- TaskFinishedMsg{TaskID: taskName, ExitCode: -1, Err: err, Canceled: ctx.Err() != nil}
+		return -1, err
+	}
+	if !ok {
+		msgCh <- TaskFinishedMsg{TaskID: taskName, Skipped: true}
+		return 0, errSkipped
+	}
 	msgCh <- TaskStartedMsg{TaskName: taskName}
+	if def.Persistent {
+		ctx = withoutJobSlot(ctx)
+	}
+
+	started := time.Now()
+	runHooks(ctx, "on_start", def.OnStart, hookEnv(taskName, 0, "", 0, ""), taskName, shell, init, msgCh)
+	var log *taskLog
+	out := msgCh
+	if len(def.OnSuccess)+len(def.OnFailure)+len(def.OnExit) > 0 && !isDryRun(ctx) {
+		log, out = startTaskLog(taskName, msgCh)
+	}
 
-	exitCode, err := runTaskSteps(ctx, taskName, def, shell, init, resolve, msgCh, stack)
+	exitCode, err := runTaskSteps(ctx, taskName, def, shell, init, resolve, out, stack)
+	canceled := ctx.Err() != nil
+	if limitErr := memoryLimitCause(ctx); limitErr != nil {
+		// Killed for its memory, which is a failure rather than a cancel.
+		exitCode, err, canceled = -1, limitErr, false
+	}
+	recordChanges(seen, err)
+	if len(def.OnSuccess)+len(def.OnFailure)+len(def.OnExit) > 0 {
+		status := statusKey(finishedStatus(err, canceled, false, errors.Is(err, errWarned)))
+		env := hookEnv(taskName, exitCode, status, time.Since(started), log.finish())
+		switch status {
+		case "passed", "warning":
+			runHooks(ctx, "on_success", def.OnSuccess, env, taskName, shell, init, msgCh)
+		case "failed":
+			runHooks(ctx, "on_failure", def.OnFailure, env, taskName, shell, init, msgCh)
+		}
+		runHooks(ctx, "on_exit", def.OnExit, env, taskName, shell, init, msgCh)
+		log.remove()
+	}
+	if errors.Is(err, errWarned) {
+		msgCh <- TaskFinishedMsg{TaskID: taskName, Warned: true}
+		return 0, err
+	}
 	msgCh <- TaskFinishedMsg{
 		TaskID:   taskName,
 		ExitCode: exitCode,
 		Err:      err,
-		Canceled: ctx.Err() != nil,
+		Canceled: canceled,
 	}
 	return exitCode, err
 }
@@ -92,20 +203,29 @@ func runTaskSteps(ctx context.Context, taskName string, def TaskDef, shell strin
 			next := cloneStack(stack)
 			next[value] = true
 			exitCode, err := runTaskInternal(ctx, value, child, shell, init, resolve, msgCh, next)
-			if err != nil {
+			if err != nil && !errors.Is(err, errSkipped) {
 				return exitCode, err
 			}
 			return 0, nil
 		}
 		exitCode, err := runSingle(ctx, steps[0].Value, shell, init, msgCh, taskName)
 		if err != nil {
+			if steps[0].AllowFailure && ctx.Err() == nil {
+				return exitCode, errWarned
+			}
 			return exitCode, err
 		}
 		return 0, nil
 	}
 
+	if !taskStopOnFail(def) {
+		steps = allowStepFailures(steps)
+	}
+	if def.Procfile {
+		return runProcfile(ctx, taskName, steps, mode, shell, init, resolve, msgCh, stack)
+	}
 	if mode == StepModeParallel {
-		return runParallel(ctx, taskName, steps, mode, shell, init, resolve, msgCh, stack)
+		return runParallel(ctx, taskName, steps, mode, def.FailFast, def.MaxParallel, shell, init, resolve, msgCh, stack)
 	}
 	return runSequential(ctx, taskName, steps, mode, shell, init, resolve, msgCh, stack)
 }
@@ -115,28 +235,45 @@ func runSequential(ctx context.Context, taskName string, steps StepList, mode St
 		return -1, fmt.Errorf("no commands to run")
 	}
 
+	warned := false
 	for idx, step := range steps {
 		exitCode, err := runStep(ctx, taskName, step, mode, idx, shell, init, resolve, msgCh, stack)
+		if errors.Is(err, errWarned) {
+			warned = true
+			continue
+		}
 		if err != nil {
 			return exitCode, err
 		}
 	}
 
+	if warned {
+		return 0, errWarned
+	}
 	return 0, nil
 }
 
-func runParallel(ctx context.Context, taskName string, steps StepList, mode StepMode, shell string, init CommandList, resolve TaskResolver, msgCh chan<- tea.Msg, stack map[string]bool) (int, error) {
+func runParallel(ctx context.Context, taskName string, steps StepList, mode StepMode, failFast bool, maxParallel int, shell string, init CommandList, resolve TaskResolver, msgCh chan<- tea.Msg, stack map[string]bool) (int, error) {
 	if len(steps) == 0 {
 		return -1, fmt.Errorf("no commands to run")
 	}
 
-	type result struct {
-		exitCode int
-		err      error
+	if isDryRun(ctx) {
+		// Walk parallel steps in order so the plan is stable.
+		return runSequential(ctx, taskName, steps, mode, shell, init, resolve, msgCh, stack)
 	}
 
-	results := make(chan result, len(steps))
-	var wg sync.WaitGroup
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	limit := newSemaphore(maxParallel)
+
+	var (
+		mu       sync.Mutex
+		exitCode int
+		firstErr error
+		warned   bool
+		wg       sync.WaitGroup
+	)
 
 	for idx, step := range steps {
 		step := step
@@ -144,24 +281,39 @@ func runParallel(ctx context.Context, taskName string, steps StepList, mode Step
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			exitCode, err := runStep(ctx, taskName, step, mode, idx, shell, init, resolve, msgCh, cloneStack(stack))
-			results <- result{exitCode: exitCode, err: err}
+			id := stepID(taskName, mode, idx)
+			code := -1
+			err := limit.acquire(runCtx, func() {
+				msgCh <- JobSlotMsg{Target: id, Waiting: true}
+			})
+			if err != nil {
+				msgCh <- StepFinishedMsg{StepID: id, ExitCode: code, Err: err, Canceled: true}
+			} else {
+				code, err = runStep(runCtx, taskName, step, mode, idx, shell, init, resolve, msgCh, cloneStack(stack))
+				limit.release()
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if errors.Is(err, errWarned) {
+				warned = true
+				return
+			}
+			if err != nil && firstErr == nil {
+				firstErr = err
+				exitCode = code
+				if failFast {
+					cancel()
+				}
+			}
 		}()
 	}
 
 	wg.Wait()
-	close(results)
-
-	exitCode := 0
-	var err error
-	for res := range results {
-		if res.err != nil && err == nil {
-			err = res.err
-			exitCode = res.exitCode
-		}
-	}
 
-	return exitCode, err
+	if firstErr == nil && warned {
+		return 0, errWarned
+	}
+	return exitCode, firstErr
 }
 
 func runStep(ctx context.Context, taskName string, step Step, mode StepMode, index int, shell string, init CommandList, resolve TaskResolver, msgCh chan<- tea.Msg, stack map[string]bool) (int, error) {
@@ -170,6 +322,18 @@ func runStep(ctx context.Context, taskName string, step Step, mode StepMode, ind
 		return -1, fmt.Errorf("empty step")
 	}
 	kind, resolved := resolveStepKind(step, resolve)
+	if len(step.Env) > 0 {
+		ctx = withStepEnv(ctx, step.Env)
+	}
+	ok, seen, err := checkCondition(ctx, step.If, shell, init, msgCh, stepID(taskName, mode, index))
+	if err != nil {
+		msgCh <- StepFinishedMsg{StepID: stepID(taskName, mode, index), ExitCode: -1, Err: err, Canceled: ctx.Err() != nil}
+		return -1, err
+	}
+	if !ok {
+		msgCh <- StepFinishedMsg{StepID: stepID(taskName, mode, index), Skipped: true}
+		return 0, nil
+	}
 	switch kind {
 	case StepTask:
 		def, ok := resolve(resolved)
@@ -184,81 +348,196 @@ func runStep(ctx context.Context, taskName string, step Step, mode StepMode, ind
 		next := cloneStack(stack)
 		next[resolved] = true
 		exitCode, err := runTaskInternal(ctx, resolved, def, shell, init, resolve, msgCh, next)
-		msgCh <- StepFinishedMsg{
-			StepID:   stepID,
-			ExitCode: exitCode,
-			Err:      err,
-			Canceled: ctx.Err() != nil,
+		recordChanges(seen, err)
+		if errors.Is(err, errSkipped) {
+			msgCh <- StepFinishedMsg{StepID: stepID, Skipped: true}
+			return 0, nil
 		}
-		return exitCode, err
+		return finishStep(ctx, step, stepID, exitCode, err, msgCh)
 	case StepCommand:
 		stepID := stepID(taskName, mode, index)
 		msgCh <- StepStartedMsg{StepID: stepID}
 		exitCode, err := runSingle(ctx, value, shell, init, msgCh, stepID)
-		msgCh <- StepFinishedMsg{
-			StepID:   stepID,
-			ExitCode: exitCode,
-			Err:      err,
-			Canceled: ctx.Err() != nil,
-		}
-		return exitCode, err
+		recordChanges(seen, err)
+		return finishStep(ctx, step, stepID, exitCode, err, msgCh)
 	default:
 		return -1, fmt.Errorf("unknown step kind")
 	}
 }
 
+// checkCondition evaluates cond for target. Dry runs note the condition
+// and assume it holds.
+func checkCondition(ctx context.Context, cond Condition, shell string, init CommandList, msgCh chan<- tea.Msg, target string) (bool, fileHashes, error) {
+	if isDryRun(ctx) {
+		if !cond.IsZero() {
+			msgCh <- planNoteMsg{Target: target, Text: conditionText(cond), Condition: true}
+		}
+		return true, nil, nil
+	}
+	return evalCondition(ctx, cond, shell, init, target)
+}
+
+// recordChanges records the changed: hashes that gated a run once the run
+// passed, so a failed or canceled run goes again next time.
+func recordChanges(seen fileHashes, err error) {
+	if err == nil || errors.Is(err, errWarned) {
+		changeTracker.record(seen)
+	}
+}
+
+func finishStep(ctx context.Context, step Step, stepID string, exitCode int, err error, msgCh chan<- tea.Msg) (int, error) {
+	msg := StepFinishedMsg{
+		StepID:   stepID,
+		ExitCode: exitCode,
+		Err:      err,
+		Canceled: ctx.Err() != nil,
+	}
+	if errors.Is(err, errWarned) {
+		msg.Err = nil
+		msg.Warned = true
+	} else if err != nil && step.AllowFailure && ctx.Err() == nil {
+		msg.Warned = true
+		err = errWarned
+	}
+	msgCh <- msg
+	return exitCode, err
+}
+
+func allowStepFailures(steps StepList) StepList {
+	out := make(StepList, len(steps))
+	for i, step := range steps {
+		step.AllowFailure = true
+		out[i] = step
+	}
+	return out
+}
+
 func runSingle(ctx context.Context, command string, shell string, init CommandList, msgCh chan<- tea.Msg, target string) (int, error) {
-	fullCommand := buildShellCommand(init, command)
-	if shell == "" {
-		shell = "/bin/sh"
+	if isDryRun(ctx) {
+		msgCh <- planCommand(ctx, command, shell, init, target)
+		return 0, nil
 	}
-	cmd := exec.Command(shell, "-c", fullCommand)
-	prepareCommand(cmd)
-	cmd.Env = envWithShell(shell)
-	if isTerminal(os.Stdin) {
-		cmd.Stdin = os.Stdin
+	if !jobExempt(ctx) {
+		slots := jobSlots
+		waited := false
+		err := slots.acquire(ctx, func() {
+			waited = true
+			msgCh <- JobSlotMsg{Target: target, Waiting: true}
+		})
+		if err != nil {
+			return -1, err
+		}
+		defer slots.release()
+		if waited {
+			msgCh <- JobSlotMsg{Target: target}
+		}
 	}
-	stdout, err := cmd.StdoutPipe()
+
+	cmd := shellCommand(ctx, command, shell, init)
+	prepareCommand(cmd)
+	scope, err := newCgroupScope(cmd)
 	if err != nil {
-		return -1, err
+		msgCh <- TaskOutputMsg{Target: target, Line: fmt.Sprintf("%v; using process groups", err)}
+	}
+	defer scope.close()
+	if term, ok := foregroundTerminal(ctx); ok {
+		return runForeground(ctx, cmd, term, scope, target, command)
 	}
-	stderr, err := cmd.StderrPipe()
+	register, err := inputs.connect(cmd, target)
 	if err != nil {
 		return -1, err
 	}
+	// Output goes through io.Pipes rather than StdoutPipe so Wait copies it
+	// all before returning, but gives up after outputWaitDelay when a
+	// background child (say `server &`) still holds stdout open.
+	stdout, stdoutW := io.Pipe()
+	stderr, stderrW := io.Pipe()
+	cmd.Stdout = stdoutW
+	cmd.Stderr = stderrW
+	cmd.WaitDelay = outputWaitDelay
 
 	if err := cmd.Start(); err != nil {
 		return -1, err
 	}
+	processes.add(cmd.Process.Pid, target, command, scope.Path())
+	defer processes.remove(cmd.Process.Pid)
+	defer trackRunProcess(ctx, cmd.Process.Pid, target, scope.Path())()
+	defer register()()
+
+	stop := killOnCancel(ctx, cmd, scope)
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go streamLines(ctx, target, stdout, msgCh, &wg)
+	go streamLines(ctx, target, stderr, msgCh, &wg)
+
+	err = cmd.Wait()
+	stdoutW.Close()
+	stderrW.Close()
+	stop()
+	wg.Wait()
+	if errors.Is(err, exec.ErrWaitDelay) {
+		err = nil
+	}
+
+	return exitCode(err), err
+}
 
+// killOnCancel kills cmd's process group (and cgroup) when ctx is
+// canceled, until the returned stop is called.
+func killOnCancel(ctx context.Context, cmd *exec.Cmd, scope *cgroupScope) (stop func()) {
 	done := make(chan struct{})
 	go func() {
 		select {
 		case <-ctx.Done():
+			scope.terminate()
 			killProcess(cmd)
+			scope.kill()
 		case <-done:
 		}
 	}()
+	return func() { close(done) }
+}
 
-	var wg sync.WaitGroup
-	wg.Add(2)
-	go streamLines(target, stdout, msgCh, &wg)
-	go streamLines(target, stderr, msgCh, &wg)
-
-	err = cmd.Wait()
-	close(done)
-	wg.Wait()
+// runTracked runs a command that isn't a step, like an if: cmd check, the
+// way runSingle starts steps: in its own process group or cgroup, recorded
+// in the state file, and killed with everything it started on cancel.
+func runTracked(ctx context.Context, cmd *exec.Cmd, target, command string) error {
+	prepareCommand(cmd)
+	scope, _ := newCgroupScope(cmd) // runSingle reports cgroup errors
+	defer scope.close()
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	processes.add(cmd.Process.Pid, target, command, scope.Path())
+	defer processes.remove(cmd.Process.Pid)
+	defer trackRunProcess(ctx, cmd.Process.Pid, target, scope.Path())()
+	stop := killOnCancel(ctx, cmd, scope)
+	defer stop()
+	return cmd.Wait()
+}
 
-	exitCode := 0
-	if err != nil {
-		if exitErr, ok := err.(*exec.ExitError); ok {
-			exitCode = exitErr.ExitCode()
-		} else {
-			exitCode = -1
-		}
+// exitCode is the exit status behind a Wait error; -1 when the command
+// didn't exit normally.
+func exitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		return exitErr.ExitCode()
 	}
+	return -1
+}
 
-	return exitCode, err
+// shellCommand builds the command runSingle starts; dry runs describe the
+// same value.
+func shellCommand(ctx context.Context, command, shell string, init CommandList) *exec.Cmd {
+	if shell == "" {
+		shell = "/bin/sh"
+	}
+	cmd := exec.Command(shell, "-c", buildShellCommand(init, command))
+	cmd.Env = append(envWithShell(shell), sortedEnv(stepEnv(ctx))...)
+	return cmd
 }
 
 func buildShellCommand(init CommandList, command string) string {
@@ -292,6 +571,39 @@ func envWithShell(shell string) []string {
 	return append(env, "SHELL="+shell)
 }
 
+type stepEnvKey struct{}
+
+// withStepEnv layers extra environment variables (e.g. matrix values) onto
+// every command run under ctx, including nested task references.
+func withStepEnv(ctx context.Context, env map[string]string) context.Context {
+	merged := make(map[string]string, len(env))
+	for key, value := range stepEnv(ctx) {
+		merged[key] = value
+	}
+	for key, value := range env {
+		merged[key] = value
+	}
+	return context.WithValue(ctx, stepEnvKey{}, merged)
+}
+
+func stepEnv(ctx context.Context) map[string]string {
+	env, _ := ctx.Value(stepEnvKey{}).(map[string]string)
+	return env
+}
+
+func sortedEnv(env map[string]string) []string {
+	keys := make([]string, 0, len(env))
+	for key := range env {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	out := make([]string, 0, len(keys))
+	for _, key := range keys {
+		out = append(out, key+"="+env[key])
+	}
+	return out
+}
+
 func cloneStack(stack map[string]bool) map[string]bool {
 	next := make(map[string]bool, len(stack))
 	for key, value := range stack {
@@ -300,24 +612,30 @@ func cloneStack(stack map[string]bool) map[string]bool {
 	return next
 }
 
-func streamLines(target string, r io.Reader, msgCh chan<- tea.Msg, wg *sync.WaitGroup) {
+// streamLines sends every line of r. Sends block while the listener is
+// behind, so headless output, events and reports get all of it; a canceled
+// run only drops what the listener can't take.
+func streamLines(ctx context.Context, target string, r io.Reader, msgCh chan<- tea.Msg, wg *sync.WaitGroup) {
 	defer wg.Done()
 	scanner := bufio.NewScanner(r)
 	buf := make([]byte, 0, 64*1024)
 	scanner.Buffer(buf, 1024*1024)
+	scanner.Split(scanOutput)
 
 	for scanner.Scan() {
-		trySend(msgCh, TaskOutputMsg{Target: target, Line: scanner.Text()})
+		sendOutput(ctx, msgCh, TaskOutputMsg{Target: target, Line: scanner.Text()})
 	}
 
 	if err := scanner.Err(); err != nil {
-		trySend(msgCh, TaskOutputMsg{Target: target, Line: fmt.Sprintf("[stream error] %v", err)})
+		sendOutput(ctx, msgCh, TaskOutputMsg{Target: target, Line: fmt.Sprintf("[stream error] %v", err)})
 	}
+	// Keep reading so the command never blocks on a full pipe.
+	_, _ = io.Copy(io.Discard, r)
 }
 
-func trySend(msgCh chan<- tea.Msg, msg tea.Msg) {
+func sendOutput(ctx context.Context, msgCh chan<- tea.Msg, msg tea.Msg) {
 	select {
 	case msgCh <- msg:
-	default:
+	case <-ctx.Done():
 	}
 }
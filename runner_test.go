@@ -82,6 +82,18 @@ func TestRunCommandCanceled(t *testing.T) {
 	}
 }
 
+func TestRunCommandBackgroundChildDoesNotHoldStep(t *testing.T) {
+	def := TaskDef{Cmd: StepList{{Value: "echo start; sleep 3 & echo done", Kind: StepCommand}}}
+	started := time.Now()
+	outputs, done := runTaskAndCollect(context.Background(), "task", def)
+	if elapsed := time.Since(started); elapsed > 2*time.Second {
+		t.Fatalf("expected the step to end with the shell, took %v", elapsed)
+	}
+	if done.Err != nil || len(outputs) != 2 || outputs[1].Line != "done" {
+		t.Fatalf("unexpected result %v %v", done.Err, outputs)
+	}
+}
+
 func TestRunTaskSequentialStopsOnFail(t *testing.T) {
 	def := TaskDef{Cmd: StepList{
 		{Value: "printf 'one\n'", Kind: StepCommand},
@@ -165,3 +177,249 @@ func TestRunTaskWithTaskReference(t *testing.T) {
 		t.Fatalf("unexpected output: %v", lines)
 	}
 }
+
+func TestRunTaskSkipsFalseConditions(t *testing.T) {
+	tasks := map[string]TaskDef{
+		"child":  {Name: "child", If: Condition{Cmd: "false"}, Cmd: StepList{{Value: "printf 'child\n'", Kind: StepCommand}}},
+		"parent": {Name: "parent", Seq: StepList{{Value: "child", Kind: StepTask}, {Value: "printf 'skip\n'", Kind: StepCommand, If: Condition{Cmd: "false"}}, {Value: "printf 'parent\n'", Kind: StepCommand}}},
+	}
+	resolve := func(name string) (TaskDef, bool) {
+		def, ok := tasks[name]
+		return def, ok
+	}
+
+	msgCh := make(chan tea.Msg, 32)
+	go runTask(context.Background(), "parent", tasks["parent"], "/bin/sh", nil, resolve, msgCh)
+
+	lines := []string{}
+	skipped := map[string]bool{}
+	var done TaskFinishedMsg
+	for msg := range msgCh {
+		switch msg := msg.(type) {
+		case TaskOutputMsg:
+			lines = append(lines, msg.Line)
+		case StepFinishedMsg:
+			if msg.Skipped {
+				skipped[msg.StepID] = true
+			}
+		case TaskFinishedMsg:
+			if msg.TaskID == "parent" {
+				done = msg
+			}
+		}
+	}
+
+	if done.Err != nil || done.Skipped {
+		t.Fatalf("expected parent to pass, got %+v", done)
+	}
+	if len(lines) != 1 || lines[0] != "parent" {
+		t.Fatalf("unexpected output: %v", lines)
+	}
+	if !skipped["parent::seq::0"] || !skipped["parent::seq::1"] {
+		t.Fatalf("expected both steps skipped, got %v", skipped)
+	}
+}
+
+func TestRunTaskAllowFailureWarns(t *testing.T) {
+	def := TaskDef{Seq: StepList{
+		{Value: "exit 4", Kind: StepCommand, AllowFailure: true},
+		{Value: "printf 'after\n'", Kind: StepCommand},
+	}}
+
+	outputs, done := runTaskAndCollect(context.Background(), "task", def)
+
+	if done.Err != nil || !done.Warned {
+		t.Fatalf("expected warned pass, got %+v", done)
+	}
+	if len(outputs) != 1 || outputs[0].Line != "after" {
+		t.Fatalf("expected later step to run, got %v", outputs)
+	}
+}
+
+func TestRunTaskStopOnFailFalseContinues(t *testing.T) {
+	f := false
+	def := TaskDef{StopOnFail: &f, Cmd: StepList{
+		{Value: "exit 3", Kind: StepCommand},
+		{Value: "printf 'two\n'", Kind: StepCommand},
+	}}
+
+	outputs, done := runTaskAndCollect(context.Background(), "task", def)
+
+	if done.Err != nil || !done.Warned {
+		t.Fatalf("expected warned pass, got %+v", done)
+	}
+	if len(outputs) != 1 || outputs[0].Line != "two" {
+		t.Fatalf("expected second step to run, got %v", outputs)
+	}
+}
+
+func TestRunTaskParallelFailFast(t *testing.T) {
+	def := TaskDef{FailFast: true, Parallel: StepList{
+		{Value: "exit 5", Kind: StepCommand},
+		{Value: "sleep 5; printf 'late\n'", Kind: StepCommand},
+	}}
+
+	start := time.Now()
+	outputs, done := runTaskAndCollect(context.Background(), "task", def)
+
+	if done.ExitCode != 5 {
+		t.Fatalf("expected exit code 5, got %d", done.ExitCode)
+	}
+	if time.Since(start) > 3*time.Second {
+		t.Fatalf("expected sibling to be canceled")
+	}
+	for _, out := range outputs {
+		if out.Line == "late" {
+			t.Fatalf("unexpected output from canceled sibling")
+		}
+	}
+}
+
+func TestRunTaskParallelMaxParallel(t *testing.T) {
+	def := TaskDef{MaxParallel: 1, Parallel: StepList{
+		{Value: "sleep 0.1", Kind: StepCommand},
+		{Value: "sleep 0.1", Kind: StepCommand},
+		{Value: "sleep 0.1", Kind: StepCommand},
+	}}
+
+	msgCh := make(chan tea.Msg, 64)
+	go runTask(context.Background(), "task", def, "/bin/sh", nil, func(string) (TaskDef, bool) { return TaskDef{}, false }, msgCh)
+
+	queued := 0
+	running, maxRunning := 0, 0
+	for msg := range msgCh {
+		switch msg := msg.(type) {
+		case JobSlotMsg:
+			if msg.Waiting {
+				queued++
+			}
+		case StepStartedMsg:
+			running++
+			if running > maxRunning {
+				maxRunning = running
+			}
+		case StepFinishedMsg:
+			running--
+		}
+	}
+
+	if queued != 2 {
+		t.Fatalf("expected 2 queued steps, got %d", queued)
+	}
+	if maxRunning != 1 {
+		t.Fatalf("expected at most 1 running step, got %d", maxRunning)
+	}
+}
+
+func TestRunTaskGlobalJobs(t *testing.T) {
+	setJobLimit(1)
+	t.Cleanup(func() { setJobLimit(0) })
+
+	def := TaskDef{Parallel: StepList{
+		{Value: "sleep 0.1", Kind: StepCommand},
+		{Value: "sleep 0.1", Kind: StepCommand},
+	}}
+
+	msgCh := make(chan tea.Msg, 64)
+	go runTask(context.Background(), "task", def, "/bin/sh", nil, func(string) (TaskDef, bool) { return TaskDef{}, false }, msgCh)
+
+	waiting := 0
+	for msg := range msgCh {
+		if slot, ok := msg.(JobSlotMsg); ok && slot.Waiting {
+			waiting++
+		}
+	}
+	if waiting != 1 {
+		t.Fatalf("expected one command to wait for a job slot, got %d", waiting)
+	}
+}
+
+func TestRunTaskHooks(t *testing.T) {
+	def := TaskDef{
+		Cmd: StepList{{Value: "echo work; exit 3", Kind: StepCommand}},
+		TaskHooks: TaskHooks{
+			OnStart:   CommandList{"echo start $SUITE_TASK"},
+			OnSuccess: CommandList{"echo success"},
+			OnFailure: CommandList{"echo failure $SUITE_EXIT_CODE $SUITE_STATUS", "cat \"$SUITE_LOG\""},
+			OnExit:    CommandList{"test -n \"$SUITE_DURATION\" && echo exit"},
+		},
+	}
+	outputs, done := runTaskAndCollect(context.Background(), "task", def)
+
+	if done.ExitCode != 3 {
+		t.Fatalf("expected hooks to keep exit code 3, got %d", done.ExitCode)
+	}
+	lines := []string{}
+	for _, out := range outputs {
+		lines = append(lines, out.Line)
+	}
+	want := []string{"start task", "work", "failure 3 failed", "work", "exit"}
+	if strings.Join(lines, "|") != strings.Join(want, "|") {
+		t.Fatalf("unexpected output: %v", lines)
+	}
+}
+
+func TestRunTaskHookFailureReported(t *testing.T) {
+	def := TaskDef{
+		Cmd:       StepList{{Value: "true", Kind: StepCommand}},
+		TaskHooks: TaskHooks{OnSuccess: CommandList{"exit 1"}},
+	}
+	outputs, done := runTaskAndCollect(context.Background(), "task", def)
+
+	if done.Err != nil {
+		t.Fatalf("expected hook failure not to fail the task, got %v", done.Err)
+	}
+	if len(outputs) != 1 || !strings.Contains(outputs[0].Line, "on_success hook failed") {
+		t.Fatalf("unexpected output: %v", outputs)
+	}
+}
+
+func TestRunTaskHookKill(t *testing.T) {
+	def := TaskDef{
+		Cmd:       StepList{{Value: "sleep 30", Kind: StepCommand}},
+		TaskHooks: TaskHooks{OnExit: CommandList{"echo cleanup; sleep 30"}},
+	}
+	runCtx, cancel := context.WithCancel(context.Background())
+	ctx, killHooks := withHookKill(runCtx)
+	defer killHooks()
+
+	result := make(chan []TaskOutputMsg, 1)
+	go func() {
+		outputs, _ := runTaskAndCollect(ctx, "task", def)
+		result <- outputs
+	}()
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+	time.Sleep(200 * time.Millisecond)
+	select {
+	case <-result:
+		t.Fatalf("expected on_exit to outlive the task's cancel")
+	default:
+	}
+	killHooks()
+
+	select {
+	case outputs := <-result:
+		if len(outputs) == 0 || outputs[0].Line != "cleanup" {
+			t.Fatalf("unexpected output: %v", outputs)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("expected the hook kill to stop on_exit")
+	}
+}
+
+func TestCollectFrameBatchesQueuedMessages(t *testing.T) {
+	ch := make(chan tea.Msg, 4)
+	ch <- TaskOutputMsg{Target: "web", Line: "a"}
+	ch <- TaskOutputMsg{Target: "web", Line: "b"}
+	ch <- TaskFinishedMsg{TaskID: "web"}
+	close(ch)
+
+	msgs := collectFrame(ch)
+	if len(msgs) != 3 || msgs[2] != (TaskFinishedMsg{TaskID: "web"}) {
+		t.Fatalf("expected the three queued messages in one frame, got %v", msgs)
+	}
+	if msgs := collectFrame(ch); msgs != nil {
+		t.Fatalf("expected nil once the channel is drained, got %v", msgs)
+	}
+}
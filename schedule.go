@@ -0,0 +1,187 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule is either a fixed interval ("every 5m") or a five-field cron
+// expression (minute hour day-of-month month day-of-week).
+type Schedule struct {
+	Every time.Duration
+	cron  *cronSpec
+}
+
+type cronSpec struct {
+	minute, hour, dom, month, dow []bool
+	domStar, dowStar              bool
+}
+
+var cronDescriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+func parseSchedule(text string) (Schedule, error) {
+	text = strings.TrimSpace(text)
+	lower := strings.ToLower(text)
+	if strings.HasPrefix(lower, "every ") || strings.HasPrefix(lower, "@every ") {
+		value := strings.TrimSpace(text[strings.Index(text, " ")+1:])
+		every, err := time.ParseDuration(value)
+		if err != nil {
+			return Schedule{}, fmt.Errorf("invalid schedule interval %q", value)
+		}
+		if every < time.Second {
+			return Schedule{}, fmt.Errorf("schedule interval must be at least 1s")
+		}
+		return Schedule{Every: every}, nil
+	}
+	if expr, ok := cronDescriptors[lower]; ok {
+		text = expr
+	}
+	spec, err := parseCron(text)
+	if err != nil {
+		return Schedule{}, err
+	}
+	return Schedule{cron: spec}, nil
+}
+
+func parseCron(text string) (*cronSpec, error) {
+	fields := strings.Fields(text)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("schedule %q must be \"every <duration>\" or a 5-field cron expression", text)
+	}
+	spec := &cronSpec{
+		domStar: fields[2] == "*",
+		dowStar: fields[4] == "*",
+	}
+	var err error
+	if spec.minute, err = parseCronField(fields[0], 0, 59); err != nil {
+		return nil, err
+	}
+	if spec.hour, err = parseCronField(fields[1], 0, 23); err != nil {
+		return nil, err
+	}
+	if spec.dom, err = parseCronField(fields[2], 1, 31); err != nil {
+		return nil, err
+	}
+	if spec.month, err = parseCronField(fields[3], 1, 12); err != nil {
+		return nil, err
+	}
+	if spec.dow, err = parseCronField(fields[4], 0, 7); err != nil {
+		return nil, err
+	}
+	// Both 0 and 7 mean Sunday.
+	if spec.dow[7] {
+		spec.dow[0] = true
+	}
+	return spec, nil
+}
+
+func parseCronField(field string, min, max int) ([]bool, error) {
+	set := make([]bool, max+1)
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if idx := strings.Index(part, "/"); idx >= 0 {
+			n, err := strconv.Atoi(part[idx+1:])
+			if err != nil || n <= 0 {
+				return nil, fmt.Errorf("invalid cron step in %q", field)
+			}
+			step = n
+			part = part[:idx]
+		}
+		lo, hi := min, max
+		switch {
+		case part == "*":
+		case strings.Contains(part, "-"):
+			bounds := strings.SplitN(part, "-", 2)
+			a, errA := strconv.Atoi(bounds[0])
+			b, errB := strconv.Atoi(bounds[1])
+			if errA != nil || errB != nil {
+				return nil, fmt.Errorf("invalid cron range in %q", field)
+			}
+			lo, hi = a, b
+		default:
+			n, err := strconv.Atoi(part)
+			if err != nil {
+				return nil, fmt.Errorf("invalid cron value in %q", field)
+			}
+			lo, hi = n, n
+			if step > 1 {
+				hi = max
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return nil, fmt.Errorf("cron value out of range in %q", field)
+		}
+		for v := lo; v <= hi; v += step {
+			set[v] = true
+		}
+	}
+	return set, nil
+}
+
+// Next returns the first run time strictly after from.
+func (s Schedule) Next(from time.Time) time.Time {
+	if s.Every > 0 {
+		return from.Add(s.Every)
+	}
+	if s.cron == nil {
+		return time.Time{}
+	}
+	t := from.Truncate(time.Minute).Add(time.Minute)
+	// Four years covers every valid day/month combination, including Feb 29.
+	limit := t.AddDate(4, 0, 0)
+	for t.Before(limit) {
+		if !s.cron.month[int(t.Month())] {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.cron.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.cron.hour[t.Hour()] {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.cron.minute[t.Minute()] {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// dayMatches follows cron: when both day fields are restricted, either may
+// match.
+func (c *cronSpec) dayMatches(t time.Time) bool {
+	dom := c.dom[t.Day()]
+	dow := c.dow[int(t.Weekday())]
+	switch {
+	case c.domStar && c.dowStar:
+		return true
+	case c.domStar:
+		return dow
+	case c.dowStar:
+		return dom
+	default:
+		return dom || dow
+	}
+}
+
+// clockFormat picks how precisely to show the next run time.
+func (s Schedule) clockFormat() string {
+	if s.Every > 0 && s.Every%time.Minute != 0 {
+		return "15:04:05"
+	}
+	return "15:04"
+}
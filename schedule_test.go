@@ -0,0 +1,63 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseScheduleEvery(t *testing.T) {
+	sched, err := parseSchedule("every 5m")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	from := time.Date(2026, 1, 10, 12, 0, 30, 0, time.UTC)
+	if next := sched.Next(from); !next.Equal(from.Add(5 * time.Minute)) {
+		t.Fatalf("unexpected next run: %v", next)
+	}
+	if sched.clockFormat() != "15:04" {
+		t.Fatalf("expected minute precision")
+	}
+
+	sched, err = parseSchedule("every 30s")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if sched.clockFormat() != "15:04:05" {
+		t.Fatalf("expected second precision")
+	}
+}
+
+func TestParseScheduleCron(t *testing.T) {
+	cases := []struct {
+		expr string
+		from time.Time
+		want time.Time
+	}{
+		{"*/15 * * * *", time.Date(2026, 1, 10, 12, 7, 12, 0, time.UTC), time.Date(2026, 1, 10, 12, 15, 0, 0, time.UTC)},
+		{"0 9 * * 1-5", time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC), time.Date(2026, 1, 12, 9, 0, 0, 0, time.UTC)},
+		{"30 2 1 * *", time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC), time.Date(2026, 2, 1, 2, 30, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC), time.Date(2026, 1, 10, 13, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC), time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC), time.Date(2026, 1, 11, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.expr, func(t *testing.T) {
+			sched, err := parseSchedule(tc.expr)
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+			if got := sched.Next(tc.from); !got.Equal(tc.want) {
+				t.Fatalf("expected %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestParseScheduleErrors(t *testing.T) {
+	for _, expr := range []string{"every soon", "every 10ms", "* * *", "61 * * * *", "*/0 * * * *", "5-1 * * * *"} {
+		if _, err := parseSchedule(expr); err == nil {
+			t.Fatalf("expected error for %q", expr)
+		}
+	}
+}
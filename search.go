@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// outputSearch is the / search of the output pane. It searches the plain
+// text of the whole output, spilled lines included.
+type outputSearch struct {
+	query   []rune
+	editing bool
+	// line is the output line of the current match, or -1 if there is none.
+	line int
+}
+
+func (m *model) openSearch() {
+	if m.viewedBuffer() == nil {
+		return
+	}
+	m.search = &outputSearch{editing: true, line: -1}
+}
+
+// handleSearchKey edits the query; enter finds the first match from the top
+// of the viewport, wrapping around.
+func (m *model) handleSearchKey(msg tea.KeyMsg) {
+	search := m.search
+	switch msg.String() {
+	case "esc", "ctrl+c":
+		m.search = nil
+	case "enter":
+		search.editing = false
+		m.findNext(false, m.outputStart+m.viewport.YOffset)
+	case "backspace", "ctrl+h":
+		if len(search.query) > 0 {
+			search.query = search.query[:len(search.query)-1]
+		}
+	case "ctrl+u":
+		search.query = nil
+	case " ":
+		search.query = append(search.query, ' ')
+	default:
+		if msg.Type == tea.KeyRunes {
+			search.query = append(search.query, msg.Runes...)
+		}
+	}
+}
+
+// findNext moves to the next match at or after from, or the previous one
+// before it when backward, wrapping around the output.
+func (m *model) findNext(backward bool, from int) {
+	buf := m.viewedBuffer()
+	if buf == nil || m.search == nil {
+		return
+	}
+	query := string(m.search.query)
+	line, ok := buf.Find(query, from, backward)
+	if !ok {
+		if backward {
+			line, ok = buf.Find(query, buf.Len(), true)
+		} else {
+			line, ok = buf.Find(query, 0, false)
+		}
+	}
+	if !ok {
+		m.search.line = -1
+		return
+	}
+	m.search.line = line
+	m.showOutputLine(line)
+}
+
+// searchStatus is the status bar while searching.
+func (m model) searchStatus() string {
+	query := "/" + string(m.search.query)
+	if m.search.editing {
+		return query + "▏  enter find · esc cancel"
+	}
+	if m.search.line < 0 {
+		return query + "  no match · esc clear"
+	}
+	return fmt.Sprintf("%s  line %d · n next · N previous · esc clear", query, m.search.line+1)
+}
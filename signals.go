@@ -0,0 +1,178 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// menuSignals are the signals the signal menu offers, in order.
+var menuSignals = []string{"HUP", "INT", "QUIT", "TERM", "USR1", "USR2", "TTIN", "TTOU", "WINCH", "CONT", "STOP", "KILL"}
+
+// normalizeSignal turns "SIGHUP", "sighup" or "HUP" into "HUP". Only the
+// signals in the menu are known.
+func normalizeSignal(name string) (string, bool) {
+	name = strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(name)), "SIG")
+	for _, known := range menuSignals {
+		if name == known {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// signalMenu picks a signal for the selected running task or step.
+type signalMenu struct {
+	target   string
+	label    string
+	items    []signalItem
+	selected int
+}
+
+type signalItem struct {
+	label  string
+	signal string
+}
+
+// taskActions lists a task's named actions, sorted by name.
+func taskActions(def TaskDef) []signalItem {
+	names := make([]string, 0, len(def.Actions))
+	for name := range def.Actions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	items := make([]signalItem, 0, len(names))
+	for _, name := range names {
+		sig, _ := normalizeSignal(def.Actions[name])
+		items = append(items, signalItem{label: name, signal: sig})
+	}
+	return items
+}
+
+// openSignalMenu offers the selected entry's actions followed by every
+// signal in menuSignals. Entries that aren't running have nothing to signal.
+func (m *model) openSignalMenu() {
+	entry := m.selectedEntry()
+	if entry == nil || !m.isRunningTarget(entry.Target) {
+		return
+	}
+	taskName := entry.Target
+	if name, ok := stepTaskFromID(entry.Target); ok {
+		taskName = name
+	}
+	var items []signalItem
+	if task := m.taskByName[taskName]; task != nil {
+		items = taskActions(task.Def)
+	}
+	for _, sig := range menuSignals {
+		items = append(items, signalItem{label: "SIG" + sig, signal: sig})
+	}
+	m.signals = &signalMenu{target: entry.Target, label: entry.Label, items: items}
+}
+
+func (m *model) handleSignalMenuKey(key string) {
+	menu := m.signals
+	switch key {
+	case "esc", "q", "ctrl+s":
+		m.signals = nil
+	case "up", "k":
+		if menu.selected > 0 {
+			menu.selected--
+		}
+	case "down", "j":
+		if menu.selected < len(menu.items)-1 {
+			menu.selected++
+		}
+	case "enter":
+		m.signals = nil
+		m.sendSignal(menu.target, menu.items[menu.selected].signal)
+	default:
+		if len(key) == 1 && key >= "1" && key <= "9" {
+			if idx := int(key[0] - '1'); idx < len(menu.items) {
+				m.signals = nil
+				m.sendSignal(menu.target, menu.items[idx].signal)
+			}
+		}
+	}
+}
+
+// sendSignal delivers sig to the process group of every command running
+// for target and notes it in target's output.
+func (m *model) sendSignal(target, sig string) {
+	if m.remote != nil {
+		m.remote.send(daemonRequest{Op: "signal", Target: target, Command: sig})
+		m.handleOutput(TaskOutputMsg{Target: target, Line: fmt.Sprintf("suite: sent SIG%s", sig)})
+		return
+	}
+	sent, err := m.signalTarget(target, sig)
+	line := fmt.Sprintf("suite: sent SIG%s to %d process group(s)", sig, sent)
+	if err != nil {
+		line = fmt.Sprintf("suite: SIG%s: %v", sig, err)
+	}
+	m.handleOutput(TaskOutputMsg{Target: target, Line: line})
+}
+
+// signalTarget signals the commands running for target: all of a run the UI
+// started, or the commands of a task or step inside another run.
+func (m *model) signalTarget(target, sig string) (int, error) {
+	var pids []int
+	if procs := m.runProcs[target]; procs != nil {
+		pids = procs.pids(func(string) bool { return true })
+	} else {
+		match := func(command string) bool {
+			taskName, ok := stepTaskFromID(command)
+			return command == target || (ok && taskName == target)
+		}
+		for _, procs := range m.runProcs {
+			pids = append(pids, procs.pids(match)...)
+		}
+	}
+	if len(pids) == 0 {
+		return 0, fmt.Errorf("nothing running")
+	}
+	sent := 0
+	var firstErr error
+	for _, pid := range pids {
+		if err := signalProcessGroup(pid, sig); err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		sent++
+	}
+	if sent == 0 {
+		return 0, firstErr
+	}
+	return sent, nil
+}
+
+func (m model) renderSignalMenu() string {
+	lines := []string{modalTitleStyle.Render("Send to " + m.signals.label), ""}
+	width := 0
+	for _, item := range m.signals.items {
+		if w := ansi.StringWidth(item.label); w > width {
+			width = w
+		}
+	}
+	for i, item := range m.signals.items {
+		key := " "
+		if i < 9 {
+			key = fmt.Sprint(i + 1)
+		}
+		line := fmt.Sprintf("%s  %s", modalKeyStyle.Render(key), padRight(item.label, width))
+		if item.label != "SIG"+item.signal {
+			line += "  " + modalHintStyle.Render("SIG"+item.signal)
+		}
+		if i == m.signals.selected {
+			line = selectedStyle.Render(line)
+		}
+		lines = append(lines, line)
+	}
+	lines = append(lines, "", modalHintStyle.Render("Enter or 1-9 to send · Esc to close"))
+	modal := modalStyle.Render(strings.Join(lines, "\n"))
+	return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, modal)
+}
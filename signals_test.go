@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestNormalizeSignal(t *testing.T) {
+	for input, want := range map[string]string{"SIGHUP": "HUP", "hup": "HUP", " usr2 ": "USR2", "sigttin": "TTIN"} {
+		if got, ok := normalizeSignal(input); !ok || got != want {
+			t.Fatalf("normalizeSignal(%q) = %q, %v; want %q", input, got, ok, want)
+		}
+	}
+	if _, ok := normalizeSignal("SIGFOO"); ok {
+		t.Fatalf("expected unknown signal to be rejected")
+	}
+}
+
+func TestSignalMenuSendsToProcessGroup(t *testing.T) {
+	cfg := Config{
+		Tasks: []TaskDef{{
+			Name:    "web",
+			Key:     "w",
+			Actions: map[string]string{"reload": "SIGHUP", "dump": "ttin"},
+			Cmd:     StepList{{Value: "server", Kind: StepCommand}},
+		}},
+		SidebarWidth: 32,
+	}
+	m := newModel(cfg)
+	m.handleTaskStarted("web")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ctx, m.runProcs["web"] = withRunProcesses(ctx)
+	msgCh := make(chan tea.Msg, 8)
+	go func() {
+		_, _ = runSingle(ctx, "trap 'echo reloaded' HUP; echo ready; while :; do sleep 0.05; done", "/bin/sh", nil, msgCh, "web")
+	}()
+	select {
+	case msg := <-msgCh:
+		if msg.(TaskOutputMsg).Line != "ready" {
+			t.Fatalf("unexpected output %#v", msg)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("expected the command to start")
+	}
+
+	m.openSignalMenu()
+	if m.signals == nil || len(m.signals.items) != 2+len(menuSignals) {
+		t.Fatalf("expected actions followed by signals, got %#v", m.signals)
+	}
+	if first := m.signals.items[0]; first.label != "dump" || first.signal != "TTIN" {
+		t.Fatalf("expected actions sorted by name, got %#v", first)
+	}
+	m.handleSignalMenuKey("2")
+	if m.signals != nil {
+		t.Fatalf("expected the menu to close after sending")
+	}
+	if task := m.taskByName["web"]; !strings.Contains(task.Output.Last(), "sent SIGHUP to 1") {
+		t.Fatalf("expected a note in the output, got %v", task.Output.All())
+	}
+	// The group's sleep dies of the signal too, and the shell may say so.
+	deadline := time.After(2 * time.Second)
+	for {
+		select {
+		case msg := <-msgCh:
+			if msg.(TaskOutputMsg).Line == "reloaded" {
+				return
+			}
+		case <-deadline:
+			t.Fatalf("expected the command to handle SIGHUP")
+		}
+	}
+}
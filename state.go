@@ -0,0 +1,372 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// processes records the process groups runSingle starts, so a later session
+// can find them if this one dies without cleaning up. Nil (the default, and
+// in tests) tracks nothing.
+var processes *processTable
+
+type processTable struct {
+	mu      sync.Mutex
+	path    string
+	state   sessionState
+	entries map[int]trackedProcess
+	closed  bool
+}
+
+// sessionState is the state file of one suite process.
+type sessionState struct {
+	PID       int              `json:"pid"`
+	Config    string           `json:"config"`
+	Processes []trackedProcess `json:"processes"`
+}
+
+type trackedProcess struct {
+	PGID    int       `json:"pgid"`
+	Target  string    `json:"target"`
+	Command string    `json:"command"`
+	Cgroup  string    `json:"cgroup,omitempty"`
+	Started time.Time `json:"started"`
+}
+
+// leftover is a tracked process group that outlived its session.
+type leftover struct {
+	trackedProcess
+	Session   int
+	StatePath string
+	Members   []string
+}
+
+// sessionDir returns the directory for sockets, daemon logs and state files:
+// $XDG_RUNTIME_DIR/suite, or suite in the user cache directory. Other local
+// users must not be able to plant files there or listen on the socket, so
+// it is created 0700 and refused unless only the current user can use it.
+func sessionDir() (string, error) {
+	base := os.Getenv("XDG_RUNTIME_DIR")
+	if base == "" {
+		cache, err := os.UserCacheDir()
+		if err != nil {
+			return "", err
+		}
+		if err := os.MkdirAll(cache, 0o700); err != nil {
+			return "", err
+		}
+		base = cache
+	}
+	dir := filepath.Join(base, "suite")
+	if err := os.Mkdir(dir, 0o700); err != nil && !errors.Is(err, os.ErrExist) {
+		return "", err
+	}
+	info, err := os.Lstat(dir)
+	if err != nil {
+		return "", err
+	}
+	if !info.IsDir() || !userPrivate(info) {
+		return "", fmt.Errorf("%s must be a directory only you can access", dir)
+	}
+	return dir, nil
+}
+
+// sessionPath derives a per-config path in sessionDir, so every project
+// gets its own socket and state files.
+func sessionPath(configPath, suffix string) (string, error) {
+	dir, err := sessionDir()
+	if err != nil {
+		return "", err
+	}
+	abs, err := filepath.Abs(configPath)
+	if err != nil {
+		abs = configPath
+	}
+	sum := sha256.Sum256([]byte(abs))
+	return filepath.Join(dir, fmt.Sprintf("suite-%x%s", sum[:6], suffix)), nil
+}
+
+func statePath(configPath string, pid int) (string, error) {
+	return sessionPath(configPath, fmt.Sprintf("-%d.state", pid))
+}
+
+// startProcessTracking records this session's processes until the returned
+// func is called. Without a usable session directory nothing is recorded.
+func startProcessTracking(configPath string) func() {
+	path, err := statePath(configPath, os.Getpid())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "process tracking disabled: %v\n", err)
+		return func() {}
+	}
+	abs, _ := filepath.Abs(configPath)
+	processes = &processTable{
+		path:    path,
+		state:   sessionState{PID: os.Getpid(), Config: abs},
+		entries: make(map[int]trackedProcess),
+	}
+	return processes.close
+}
+
+func (t *processTable) add(pgid int, target, command, cgroup string) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		return
+	}
+	t.entries[pgid] = trackedProcess{PGID: pgid, Target: target, Command: command, Cgroup: cgroup, Started: time.Now()}
+	t.save()
+}
+
+func (t *processTable) remove(pgid int) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		return
+	}
+	delete(t.entries, pgid)
+	t.save()
+}
+
+func (t *processTable) close() {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.closed = true
+	// Anything still tracked outlived the shutdown; keep it for the next
+	// session to find.
+	if len(t.entries) == 0 {
+		_ = os.Remove(t.path)
+	}
+}
+
+// save rewrites the state file; it is removed while nothing runs.
+func (t *processTable) save() {
+	if len(t.entries) == 0 {
+		_ = os.Remove(t.path)
+		return
+	}
+	state := t.state
+	state.Processes = make([]trackedProcess, 0, len(t.entries))
+	for _, entry := range t.entries {
+		state.Processes = append(state.Processes, entry)
+	}
+	sort.Slice(state.Processes, func(i, j int) bool {
+		return state.Processes[i].Started.Before(state.Processes[j].Started)
+	})
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return
+	}
+	// A fresh temp file, so nothing planted at a fixed name is followed.
+	tmp, err := os.CreateTemp(filepath.Dir(t.path), filepath.Base(t.path)+".*.tmp")
+	if err != nil {
+		return
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return
+	}
+	_ = os.Rename(tmp.Name(), t.path)
+}
+
+// findLeftovers lists process groups from earlier sessions of configPath
+// whose suite is gone but which are still running. State files with nothing
+// left alive are removed.
+func findLeftovers(configPath string) []leftover {
+	pattern, err := sessionPath(configPath, "-*.state")
+	if err != nil {
+		return nil
+	}
+	paths, _ := filepath.Glob(pattern)
+	var found []leftover
+	for _, path := range paths {
+		// Only state files of the current user's own sessions are trusted:
+		// they name process groups and cgroups to kill.
+		if info, err := os.Lstat(path); err != nil || !info.Mode().IsRegular() || !userPrivate(info) {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		var state sessionState
+		if err := json.Unmarshal(data, &state); err != nil {
+			continue
+		}
+		if state.PID == os.Getpid() || processAlive(state.PID) {
+			continue
+		}
+		alive := 0
+		for _, proc := range state.Processes {
+			if !ownsGroup(proc) {
+				continue
+			}
+			members := processGroupMembers(proc.PGID)
+			for _, pid := range cgroupPIDs(proc.Cgroup) {
+				if !containsMember(members, pid) {
+					members = append(members, strings.TrimSpace(strconv.Itoa(pid)+" "+processArgs(pid)))
+				}
+			}
+			if len(members) == 0 {
+				continue
+			}
+			alive++
+			found = append(found, leftover{trackedProcess: proc, Session: state.PID, StatePath: path, Members: members})
+		}
+		if alive == 0 {
+			_ = os.Remove(path)
+		}
+	}
+	return found
+}
+
+func containsMember(members []string, pid int) bool {
+	prefix := strconv.Itoa(pid) + " "
+	for _, member := range members {
+		if member == strconv.Itoa(pid) || strings.HasPrefix(member, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// startTolerance allows for the granularity of process start times (whole
+// seconds from ps) and the moment between starting a command and tracking it.
+const startTolerance = 2 * time.Second
+
+// ownsGroup reports whether the group in proc still is the one a session
+// started: PGIDs come from an old state file and may have been reused since.
+// A PGID can't be reused while any member of the old group lives, so only
+// the leader needs checking: if a process with the PGID as its PID runs, it
+// must have started when proc did, running proc's command.
+func ownsGroup(proc trackedProcess) bool {
+	if !processAlive(proc.PGID) {
+		return true
+	}
+	started, ok := processStartTime(proc.PGID)
+	if !ok || started.Sub(proc.Started).Abs() > startTolerance {
+		return false
+	}
+	return commandMatches(processArgs(proc.PGID), proc.Command)
+}
+
+// commandMatches reports whether args, a group leader's command line, runs
+// command. The shell may exec the command in its place, and programs may
+// rename themselves, so the program name appearing in command is enough.
+func commandMatches(args, command string) bool {
+	fields := strings.Fields(args)
+	line := strings.TrimSpace(firstLine(command))
+	if len(fields) == 0 || line == "" {
+		return false
+	}
+	if strings.Contains(args, line) {
+		return true
+	}
+	program := filepath.Base(fields[0])
+	for _, word := range strings.Fields(command) {
+		if filepath.Base(word) == program {
+			return true
+		}
+	}
+	return false
+}
+
+// stopLeftovers stops every leftover group and drops their state files.
+// Groups are checked again first, since time passes while the user decides.
+func stopLeftovers(found []leftover) {
+	done := map[string]bool{}
+	for _, proc := range found {
+		if !ownsGroup(proc.trackedProcess) {
+			continue
+		}
+		stopProcessGroup(proc.PGID)
+		if proc.Cgroup != "" {
+			removeCgroup(proc.Cgroup)
+		}
+		if !done[proc.StatePath] {
+			done[proc.StatePath] = true
+			_ = os.Remove(proc.StatePath)
+		}
+	}
+}
+
+func writeLeftovers(out io.Writer, found []leftover) {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "PGID\tTASK\tSTARTED\tCOMMAND")
+	for _, proc := range found {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", proc.PGID, proc.Target, proc.Started.Local().Format("2006-01-02 15:04:05"), firstLine(proc.Command))
+	}
+	_ = w.Flush()
+	for _, proc := range found {
+		fmt.Fprintf(out, "\npgid %d (suite pid %d):\n", proc.PGID, proc.Session)
+		for _, member := range proc.Members {
+			fmt.Fprintf(out, "  %s\n", member)
+		}
+	}
+}
+
+// offerLeftoverCleanup reports leftovers from a crashed session before a
+// new one starts, and offers to stop them when stdin is a terminal.
+func offerLeftoverCleanup(configPath string) {
+	found := findLeftovers(configPath)
+	if len(found) == 0 {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "%d process group(s) from an earlier suite session are still running:\n\n", len(found))
+	writeLeftovers(os.Stderr, found)
+	fmt.Fprintln(os.Stderr)
+	if !isTerminalFn(os.Stdin) {
+		fmt.Fprintln(os.Stderr, "Run suite doctor --kill to stop them.")
+		return
+	}
+	ok, err := promptYesNo("Kill them?")
+	if err != nil || !ok {
+		return
+	}
+	stopLeftovers(found)
+}
+
+// runDoctorCommand lists leftover processes, stopping them with --kill.
+func runDoctorCommand(args []string, out io.Writer) int {
+	fs, configPath := commandFlags("doctor")
+	kill := fs.Bool("kill", false, "stop leftover processes")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	found := findLeftovers(*configPath)
+	if len(found) == 0 {
+		fmt.Fprintln(out, "No leftover processes.")
+		return 0
+	}
+	writeLeftovers(out, found)
+	if *kill {
+		stopLeftovers(found)
+		fmt.Fprintf(out, "\nStopped %d process group(s).\n", len(found))
+		return 0
+	}
+	fmt.Fprintln(out, "\nStop them with suite doctor --kill.")
+	return 1
+}
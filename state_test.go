@@ -0,0 +1,264 @@
+//go:build !windows
+
+package main
+
+import (
+	"encoding/json"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testStatePath(t *testing.T, pid int) string {
+	t.Helper()
+	path, err := statePath(".suite.yml", pid)
+	if err != nil {
+		t.Fatalf("state path: %v", err)
+	}
+	return path
+}
+
+func TestProcessTableStateFile(t *testing.T) {
+	t.Setenv("XDG_RUNTIME_DIR", t.TempDir())
+	stop := startProcessTracking(".suite.yml")
+	defer func() { processes = nil }()
+
+	path := testStatePath(t, os.Getpid())
+	processes.add(4242, "web", "bin/server", "")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("expected state file: %v", err)
+	}
+	var state sessionState
+	if err := json.Unmarshal(data, &state); err != nil {
+		t.Fatalf("decode state: %v", err)
+	}
+	if state.PID != os.Getpid() || len(state.Processes) != 1 || state.Processes[0].Target != "web" {
+		t.Fatalf("unexpected state %#v", state)
+	}
+
+	processes.remove(4242)
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("expected state file removed once nothing runs")
+	}
+	stop()
+	processes.add(4243, "web", "bin/server", "")
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("expected no tracking after stop")
+	}
+}
+
+func TestFindAndStopLeftovers(t *testing.T) {
+	t.Setenv("XDG_RUNTIME_DIR", t.TempDir())
+
+	// A finished process stands in for the crashed suite.
+	dead := exec.Command("true")
+	if err := dead.Run(); err != nil {
+		t.Fatalf("run true: %v", err)
+	}
+	child := exec.Command("sleep", "30")
+	prepareCommand(child)
+	if err := child.Start(); err != nil {
+		t.Fatalf("start sleep: %v", err)
+	}
+	exited := make(chan struct{})
+	go func() {
+		_ = child.Wait()
+		close(exited)
+	}()
+	defer func() { _ = child.Process.Kill() }()
+
+	path := testStatePath(t, dead.Process.Pid)
+	state := sessionState{PID: dead.Process.Pid, Processes: []trackedProcess{
+		{PGID: child.Process.Pid, Target: "web", Command: "sleep 30", Started: time.Now()},
+		{PGID: dead.Process.Pid, Target: "gone", Command: "true", Started: time.Now()},
+	}}
+	data, _ := json.Marshal(state)
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("write state: %v", err)
+	}
+
+	found := findLeftovers(".suite.yml")
+	if len(found) != 1 || found[0].Target != "web" {
+		t.Fatalf("expected the running group only, got %#v", found)
+	}
+	if len(found[0].Members) == 0 || !strings.Contains(found[0].Members[0], "sleep") {
+		t.Fatalf("expected group members, got %v", found[0].Members)
+	}
+
+	var out strings.Builder
+	if code := runDoctorCommand([]string{"--kill"}, &out); code != 0 {
+		t.Fatalf("doctor --kill exited %d: %s", code, out.String())
+	}
+	if !strings.Contains(out.String(), "Stopped 1 process group") {
+		t.Fatalf("unexpected doctor output:\n%s", out.String())
+	}
+	select {
+	case <-exited:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("expected leftover to be stopped")
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("expected state file removed")
+	}
+	out.Reset()
+	if code := runDoctorCommand(nil, &out); code != 0 || !strings.Contains(out.String(), "No leftover") {
+		t.Fatalf("expected clean doctor, got %d: %s", code, out.String())
+	}
+}
+
+func TestLeftoversSkipReusedGroups(t *testing.T) {
+	t.Setenv("XDG_RUNTIME_DIR", t.TempDir())
+
+	dead := exec.Command("true")
+	if err := dead.Run(); err != nil {
+		t.Fatalf("run true: %v", err)
+	}
+	// An unrelated group that happens to have the tracked PGID.
+	other := exec.Command("sleep", "30")
+	prepareCommand(other)
+	if err := other.Start(); err != nil {
+		t.Fatalf("start sleep: %v", err)
+	}
+	defer func() {
+		_ = other.Process.Kill()
+		_ = other.Wait()
+	}()
+
+	path := testStatePath(t, dead.Process.Pid)
+	state := sessionState{PID: dead.Process.Pid, Processes: []trackedProcess{
+		{PGID: other.Process.Pid, Target: "web", Command: "sleep 30", Started: time.Now().Add(-time.Hour)},
+		{PGID: other.Process.Pid, Target: "api", Command: "bin/api", Started: time.Now()},
+	}}
+	data, _ := json.Marshal(state)
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("write state: %v", err)
+	}
+
+	if found := findLeftovers(".suite.yml"); len(found) != 0 {
+		t.Fatalf("expected reused groups to be skipped, got %#v", found)
+	}
+	stopLeftovers([]leftover{{trackedProcess: state.Processes[0], StatePath: path}})
+	if !processAlive(other.Process.Pid) {
+		t.Fatalf("expected the unrelated group to be left alone")
+	}
+}
+
+func TestLeftoversIgnoreUntrustedStateFiles(t *testing.T) {
+	t.Setenv("XDG_RUNTIME_DIR", t.TempDir())
+
+	dead := exec.Command("true")
+	if err := dead.Run(); err != nil {
+		t.Fatalf("run true: %v", err)
+	}
+	child := exec.Command("sleep", "30")
+	prepareCommand(child)
+	if err := child.Start(); err != nil {
+		t.Fatalf("start sleep: %v", err)
+	}
+	defer func() {
+		_ = child.Process.Kill()
+		_ = child.Wait()
+	}()
+
+	path := testStatePath(t, dead.Process.Pid)
+	state := sessionState{PID: dead.Process.Pid, Processes: []trackedProcess{
+		{PGID: child.Process.Pid, Target: "web", Command: "sleep 30", Started: time.Now()},
+	}}
+	data, _ := json.Marshal(state)
+	if err := os.WriteFile(path, data, 0o666); err != nil {
+		t.Fatalf("write state: %v", err)
+	}
+	if err := os.Chmod(path, 0o666); err != nil {
+		t.Fatalf("chmod: %v", err)
+	}
+	if found := findLeftovers(".suite.yml"); len(found) != 0 {
+		t.Fatalf("expected a state file others can write to be ignored, got %#v", found)
+	}
+}
+
+func TestProcessTableSaveIgnoresPlantedTempFile(t *testing.T) {
+	t.Setenv("XDG_RUNTIME_DIR", t.TempDir())
+	stop := startProcessTracking(".suite.yml")
+	defer func() { processes = nil }()
+	defer stop()
+
+	victim := filepath.Join(t.TempDir(), "victim")
+	if err := os.WriteFile(victim, []byte("keep"), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	path := testStatePath(t, os.Getpid())
+	if err := os.Symlink(victim, path+".tmp"); err != nil {
+		t.Fatalf("symlink: %v", err)
+	}
+	processes.add(4242, "web", "bin/server", "")
+	if data, _ := os.ReadFile(victim); string(data) != "keep" {
+		t.Fatalf("expected the symlink target to be left alone, got %q", data)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("expected state file: %v", err)
+	}
+	processes.remove(4242)
+}
+
+func TestCommandMatches(t *testing.T) {
+	cases := []struct {
+		args, command string
+		want          bool
+	}{
+		{"/bin/sh -c bin/rails s", "bin/rails s", true},
+		{"sleep 30", "sleep 30", true},
+		{"bin/server --port 3000", "PORT=1 bin/server --port 3000", true},
+		{"vim notes.txt", "bin/rails s", false},
+		{"", "bin/rails s", false},
+	}
+	for _, tc := range cases {
+		if got := commandMatches(tc.args, tc.command); got != tc.want {
+			t.Fatalf("commandMatches(%q, %q) = %v", tc.args, tc.command, got)
+		}
+	}
+}
+
+func TestSessionFilesArePrivate(t *testing.T) {
+	base := t.TempDir()
+	t.Setenv("XDG_RUNTIME_DIR", base)
+	dir, err := sessionDir()
+	if err != nil {
+		t.Fatalf("session dir: %v", err)
+	}
+	if info, err := os.Stat(dir); err != nil || info.Mode().Perm() != 0o700 {
+		t.Fatalf("expected a 0700 session dir, got %v %v", info.Mode(), err)
+	}
+
+	socketPath := filepath.Join(dir, "d.sock")
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Skipf("unix sockets unavailable: %v", err)
+	}
+	defer listener.Close()
+	if err := os.Chmod(socketPath, 0o666); err != nil {
+		t.Fatalf("chmod: %v", err)
+	}
+	if _, err := dialSocket(socketPath); err == nil {
+		t.Fatalf("expected a socket others can use to be refused")
+	}
+	if err := os.Chmod(socketPath, 0o600); err != nil {
+		t.Fatalf("chmod: %v", err)
+	}
+	conn, err := dialSocket(socketPath)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	conn.Close()
+
+	if err := os.Chmod(dir, 0o777); err != nil {
+		t.Fatalf("chmod: %v", err)
+	}
+	if _, err := sessionDir(); err == nil {
+		t.Fatalf("expected a shared session dir to be refused")
+	}
+}
@@ -19,6 +19,10 @@ type Step struct {
 	Value string
 	Name  string
 	Kind  StepKind
+	If    Condition
+
+	AllowFailure bool
+	Env          map[string]string
 }
 
 type StepList []Step
@@ -63,6 +67,8 @@ func parseStepNode(node *yaml.Node) (Step, error) {
 			name    string
 			cmd     string
 			task    string
+			cond    Condition
+			allow   bool
 			cmdSet  bool
 			taskSet bool
 		)
@@ -91,16 +97,26 @@ func parseStepNode(node *yaml.Node) (Step, error) {
 				}
 				task = strings.TrimSpace(val.Value)
 				taskSet = true
+			case "if":
+				parsed, err := parseConditionNode(val)
+				if err != nil {
+					return Step{}, err
+				}
+				cond = parsed
+			case "allow_failure":
+				if err := val.Decode(&allow); err != nil {
+					return Step{}, fmt.Errorf("step allow_failure must be a boolean")
+				}
 			}
 		}
 		if cmdSet && taskSet {
 			return Step{}, fmt.Errorf("step cannot define both cmd and task")
 		}
 		if cmdSet {
-			return Step{Value: cmd, Name: name, Kind: StepCommand}, nil
+			return Step{Value: cmd, Name: name, Kind: StepCommand, If: cond, AllowFailure: allow}, nil
 		}
 		if taskSet {
-			return Step{Value: task, Name: name, Kind: StepTask}, nil
+			return Step{Value: task, Name: name, Kind: StepTask, If: cond, AllowFailure: allow}, nil
 		}
 		return Step{}, fmt.Errorf("step must be a string, {cmd: ...}, or {task: ...}")
 	default:
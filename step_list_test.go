@@ -70,3 +70,33 @@ func TestStepListUnmarshalTaskMapWithName(t *testing.T) {
 		t.Fatalf("unexpected steps: %#v", cfg.Seq)
 	}
 }
+
+func TestStepListUnmarshalCondition(t *testing.T) {
+	var cfg struct {
+		Seq StepList `yaml:"seq"`
+	}
+
+	if err := yaml.Unmarshal([]byte("seq: [{cmd: bundle install, if: {changed: Gemfile.lock}}]"), &cfg); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if len(cfg.Seq) != 1 || len(cfg.Seq[0].If.Changed) != 1 || cfg.Seq[0].If.Changed[0] != "Gemfile.lock" {
+		t.Fatalf("unexpected steps: %#v", cfg.Seq)
+	}
+}
+
+func TestStepListUnmarshalAllowFailure(t *testing.T) {
+	var cfg struct {
+		Seq StepList `yaml:"seq"`
+	}
+
+	if err := yaml.Unmarshal([]byte("seq: [{cmd: rubocop, allow_failure: true}, {task: test}]"), &cfg); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if len(cfg.Seq) != 2 || !cfg.Seq[0].AllowFailure || cfg.Seq[1].AllowFailure {
+		t.Fatalf("unexpected steps: %#v", cfg.Seq)
+	}
+
+	if err := yaml.Unmarshal([]byte("seq: [{cmd: rubocop, allow_failure: maybe}]"), &cfg); err == nil {
+		t.Fatalf("expected error for non-boolean allow_failure")
+	}
+}
@@ -14,6 +14,13 @@ const (
 )
 
 func taskSteps(taskName string, def TaskDef, resolve TaskResolver) (StepMode, StepList, bool) {
+	if !def.Matrix.IsZero() {
+		mode, base := matrixSteps(def)
+		if len(base) != 1 {
+			return StepModeNone, base, false
+		}
+		return mode, expandMatrix(def.Matrix, base[0]), true
+	}
 	if len(def.Parallel) > 0 {
 		return StepModeParallel, def.Parallel, true
 	}
@@ -36,6 +43,16 @@ func taskSteps(taskName string, def TaskDef, resolve TaskResolver) (StepMode, St
 	return StepModeNone, def.Cmd, false
 }
 
+func matrixSteps(def TaskDef) (StepMode, StepList) {
+	if len(def.Parallel) > 0 {
+		return StepModeParallel, def.Parallel
+	}
+	if len(def.Seq) > 0 {
+		return StepModeSeq, def.Seq
+	}
+	return StepModeSeq, def.Cmd
+}
+
 func resolveStepKind(step Step, resolve TaskResolver) (StepKind, string) {
 	value := strings.TrimSpace(step.Value)
 	switch step.Kind {
@@ -10,6 +10,7 @@ var (
 	colorSuccess  = lipgloss.AdaptiveColor{Light: "#15803d", Dark: "#4ade80"}
 	colorFailed   = lipgloss.AdaptiveColor{Light: "#b91c1c", Dark: "#f87171"}
 	colorCanceled = lipgloss.AdaptiveColor{Light: "#9a3412", Dark: "#fb923c"}
+	colorWarning  = lipgloss.AdaptiveColor{Light: "#a16207", Dark: "#facc15"}
 
 	colorSelectedBg = lipgloss.AdaptiveColor{Light: "#e5e7eb", Dark: "#1f2937"}
 	colorSelectedFg = lipgloss.AdaptiveColor{Light: "#111827", Dark: "#f9fafb"}
@@ -17,13 +18,10 @@ var (
 	colorModalBg    = lipgloss.AdaptiveColor{Light: "#f8fafc", Dark: "#0f172a"}
 	colorModalFg    = lipgloss.AdaptiveColor{Light: "#0f172a", Dark: "#e2e8f0"}
 
-	titleStyle            = lipgloss.NewStyle().Bold(true)
-	sectionStyle          = lipgloss.NewStyle().Foreground(colorMuted)
-	comboStyle            = lipgloss.NewStyle().Foreground(colorMuted)
-	selectedStyle         = lipgloss.NewStyle().Background(colorSelectedBg).Foreground(colorSelectedFg).Bold(true)
-	disabledStyle         = lipgloss.NewStyle().Foreground(colorMuted).Faint(true)
-	selectedDisabledStyle = lipgloss.NewStyle().Background(colorSelectedBg).Foreground(colorMuted).Faint(true)
-	outputSelectionStyle  = lipgloss.NewStyle().Background(colorSelectedBg).Foreground(colorSelectedFg)
+	titleStyle           = lipgloss.NewStyle().Bold(true)
+	sectionStyle         = lipgloss.NewStyle().Foreground(colorMuted)
+	selectedStyle        = lipgloss.NewStyle().Background(colorSelectedBg).Foreground(colorSelectedFg).Bold(true)
+	outputSelectionStyle = lipgloss.NewStyle().Background(colorSelectedBg).Foreground(colorSelectedFg)
 
 	sidebarStyle       = lipgloss.NewStyle().Border(lipgloss.NormalBorder()).Padding(0, 1)
 	outputStyle        = lipgloss.NewStyle().Border(lipgloss.NormalBorder())
@@ -64,6 +62,12 @@ func statusStyle(status TaskStatus) lipgloss.Style {
 		return lipgloss.NewStyle().Foreground(colorFailed)
 	case StatusCanceled:
 		return lipgloss.NewStyle().Foreground(colorCanceled)
+	case StatusSkipped:
+		return lipgloss.NewStyle().Foreground(colorMuted).Faint(true)
+	case StatusWarning:
+		return lipgloss.NewStyle().Foreground(colorWarning)
+	case StatusQueued:
+		return lipgloss.NewStyle().Foreground(colorMuted)
 	default:
 		return lipgloss.NewStyle().Foreground(colorMuted)
 	}
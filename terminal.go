@@ -0,0 +1,539 @@
+package main
+
+import (
+	"bytes"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/charmbracelet/x/ansi"
+)
+
+// termRows is how many recent lines of an output stay editable, for tools
+// that move the cursor up to redraw several lines of progress.
+const termRows = 64
+
+// termCols caps cursor moves, so a sequence like ESC[99999C can't make a
+// line allocate a cell for every column it skips. Text itself still goes
+// past it.
+const termCols = 1024
+
+// terminal turns a command's raw output lines into the lines a terminal
+// would show: carriage returns and erase-line sequences rewrite the current
+// line, cursor moves redraw recent lines, and colors carry on across lines.
+// Every rendered line sets its own colors and resets them at its end, so it
+// shows correctly on its own. With strip, lines are rendered without colors.
+type terminal struct {
+	strip bool
+	rows  []*termRow // the editable rows, oldest first
+	first int        // line number of rows[0]
+	row   int        // cursor line number
+	col   int
+	pen   sgrState
+}
+
+type termRow struct {
+	cells []termCell
+	// text is the row's content, text and SGR sequences only, until an edit
+	// needs its cells: most lines are written once, whole, and never touched
+	// again. textStyle is in effect at its start and endStyle at its end.
+	text      string
+	textStyle sgrState
+	endStyle  sgrState
+	shown     bool
+	placed    []placedRow
+}
+
+type termCell struct {
+	text  string // one grapheme; "" for the second column of a wide one
+	style sgrState
+}
+
+// outputDest is a buffer a target's lines go to, with the prefix they get
+// there.
+type outputDest struct {
+	buf    *outputBuffer
+	prefix string
+	strip  bool
+}
+
+// placedRow is where a row was written, so it can be written over.
+type placedRow struct {
+	dest   outputDest
+	index  int
+	resets int
+}
+
+// write applies one line from streamLines, which ends in "\r" when it was
+// cut at a carriage return rather than a newline, and returns the rows it
+// changed, oldest first.
+func (t *terminal) write(line string) []*termRow {
+	partial := strings.HasSuffix(line, "\r")
+	text := strings.TrimSuffix(line, "\r")
+	if t.col == 0 && t.row == t.first+len(t.rows) {
+		// The common case: a whole new line of text and colors.
+		if end, ok := t.pen.styledText(text); ok {
+			row := t.touch(t.row)
+			row.text, row.textStyle, row.endStyle = text, t.pen, end
+			t.pen = end
+			if !partial {
+				t.row++
+			}
+			return []*termRow{row}
+		}
+	}
+
+	changed := map[int]*termRow{}
+	for i := 0; i < len(line); {
+		c := line[i]
+		switch {
+		case c == ansi.ESC:
+			i += t.escape(line[i:], changed)
+			continue
+		case c == '\r':
+			t.col = 0
+		case c == '\b':
+			t.col = max(t.col-1, 0)
+		case c == '\t':
+			t.moveCol((t.col/8 + 1) * 8)
+		case c < ' ' || c == ansi.DEL:
+		default:
+			r, size := utf8.DecodeRuneInString(line[i:])
+			t.put(r, changed)
+			i += size
+			continue
+		}
+		i++
+	}
+	if !partial {
+		changed[t.row] = t.touch(t.row)
+		t.row++
+		t.col = 0
+	}
+	return sortedRows(changed)
+}
+
+// escape applies the escape sequence at the start of s and returns its
+// length. Only what redraws a line is applied; the rest is dropped.
+func (t *terminal) escape(s string, changed map[int]*termRow) int {
+	if len(s) < 2 {
+		return len(s)
+	}
+	switch s[1] {
+	case '[':
+		params, final, n := parseCSI(s)
+		if final != 0 {
+			t.csi(params, final, changed)
+		}
+		return n
+	case ']', 'P', '_', '^':
+		// OSC and other strings run to BEL or ST.
+		for end := 2; end < len(s); end++ {
+			if s[end] == ansi.BEL {
+				return end + 1
+			}
+			if s[end] == ansi.ESC && end+1 < len(s) && s[end+1] == '\\' {
+				return end + 2
+			}
+		}
+		return len(s)
+	}
+	return 2
+}
+
+func (t *terminal) csi(params string, final byte, changed map[int]*termRow) {
+	if final == 'm' {
+		t.pen = t.pen.apply(params)
+		return
+	}
+	if params != "" && (params[0] < '0' || params[0] > '9') && params[0] != ';' {
+		// Private sequences like ?25l (hide the cursor).
+		return
+	}
+	n, _ := strconv.Atoi(strings.SplitN(params, ";", 2)[0])
+	count := max(n, 1)
+	last := t.first + len(t.rows) - 1
+	switch final {
+	case 'A':
+		t.row = max(t.row-count, t.first)
+	case 'B':
+		t.row = min(t.row+count, max(last, t.row))
+	case 'E':
+		t.row = min(t.row+count, max(last, t.row))
+		t.col = 0
+	case 'F':
+		t.row = max(t.row-count, t.first)
+		t.col = 0
+	case 'C':
+		t.moveCol(t.col + count)
+	case 'D':
+		t.col = max(t.col-count, 0)
+	case 'G':
+		t.moveCol(count - 1)
+	case 'K':
+		t.eraseLine(n, changed)
+	case 'J':
+		if n == 0 {
+			t.eraseLine(0, changed)
+			for line := t.row + 1; line <= last; line++ {
+				row := t.touch(line)
+				row.cells, row.text = nil, ""
+				changed[line] = row
+			}
+		}
+	}
+}
+
+// moveCol moves the cursor to col, or as far as termCols allows. A cursor
+// already past termCols through text doesn't move back.
+func (t *terminal) moveCol(col int) {
+	t.col = min(col, max(t.col, termCols-1))
+}
+
+func (t *terminal) eraseLine(mode int, changed map[int]*termRow) {
+	if t.row >= t.first+len(t.rows) {
+		return
+	}
+	row := t.touch(t.row)
+	cells := row.edit()
+	switch mode {
+	case 0:
+		row.cells = cells[:min(t.col, len(cells))]
+	case 1:
+		for i := 0; i <= t.col && i < len(cells); i++ {
+			cells[i] = termCell{text: " "}
+		}
+	case 2:
+		row.cells = nil
+	}
+	changed[t.row] = row
+}
+
+// put writes one grapheme at the cursor.
+func (t *terminal) put(c rune, changed map[int]*termRow) {
+	text := string(c)
+	width := runeWidth(c)
+	row := t.touch(t.row)
+	cells := row.edit()
+	if width == 0 {
+		// A combining mark joins the grapheme before it.
+		if t.col > 0 && t.col <= len(cells) {
+			cells[t.col-1].text += text
+			changed[t.row] = row
+		}
+		return
+	}
+	for len(cells) < t.col+width {
+		cells = append(cells, termCell{text: " "})
+	}
+	cells[t.col] = termCell{text: text, style: t.pen}
+	if width == 2 {
+		cells[t.col+1] = termCell{style: t.pen}
+	}
+	row.cells = cells
+	t.col += width
+	changed[t.row] = row
+}
+
+// touch returns the row at line, adding rows up to it and dropping the
+// oldest ones past termRows.
+func (t *terminal) touch(line int) *termRow {
+	for line >= t.first+len(t.rows) {
+		t.rows = append(t.rows, &termRow{})
+	}
+	if drop := len(t.rows) - termRows; drop > 0 {
+		t.rows = append(t.rows[:0], t.rows[drop:]...)
+		t.first += drop
+	}
+	return t.rows[max(line-t.first, 0)]
+}
+
+// edit turns the row's text into cells.
+func (r *termRow) edit() []termCell {
+	if r.text == "" {
+		return r.cells
+	}
+	pen := r.textStyle
+	for i := 0; i < len(r.text); {
+		if r.text[i] == ansi.ESC {
+			params, _, n := parseCSI(r.text[i:])
+			pen = pen.apply(params)
+			i += n
+			continue
+		}
+		c, size := utf8.DecodeRuneInString(r.text[i:])
+		i += size
+		text := string(c)
+		width := runeWidth(c)
+		if width == 0 && len(r.cells) > 0 {
+			r.cells[len(r.cells)-1].text += text
+			continue
+		}
+		r.cells = append(r.cells, termCell{text: text, style: pen})
+		if width == 2 {
+			r.cells = append(r.cells, termCell{style: pen})
+		}
+	}
+	r.text = ""
+	return r.cells
+}
+
+func (t *terminal) render(row *termRow, strip bool) string {
+	strip = strip || t.strip
+	if row.text != "" {
+		if strip {
+			if strings.IndexByte(row.text, ansi.ESC) < 0 {
+				return row.text
+			}
+			return ansi.Strip(row.text)
+		}
+		line := row.text
+		if !row.textStyle.zero() {
+			line = row.textStyle.sequence() + line
+		}
+		if !row.endStyle.zero() {
+			line += ansi.ResetStyle
+		}
+		return line
+	}
+	var b strings.Builder
+	var style sgrState
+	for _, cell := range row.cells {
+		if !strip && cell.style != style {
+			if cell.style.zero() {
+				b.WriteString(ansi.ResetStyle)
+			} else {
+				if !style.zero() {
+					b.WriteString(ansi.ResetStyle)
+				}
+				b.WriteString(cell.style.sequence())
+			}
+			style = cell.style
+		}
+		b.WriteString(cell.text)
+	}
+	if !style.zero() {
+		b.WriteString(ansi.ResetStyle)
+	}
+	return b.String()
+}
+
+// show writes row to dests the first time and over its earlier copies after
+// that, as long as they are still in memory.
+func (t *terminal) show(row *termRow, dests []outputDest) {
+	if !row.shown {
+		row.shown = true
+		for _, dest := range dests {
+			dest.buf.Append(dest.prefix + t.render(row, dest.strip))
+			row.placed = append(row.placed, placedRow{dest: dest, index: dest.buf.Len() - 1, resets: dest.buf.resets})
+		}
+		return
+	}
+	for _, placed := range row.placed {
+		if placed.dest.buf.resets == placed.resets {
+			placed.dest.buf.Set(placed.index, placed.dest.prefix+t.render(row, placed.dest.strip))
+		}
+	}
+}
+
+func sortedRows(changed map[int]*termRow) []*termRow {
+	lines := make([]int, 0, len(changed))
+	for line := range changed {
+		lines = append(lines, line)
+	}
+	sort.Ints(lines)
+	rows := make([]*termRow, len(lines))
+	for i, line := range lines {
+		rows[i] = changed[line]
+	}
+	return rows
+}
+
+// styledText reports whether text holds nothing but printable characters
+// and SGR sequences, and returns the style in effect at its end when s is in
+// effect at its start.
+func (s sgrState) styledText(text string) (sgrState, bool) {
+	for i := 0; i < len(text); i++ {
+		switch c := text[i]; {
+		case c == ansi.ESC:
+			params, final, n := parseCSI(text[i:])
+			if final != 'm' {
+				return s, false
+			}
+			s = s.apply(params)
+			i += n - 1
+		case c < ' ' || c == ansi.DEL:
+			return s, false
+		}
+	}
+	return s, true
+}
+
+// parseCSI reads the control sequence at the start of s, which begins with
+// ESC [. final is 0 when s ends before the sequence does.
+func parseCSI(s string) (params string, final byte, n int) {
+	if len(s) < 2 || s[0] != ansi.ESC || s[1] != '[' {
+		return "", 0, min(len(s), 1)
+	}
+	end := 2
+	for end < len(s) && (s[end] < 0x40 || s[end] > 0x7e) {
+		end++
+	}
+	if end == len(s) {
+		return "", 0, len(s)
+	}
+	return s[2:end], s[end], end + 1
+}
+
+// runeWidth is how many columns c takes.
+func runeWidth(c rune) int {
+	if c < utf8.RuneSelf {
+		return 1
+	}
+	return ansi.StringWidth(string(c))
+}
+
+// sgrState is the graphic rendition in effect: attributes and colors. It is
+// comparable, so cells can be grouped into runs of the same style.
+type sgrState struct {
+	attrs uint16 // bit n set for SGR n, 1 through 9
+	fg    string
+	bg    string
+	ul    string // underline color
+}
+
+func (s sgrState) zero() bool {
+	return s == sgrState{}
+}
+
+// apply updates s with the parameters of an SGR sequence.
+func (s sgrState) apply(params string) sgrState {
+	tokens := strings.Split(params, ";")
+	for i := 0; i < len(tokens); i++ {
+		token := tokens[i]
+		if strings.HasPrefix(token, "4:") {
+			// Underline styles: 4:0 is off, anything else on.
+			if token == "4:0" {
+				s.attrs &^= 1 << 4
+			} else {
+				s.attrs |= 1 << 4
+			}
+			continue
+		}
+		code, err := strconv.Atoi(token)
+		if token != "" && err != nil && !strings.Contains(token, ":") {
+			continue
+		}
+		if strings.Contains(token, ":") {
+			// Colors in colon form, like 38:2::255:0:0.
+			code, _ = strconv.Atoi(token[:strings.Index(token, ":")])
+			switch code {
+			case 38:
+				s.fg = token
+			case 48:
+				s.bg = token
+			case 58:
+				s.ul = token
+			}
+			continue
+		}
+		switch {
+		case token == "" || code == 0:
+			s = sgrState{}
+		case code >= 1 && code <= 9:
+			s.attrs |= 1 << code
+		case code == 22:
+			s.attrs &^= 1<<1 | 1<<2
+		case code >= 23 && code <= 29:
+			s.attrs &^= 1 << (code - 20)
+		case code >= 30 && code <= 37, code >= 90 && code <= 97:
+			s.fg = token
+		case code == 39:
+			s.fg = ""
+		case code >= 40 && code <= 47, code >= 100 && code <= 107:
+			s.bg = token
+		case code == 49:
+			s.bg = ""
+		case code == 59:
+			s.ul = ""
+		case code == 38 || code == 48 || code == 58:
+			// 38;5;n or 38;2;r;g;b.
+			n := 0
+			if i+1 < len(tokens) {
+				switch tokens[i+1] {
+				case "5":
+					n = 2
+				case "2":
+					n = 4
+				}
+			}
+			if n == 0 || i+n >= len(tokens) {
+				i = len(tokens)
+				continue
+			}
+			color := strings.Join(tokens[i:i+n+1], ";")
+			i += n
+			switch code {
+			case 38:
+				s.fg = color
+			case 48:
+				s.bg = color
+			default:
+				s.ul = color
+			}
+		}
+	}
+	return s
+}
+
+// sequence is the SGR sequence that sets s from a reset state.
+func (s sgrState) sequence() string {
+	var params []string
+	for code := 1; code <= 9; code++ {
+		if s.attrs&(1<<code) != 0 {
+			params = append(params, strconv.Itoa(code))
+		}
+	}
+	for _, color := range []string{s.fg, s.bg, s.ul} {
+		if color != "" {
+			params = append(params, color)
+		}
+	}
+	return "\x1b[" + strings.Join(params, ";") + "m"
+}
+
+// scanOutput splits command output like bufio.ScanLines, and also after a
+// carriage return that isn't part of a CRLF, keeping it, so a progress bar
+// that redraws its line arrives as it draws.
+func scanOutput(data []byte, atEOF bool) (int, []byte, error) {
+	if i := bytes.IndexAny(data, "\r\n"); i >= 0 {
+		if data[i] == '\n' {
+			return i + 1, data[:i], nil
+		}
+		if i+1 < len(data) {
+			if data[i+1] == '\n' {
+				return i + 2, data[:i], nil
+			}
+			return i + 1, data[:i+1], nil
+		}
+		if atEOF {
+			return len(data), data, nil
+		}
+		// Wait to see whether a newline follows.
+		return 0, nil, nil
+	}
+	if atEOF && len(data) > 0 {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
+
+// rawLine is line as the command wrote it: with its newline, unless it was
+// cut at a carriage return.
+func rawLine(line string) string {
+	if strings.HasSuffix(line, "\r") {
+		return line
+	}
+	return line + "\n"
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bufio"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// feed writes lines through a new terminal into a buffer and returns what
+// the buffer holds.
+func feed(strip bool, lines ...string) []string {
+	term := &terminal{strip: strip}
+	var buf outputBuffer
+	dests := []outputDest{{buf: &buf}}
+	for _, line := range lines {
+		for _, row := range term.write(line) {
+			term.show(row, dests)
+		}
+	}
+	return buf.All()
+}
+
+func TestTerminalCarriageReturnRewritesLine(t *testing.T) {
+	got := feed(false, "building", "  10% [=>    ]\r", "  55% [===>  ]\r", " 100% [======]\r", "\x1b[Kdone")
+	if want := []string{"building", "done"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected the progress bar to collapse, got %q", got)
+	}
+	// Without an erase, a shorter line leaves the end of the longer one.
+	if got := feed(false, "abcdef\r", "xy"); !reflect.DeepEqual(got, []string{"xycdef"}) {
+		t.Fatalf("unexpected overwrite %q", got)
+	}
+}
+
+func TestTerminalColorsSpanLines(t *testing.T) {
+	got := feed(false, "\x1b[1;31merror: boom", "  at main.go:3\x1b[0m", "plain \x1b[32mok")
+	want := []string{
+		"\x1b[1;31merror: boom\x1b[m",
+		"\x1b[1;31m  at main.go:3\x1b[0m",
+		"plain \x1b[32mok\x1b[m",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected every line to carry its own colors, got %q", got)
+	}
+	if got := feed(true, "\x1b[1;31merror: boom", "\x1b]8;;https://example.com\x07link\x1b]8;;\x07\x1b[0m"); !reflect.DeepEqual(got, []string{"error: boom", "link"}) {
+		t.Fatalf("expected plain text with strip_ansi, got %q", got)
+	}
+}
+
+func TestTerminalCursorUpRedrawsLines(t *testing.T) {
+	got := feed(false,
+		"layer a: waiting",
+		"layer b: waiting",
+		"\x1b[2A\x1b[2Klayer a: done",
+		"\x1b[2Klayer b: 40%",
+		"\x1b[1F\x1b[2Klayer b: done",
+		"finished",
+	)
+	want := []string{"layer a: done", "layer b: done", "finished"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected lines %q", got)
+	}
+}
+
+func TestTerminalRewritesEveryCopy(t *testing.T) {
+	term := &terminal{}
+	var own, parent outputBuffer
+	dests := []outputDest{{buf: &own}, {buf: &parent, prefix: "[1] "}}
+	for _, line := range []string{"compiling 1/3\r", "compiling 3/3\r", "\x1b[2Kcompiled"} {
+		for _, row := range term.write(line) {
+			term.show(row, dests)
+		}
+	}
+	if own.All()[0] != "compiled" || parent.All()[0] != "[1] compiled" || parent.Len() != 1 {
+		t.Fatalf("expected both copies rewritten, got %q and %q", own.All(), parent.All())
+	}
+}
+
+func TestTerminalClampsColumnMoves(t *testing.T) {
+	got := feed(true, "\x1b[99999Cx", "ab\x1b[99999Gy")
+	if len(got) != 2 || len(got[0]) != termCols || len(got[1]) != termCols {
+		t.Fatalf("expected two lines of %d columns, got %d lines", termCols, len(got))
+	}
+	if !strings.HasSuffix(got[0], " x") || !strings.HasPrefix(got[1], "ab ") || !strings.HasSuffix(got[1], " y") {
+		t.Fatalf("unexpected lines %q", got)
+	}
+}
+
+func TestSGRState(t *testing.T) {
+	s := sgrState{}.apply("1;38;5;208;48;2;1;2;3")
+	if got := s.sequence(); got != "\x1b[1;38;5;208;48;2;1;2;3m" {
+		t.Fatalf("unexpected sequence %q", got)
+	}
+	s = s.apply("22;49")
+	if got := s.sequence(); got != "\x1b[38;5;208m" {
+		t.Fatalf("unexpected sequence after 22;49 %q", got)
+	}
+	if s = s.apply(""); !s.zero() {
+		t.Fatalf("expected an empty SGR to reset, got %#v", s)
+	}
+}
+
+func TestScanOutputSplitsAtCarriageReturns(t *testing.T) {
+	scanner := bufio.NewScanner(strings.NewReader("a\r\nb\r\n 10%\r 20%\rdone\nlast\r"))
+	scanner.Split(scanOutput)
+	var got []string
+	for scanner.Scan() {
+		got = append(got, scanner.Text())
+	}
+	want := []string{"a", "b", " 10%\r", " 20%\r", "done", "last\r"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected tokens %q", got)
+	}
+}
+
+func TestStripANSITaskOutput(t *testing.T) {
+	cfg := Config{
+		Tasks: []TaskDef{
+			{Name: "build", Key: "b", StripANSI: true, Cmd: StepList{{Value: "cargo build", Kind: StepCommand}}},
+			{Name: "web", Key: "w", Cmd: StepList{{Value: "bin/server", Kind: StepCommand}}},
+		},
+		SidebarWidth: 32,
+	}
+	m := newModel(cfg)
+	for _, name := range []string{"build", "web"} {
+		m.handleTaskStarted(name)
+		m.handleOutputLines(name, []string{"\x1b[32m   Compiling\x1b[0m suite", "  Building [=>  ] 1/3\r", "\x1b[2K\x1b[32m    Finished\x1b[0m dev"})
+	}
+	if got := m.taskByName["build"].Output.All(); !reflect.DeepEqual(got, []string{"   Compiling suite", "    Finished dev"}) {
+		t.Fatalf("expected plain text, got %q", got)
+	}
+	if got := m.taskByName["web"].Output.All(); len(got) != 2 || got[1] != "\x1b[32m    Finished\x1b[m dev" {
+		t.Fatalf("expected colors kept, got %q", got)
+	}
+	if got := m.taskByName["web"].Output.Last(); got != "Finished dev" {
+		t.Fatalf("expected the last line as plain text, got %q", got)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"os/exec"
 	"runtime"
 	"strings"
+	"time"
 
 	"github.com/aymanbagabas/go-osc52/v2"
 	"github.com/charmbracelet/bubbles/viewport"
@@ -23,20 +24,29 @@ const (
 	StatusSuccess
 	StatusFailed
 	StatusCanceled
+	StatusSkipped
+	StatusWarning
+	StatusQueued
 )
 
 type Task struct {
 	Def         TaskDef
 	Status      TaskStatus
-	Output      []string
+	Output      outputBuffer
 	ExitCode    int
 	Running     bool
 	RunSeq      int
 	Steps       []TaskStep
 	StepRuns    map[string]*StepRun
 	StepTargets map[string]stepTargetInfo
-	cancel      context.CancelFunc
-	msgCh       chan tea.Msg
+	StartedAt   time.Time
+	FinishedAt  time.Time
+	// Usage is the latest sample of the running task's processes.
+	Usage      TaskUsageMsg
+	lastResult TaskStatus
+	cancel     context.CancelFunc
+	killHooks  context.CancelFunc
+	msgCh      chan tea.Msg
 }
 
 type TaskStep struct {
@@ -52,10 +62,13 @@ type StepRun struct {
 	ID       string
 	Label    string
 	Status   TaskStatus
-	Output   []string
+	Output   outputBuffer
 	ExitCode int
 	Running  bool
 	RunSeq   int
+
+	StartedAt  time.Time
+	FinishedAt time.Time
 }
 
 type stepTargetInfo struct {
@@ -73,13 +86,6 @@ const (
 	focusOutput
 )
 
-type comboRun struct {
-	Def       ComboDef
-	Index     int
-	WaitingOn string
-	Pending   int
-}
-
 type entryKind int
 
 const (
@@ -100,6 +106,7 @@ type entry struct {
 	IsChild    bool
 	Depth      int
 	Command    string
+	Env        map[string]string
 }
 
 type taskStreamMsg struct {
@@ -107,10 +114,32 @@ type taskStreamMsg struct {
 	Msg    tea.Msg
 }
 
+// taskStreamBatchMsg is a frame's worth of messages from one stream.
+type taskStreamBatchMsg struct {
+	Source string
+	Msgs   []tea.Msg
+}
+
+// outputView is the content last given to the viewport: lines [start, end)
+// of buf. Lines added to buf since are appended to it rather than joining
+// the whole window again.
+type outputView struct {
+	buf     *outputBuffer
+	resets  int
+	edits   int
+	start   int
+	end     int
+	content strings.Builder
+}
+
 type autostartMsg struct {
 	TaskName string
 }
 
+type scheduleMsg struct {
+	TaskName string
+}
+
 type selectionPos struct {
 	Line int
 	Col  int
@@ -134,12 +163,7 @@ type model struct {
 	taskByName     map[string]*Task
 	stepByID       map[string]*StepRun
 	stepCancel     map[string]context.CancelFunc
-	combos         []ComboDef
-	comboByName    map[string]ComboDef
 	taskKeys       map[string]string
-	comboKeys      map[string]string
-	combosByTask   map[string][]string
-	comboActive    map[string]*comboRun
 	selected       int
 	focus          focusArea
 	viewport       viewport.Model
@@ -152,16 +176,52 @@ type model struct {
 	selectedID     string
 	expanded       map[string]bool
 	streamBySource map[string]chan tea.Msg
+	runIDs         map[string]int
+	events         *eventLog
+	daemon         *daemonServer
+	remote         *daemonClient
+	headless       *headlessRun
+	remoteClosed   *remoteClosedMsg
 	showCheats     bool
+	explainID      string
+	explainLines   []string
 	restartPending map[string]bool
+	queuePending   map[string]bool
+	schedules      map[string]Schedule
+	nextRun        map[string]time.Time
+	schedulePaused bool
 	mouseSelecting bool
 	selection      outputSelection
+	input          *inputLine
+	signals        *signalMenu
+	runProcs       map[string]*runProcesses
+	outputStart    int
+	outputDirty    bool
+	terms          map[string]*terminal
+	view           *outputView
+	search         *outputSearch
+	notice         string
+}
+
+// builtinKeys are handled before task keys, so a task bound to one of them
+// can't be started with it where the built-in applies. Imports avoid them
+// and validate warns about them.
+var builtinKeys = map[string]string{
+	"q": "everywhere",
+	"?": "everywhere",
+	"i": "in the output pane",
+	"g": "in the output pane",
+	"G": "in the output pane",
+	"/": "in the output pane",
+	"n": "in the output pane while searching",
+	"N": "in the output pane while searching",
 }
 
 func newModel(cfg Config) model {
 	tasks := make([]*Task, 0, len(cfg.Tasks))
 	taskByName := make(map[string]*Task, len(cfg.Tasks))
 	taskKeys := make(map[string]string, len(cfg.Tasks))
+	schedules := make(map[string]Schedule)
 
 	for _, def := range cfg.Tasks {
 		t := &Task{Def: def, Status: StatusIdle}
@@ -170,46 +230,54 @@ func newModel(cfg Config) model {
 		if def.Key != "" {
 			taskKeys[def.Key] = def.Name
 		}
-	}
-
-	comboByName := make(map[string]ComboDef, len(cfg.Combos))
-	comboKeys := make(map[string]string, len(cfg.Combos))
-	combosByTask := make(map[string][]string)
-	for _, cb := range cfg.Combos {
-		comboByName[cb.Name] = cb
-		comboKeys[cb.Key] = cb.Name
-		for _, taskID := range cb.Run {
-			combosByTask[taskID] = append(combosByTask[taskID], cb.Name)
+		if def.Schedule != "" {
+			if sched, err := parseSchedule(def.Schedule); err == nil {
+				schedules[def.Name] = sched
+			}
 		}
 	}
 
 	vp := viewport.New(0, 0)
 
+	setJobLimit(cfg.Jobs)
+	setProcessTracking(cfg.Tracking)
+	setOutputLimit(cfg.MaxLines)
+
 	m := model{
 		cfg:            cfg,
 		tasks:          tasks,
 		taskByName:     taskByName,
 		stepByID:       make(map[string]*StepRun),
 		stepCancel:     make(map[string]context.CancelFunc),
-		combos:         cfg.Combos,
-		comboByName:    comboByName,
 		taskKeys:       taskKeys,
-		comboKeys:      comboKeys,
-		combosByTask:   combosByTask,
-		comboActive:    make(map[string]*comboRun),
 		selected:       0,
 		focus:          focusList,
 		viewport:       vp,
+		view:           &outputView{},
+		terms:          make(map[string]*terminal),
 		autoScroll:     true,
 		expanded:       make(map[string]bool),
 		streamBySource: make(map[string]chan tea.Msg),
+		runIDs:         make(map[string]int),
+		runProcs:       make(map[string]*runProcesses),
 		restartPending: make(map[string]bool),
+		queuePending:   make(map[string]bool),
+		schedules:      schedules,
+		nextRun:        make(map[string]time.Time),
 	}
 	m.rebuildEntries()
 	return m
 }
 
 func (m model) Init() tea.Cmd {
+	if m.remote != nil {
+		// The daemon autostarts and schedules; an attached UI only listens.
+		return listenRemote(m.remote.msgs)
+	}
+	if m.headless != nil {
+		// Headless runs only the tasks it was given.
+		return func() tea.Msg { return headlessNextMsg{} }
+	}
 	var cmds []tea.Cmd
 	for _, task := range m.tasks {
 		if !task.Def.Autostart {
@@ -220,10 +288,23 @@ func (m model) Init() tea.Cmd {
 			return autostartMsg{TaskName: taskName}
 		})
 	}
+	now := time.Now()
+	for _, task := range m.tasks {
+		cmds = append(cmds, m.scheduleNext(task.Def.Name, now))
+	}
 	return tea.Batch(cmds...)
 }
 
 func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	next, cmd := m.update(msg)
+	if updated, ok := next.(model); ok {
+		updated.flushOutput()
+		next = updated
+	}
+	return next, cmd
+}
+
+func (m model) update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.WindowSizeMsg:
 		m.setSize(msg.Width, msg.Height)
@@ -231,12 +312,52 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, nil
 	case autostartMsg:
 		return m, m.startTask(msg.TaskName, true)
+	case headlessNextMsg:
+		return m, m.startHeadlessTask()
+	case headlessStopMsg:
+		m.headless.next = len(m.headless.names)
+		m.killAllTasks()
+		return m, nil
+	case daemonRequestMsg:
+		return m, m.handleDaemonRequest(msg)
+	case remoteBatchMsg:
+		var cmds []tea.Cmd
+		for _, inner := range msg {
+			switch inner := inner.(type) {
+			case remoteStreamMsg:
+				cmds = append(cmds, m.handleRemoteStream(inner)...)
+			case remoteClosedMsg:
+				m.remoteClosed = &inner
+				return m, tea.Quit
+			}
+		}
+		cmds = append(cmds, listenRemote(m.remote.msgs))
+		return m, tea.Batch(cmds...)
+	case scheduleMsg:
+		cmds := []tea.Cmd{m.scheduleNext(msg.TaskName, time.Now())}
+		if !m.schedulePaused {
+			cmds = append(cmds, m.startTask(msg.TaskName, true))
+		}
+		return m, tea.Batch(cmds...)
 
 	case tea.KeyMsg:
 		key := msg.String()
+		m.notice = ""
 		if key == "ctrl+z" {
 			return m, tea.Suspend
 		}
+		if m.input != nil {
+			m.handleInputKey(msg)
+			return m, nil
+		}
+		if m.signals != nil {
+			m.handleSignalMenuKey(key)
+			return m, nil
+		}
+		if m.search != nil && m.search.editing {
+			m.handleSearchKey(msg)
+			return m, nil
+		}
 		if m.showCheats {
 			switch key {
 			case "esc", "q", "?":
@@ -259,6 +380,18 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, m.killSelectedTask()
 		case "ctrl+r":
 			return m, m.restartSelectedTask()
+		case "ctrl+p":
+			m.schedulePaused = !m.schedulePaused
+			if m.remote != nil {
+				m.remote.send(daemonRequest{Op: "pause"})
+			}
+			return m, nil
+		case "ctrl+e":
+			m.toggleExplain()
+			return m, nil
+		case "ctrl+s":
+			m.openSignalMenu()
+			return m, nil
 		case "ctrl+h":
 			m.focus = focusList
 			return m, nil
@@ -276,6 +409,10 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.showCheats = true
 			return m, nil
 		case "esc", "q":
+			if m.search != nil {
+				m.search = nil
+				return m, nil
+			}
 			m.focus = focusList
 			m.autoScroll = true
 			m.viewport.GotoBottom()
@@ -285,14 +422,31 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 		if m.focus == focusOutput {
 			switch key {
+			case "i":
+				m.attachInput()
+				return m, nil
 			case "g", "home":
-				m.viewport.GotoTop()
-				m.autoScroll = m.viewport.AtBottom()
+				m.showOutputLine(0)
+				m.scrollOutputWindow()
 				return m, nil
 			case "G", "end":
-				m.viewport.GotoBottom()
 				m.autoScroll = true
+				m.refreshViewport()
+				m.viewport.GotoBottom()
+				return m, nil
+			case "/":
+				m.openSearch()
 				return m, nil
+			case "n", "N":
+				if m.search != nil {
+					top := m.outputStart + m.viewport.YOffset
+					if key == "n" {
+						m.findNext(false, top+1)
+					} else {
+						m.findNext(true, top)
+					}
+					return m, nil
+				}
 			}
 		}
 
@@ -300,12 +454,8 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			if taskID, ok := m.taskKeys[key]; ok {
 				if task := m.taskByName[taskID]; task != nil && task.Running {
 					m.selectTaskEntry(taskID)
-					return m, nil
 				}
-				return m, m.startTask(taskID, false)
-			}
-			if comboID, ok := m.comboKeys[key]; ok {
-				return m, m.triggerCombo(comboID)
+				return m, m.triggerTask(taskID)
 			}
 		}
 
@@ -329,7 +479,7 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					return m, nil
 				}
 				if entry.Kind == entryTask {
-					return m, m.startTask(entry.Target, false)
+					return m, m.triggerTask(entry.Target)
 				}
 				if entry.Kind == entryStep {
 					return m, m.startStepEntry(*entry)
@@ -341,7 +491,7 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if m.focus == focusOutput {
 			var cmd tea.Cmd
 			m.viewport, cmd = m.viewport.Update(msg)
-			m.autoScroll = m.viewport.AtBottom()
+			m.scrollOutputWindow()
 			return m, cmd
 		}
 
@@ -353,38 +503,15 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 			var cmd tea.Cmd
 			m.viewport, cmd = m.viewport.Update(msg)
-			m.autoScroll = m.viewport.AtBottom()
+			m.scrollOutputWindow()
 			return m, cmd
 		}
 		m.focus = focusList
 		m.mouseSelecting = false
 		return m, nil
 
-	case taskStreamMsg:
-		cmds := []tea.Cmd{}
-		switch inner := msg.Msg.(type) {
-		case TaskStartedMsg:
-			m.handleTaskStarted(inner.TaskName)
-		case TaskOutputMsg:
-			m.handleOutput(inner)
-		case TaskFinishedMsg:
-			if inner.TaskID == msg.Source {
-				if task := m.taskByName[msg.Source]; task != nil {
-					task.msgCh = nil
-				}
-				delete(m.streamBySource, msg.Source)
-			}
-			m.handleTaskFinished(inner)
-			cmds = append(cmds, m.maybeRestartTask(inner.TaskID))
-			m.advanceCombos(inner, &cmds)
-			m.rebuildEntries()
-		case StepStartedMsg:
-			m.handleStepStarted(inner)
-		case StepFinishedMsg:
-			m.handleStepFinished(inner)
-			delete(m.streamBySource, inner.StepID)
-		}
-
+	case taskStreamBatchMsg:
+		cmds := m.handleStreamBatch(msg)
 		if ch, ok := m.streamBySource[msg.Source]; ok && ch != nil {
 			cmds = append(cmds, listenTaskMsgs(msg.Source, ch))
 		}
@@ -397,6 +524,78 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
+// handleStreamBatch applies a frame of messages from one stream. Runs of
+// output lines for the same target are appended together.
+func (m *model) handleStreamBatch(msg taskStreamBatchMsg) []tea.Cmd {
+	cmds := []tea.Cmd{}
+	run := m.runIDs[msg.Source]
+	for i := 0; i < len(msg.Msgs); {
+		out, ok := msg.Msgs[i].(TaskOutputMsg)
+		if !ok {
+			cmds = append(cmds, m.handleStreamMsg(taskStreamMsg{Source: msg.Source, Msg: msg.Msgs[i]})...)
+			i++
+			continue
+		}
+		var lines []string
+		for ; i < len(msg.Msgs); i++ {
+			next, ok := msg.Msgs[i].(TaskOutputMsg)
+			if !ok || next.Target != out.Target {
+				break
+			}
+			m.events.write(run, next)
+			m.daemon.publish(msg.Source, run, next)
+			m.printHeadless(msg.Source, next)
+			lines = append(lines, next.Line)
+		}
+		m.handleOutputLines(out.Target, lines)
+	}
+	return cmds
+}
+
+// handleStreamMsg applies one runner message from the stream of source.
+func (m *model) handleStreamMsg(msg taskStreamMsg) []tea.Cmd {
+	cmds := []tea.Cmd{}
+	m.events.write(m.runIDs[msg.Source], msg.Msg)
+	m.daemon.publish(msg.Source, m.runIDs[msg.Source], msg.Msg)
+	switch inner := msg.Msg.(type) {
+	case TaskStartedMsg:
+		m.handleTaskStarted(inner.TaskName)
+	case TaskOutputMsg:
+		m.printHeadless(msg.Source, inner)
+		m.handleOutput(inner)
+	case TaskFinishedMsg:
+		if inner.TaskID == msg.Source {
+			if task := m.taskByName[msg.Source]; task != nil {
+				task.msgCh = nil
+			}
+			delete(m.streamBySource, msg.Source)
+			delete(m.runProcs, msg.Source)
+		}
+		cmds = append(cmds, m.handleTaskFinished(inner))
+		cmds = append(cmds, m.startPendingRuns())
+		if inner.TaskID == msg.Source {
+			cmds = append(cmds, m.headlessTaskFinished(inner.TaskID))
+		}
+		m.rebuildEntries()
+	case JobSlotMsg:
+		m.handleJobSlot(inner)
+	case TaskUsageMsg:
+		if task := m.taskByName[inner.TaskID]; task != nil && task.Running {
+			task.Usage = inner
+		}
+	case StepStartedMsg:
+		m.handleStepStarted(inner)
+	case StepFinishedMsg:
+		m.handleStepFinished(inner)
+		delete(m.streamBySource, inner.StepID)
+		delete(m.runProcs, inner.StepID)
+	}
+	if m.input != nil && !m.isRunningTarget(m.input.target) {
+		m.input = nil
+	}
+	return cmds
+}
+
 func (m *model) handleOutputMouseSelection(msg tea.MouseMsg) (bool, tea.Cmd) {
 	if msg.Button == tea.MouseButtonWheelUp || msg.Button == tea.MouseButtonWheelDown ||
 		msg.Button == tea.MouseButtonWheelLeft || msg.Button == tea.MouseButtonWheelRight {
@@ -469,6 +668,9 @@ func (m model) View() string {
 	if m.showCheats {
 		return overlayView(base, m.renderCheatsheet())
 	}
+	if m.signals != nil {
+		return overlayView(base, m.renderSignalMenu())
+	}
 	return base
 }
 
@@ -528,16 +730,172 @@ func (m *model) setSize(width, height int) {
 func (m *model) refreshViewport() {
 	entry := m.selectedEntry()
 	if entry == nil {
+		m.view.buf = nil
 		m.viewport.SetContent("No output yet.")
 		return
 	}
+	if m.explainID != "" && entry.ID == m.explainID {
+		m.view.buf = nil
+		m.viewport.SetContent(strings.Join(m.explainLines, "\n"))
+		return
+	}
 
-	lines := m.outputForEntry(*entry)
-	if len(lines) == 0 {
+	buf := m.outputForEntry(*entry)
+	if buf == nil || buf.Len() == 0 {
+		m.outputStart = 0
+		m.view.buf = nil
 		m.viewport.SetContent("No output yet.")
 		return
 	}
-	m.viewport.SetContent(strings.Join(lines, "\n"))
+	window := m.outputWindow()
+	start, end := m.outputStart, buf.Len()
+	switch {
+	case m.view.buf != buf || m.view.resets != buf.resets || start > buf.Len():
+		start = max(buf.Len()-window, 0)
+	case m.autoScroll:
+		// Following the end, the window grows to twice its size before it
+		// moves, so most frames only append.
+		if buf.Len()-start > 2*window {
+			start = buf.Len() - window
+		}
+	default:
+		end = min(max(m.view.end, start+window), buf.Len())
+	}
+	m.setOutputContent(buf, start, end)
+}
+
+// outputWindowLines is how many lines of output the viewport is given at
+// least. Only that window is rendered; scrolling past either end moves it.
+const outputWindowLines = 1000
+
+func (m *model) outputWindow() int {
+	return max(outputWindowLines, 4*m.viewport.Height)
+}
+
+// setOutputContent shows lines [start, end) of buf in the viewport. When
+// only lines at the end are new, they are appended to the previous content.
+func (m *model) setOutputContent(buf *outputBuffer, start, end int) {
+	view := m.view
+	m.outputStart = start
+	same := view.buf == buf && view.resets == buf.resets && view.edits == buf.edits && view.start == start
+	if same && view.end == end {
+		return
+	}
+	if same && view.end > start && view.end < end {
+		for _, line := range buf.Lines(view.end, end) {
+			view.content.WriteByte('\n')
+			view.content.WriteString(line)
+		}
+	} else {
+		view.content.Reset()
+		for i, line := range buf.Lines(start, end) {
+			if i > 0 {
+				view.content.WriteByte('\n')
+			}
+			view.content.WriteString(line)
+		}
+	}
+	view.buf, view.resets, view.edits, view.start, view.end = buf, buf.resets, buf.edits, start, end
+	m.viewport.SetContent(view.content.String())
+}
+
+// flushOutput brings the viewport up to date with output handled since the
+// last update.
+func (m *model) flushOutput() {
+	if !m.outputDirty {
+		return
+	}
+	m.outputDirty = false
+	m.refreshViewport()
+	if m.autoScroll {
+		m.viewport.GotoBottom()
+	}
+}
+
+// viewedBuffer is the output shown in the viewport, if any.
+func (m *model) viewedBuffer() *outputBuffer {
+	entry := m.selectedEntry()
+	if entry == nil || (m.explainID != "" && entry.ID == m.explainID) {
+		return nil
+	}
+	return m.outputForEntry(*entry)
+}
+
+// scrollOutputWindow moves the window when scrolling has reached its top or
+// bottom and the output goes on, keeping the same lines on screen.
+func (m *model) scrollOutputWindow() {
+	buf := m.viewedBuffer()
+	if buf == nil {
+		m.autoScroll = m.viewport.AtBottom()
+		return
+	}
+	window := m.outputWindow()
+	start, end := m.outputStart, m.view.end
+	switch {
+	case m.viewport.YOffset == 0 && start > 0:
+		start -= min(start, window/2)
+		end = min(start+window, buf.Len())
+	case m.viewport.AtBottom() && end < buf.Len():
+		end = min(end+window/2, buf.Len())
+		start = max(end-window, 0)
+	}
+	if start != m.outputStart || end != m.view.end {
+		offset := m.viewport.YOffset - (start - m.outputStart)
+		m.setOutputContent(buf, start, end)
+		m.viewport.SetYOffset(offset)
+	}
+	m.autoScroll = m.viewport.AtBottom() && m.view.end >= buf.Len()
+}
+
+// showOutputLine scrolls the viewport so line i of the output is at the top.
+func (m *model) showOutputLine(i int) {
+	buf := m.viewedBuffer()
+	if buf == nil {
+		return
+	}
+	window := m.outputWindow()
+	m.autoScroll = false
+	start := min(max(i-window/2, 0), max(buf.Len()-window, 0))
+	m.setOutputContent(buf, start, min(start+window, buf.Len()))
+	m.viewport.SetYOffset(i - start)
+}
+
+// toggleExplain swaps the selected entry's output for its dry-run plan.
+func (m *model) toggleExplain() {
+	entry := m.selectedEntry()
+	if entry == nil {
+		return
+	}
+	if m.explainID == entry.ID {
+		m.explainID = ""
+		m.explainLines = nil
+		m.autoScroll = true
+		m.refreshViewport()
+		m.viewport.GotoBottom()
+		return
+	}
+	switch entry.Kind {
+	case entryTask:
+		m.explainLines = explainTask(m.cfg, entry.Target, m.resolveTask)
+	case entryStep:
+		if strings.TrimSpace(entry.Command) == "" {
+			return
+		}
+		// Same path as startStepEntry, run dry.
+		ctx := withDryRun(withStepEnv(context.Background(), entry.Env))
+		msgCh := make(chan tea.Msg, 1)
+		_, _ = runSingle(ctx, entry.Command, m.cfg.Shell, m.cfg.Init, msgCh, entry.Target)
+		p := newPlanPrinter(m.cfg, entry.Label, m.resolveTask)
+		p.header(entry.Target, entry.Label)
+		p.handle(<-msgCh)
+		m.explainLines = p.lines
+	default:
+		return
+	}
+	m.explainID = entry.ID
+	m.autoScroll = false
+	m.refreshViewport()
+	m.viewport.GotoTop()
 }
 
 func (m *model) moveSelection(delta int) {
@@ -554,6 +912,7 @@ func (m *model) moveSelection(delta int) {
 	if m.selected >= 0 && m.selected < len(m.entries) {
 		m.selectedID = m.entries[m.selected].ID
 	}
+	m.search = nil
 	m.autoScroll = true
 	m.refreshViewport()
 	m.viewport.GotoBottom()
@@ -610,31 +969,6 @@ func (m *model) collapseSelected() {
 	}
 }
 
-func (m *model) isTaskDisabled(taskName string) bool {
-	for _, comboName := range m.combosByTask[taskName] {
-		if _, ok := m.comboActive[comboName]; ok {
-			return true
-		}
-	}
-	return false
-}
-
-func (m *model) isComboDisabled(comboName string) bool {
-	if _, ok := m.comboActive[comboName]; ok {
-		return true
-	}
-	cb, ok := m.comboByName[comboName]
-	if !ok {
-		return true
-	}
-	for _, taskName := range cb.Run {
-		if task, ok := m.taskByName[taskName]; ok && task.Running {
-			return true
-		}
-	}
-	return false
-}
-
 func (m *model) rebuildEntries() {
 	entries := make([]entry, 0, len(m.tasks))
 	for _, task := range m.tasks {
@@ -709,6 +1043,7 @@ func (m *model) appendTaskChildren(entries *[]entry, parent entry, stack map[str
 				Index:      idx,
 				Depth:      parent.Depth + 1,
 				Command:    value,
+				Env:        step.Env,
 			})
 			continue
 		}
@@ -768,6 +1103,8 @@ func (m *model) prepareTaskSteps(task *Task) {
 			run.ExitCode = 0
 			run.Running = false
 			run.RunSeq = task.RunSeq
+			run.StartedAt = time.Time{}
+			run.FinishedAt = time.Time{}
 			m.stepByID[id] = run
 			task.StepTargets[id] = stepTargetInfo{
 				Label:   label,
@@ -794,6 +1131,8 @@ func (m *model) prepareTaskSteps(task *Task) {
 			run.ExitCode = 0
 			run.Running = false
 			run.RunSeq = task.RunSeq
+			run.StartedAt = time.Time{}
+			run.FinishedAt = time.Time{}
 			m.stepByID[id] = run
 			task.StepTargets[value] = stepTargetInfo{
 				Label:   label,
@@ -819,10 +1158,13 @@ func (m *model) handleTaskStarted(taskName string) {
 	if task == nil || (task.Running && task.RunSeq != 0) {
 		return
 	}
-	task.Output = nil
+	task.Output.Reset()
+	delete(m.terms, task.Def.Name)
 	task.Status = StatusRunning
 	task.ExitCode = 0
 	task.Running = true
+	task.StartedAt = time.Now()
+	task.Usage = TaskUsageMsg{}
 	m.runSeq++
 	task.RunSeq = m.runSeq
 	m.prepareTaskSteps(task)
@@ -831,20 +1173,20 @@ func (m *model) handleTaskStarted(taskName string) {
 	m.rebuildEntries()
 }
 
-func (m *model) handleTaskFinished(msg TaskFinishedMsg) {
+func (m *model) handleTaskFinished(msg TaskFinishedMsg) tea.Cmd {
 	task := m.taskByName[msg.TaskID]
 	if task == nil {
-		return
+		return nil
 	}
+	// Only runs started from the UI notify; child tasks report through them.
+	// A daemon has no terminal to notify; attached UIs do it instead.
+	topLevel := task.cancel != nil && m.daemon == nil
 	task.Running = false
 	task.cancel = nil
-	if msg.Canceled {
-		task.Status = StatusCanceled
-	} else if msg.Err != nil {
-		task.Status = StatusFailed
-	} else {
-		task.Status = StatusSuccess
-	}
+	task.killHooks = nil
+	task.Usage = TaskUsageMsg{}
+	task.Status = finishedStatus(msg.Err, msg.Canceled, msg.Skipped, msg.Warned)
+	task.FinishedAt = time.Now()
 	task.ExitCode = msg.ExitCode
 	task.StepTargets = nil
 	m.updateParentStepRuns(msg.TaskID, task.Status, msg.ExitCode)
@@ -852,6 +1194,32 @@ func (m *model) handleTaskFinished(msg TaskFinishedMsg) {
 	if entry := m.selectedEntry(); entry != nil && entry.Kind == entryTask && entry.Target == task.Def.Name {
 		m.refreshViewport()
 	}
+	previous := task.lastResult
+	task.lastResult = task.Status
+	if !topLevel {
+		return nil
+	}
+	return m.notifyTaskFinished(task, previous)
+}
+
+// notifyTaskFinished emits the configured notification for a finished run.
+func (m *model) notifyTaskFinished(task *Task, previous TaskStatus) tea.Cmd {
+	cfg := mergeNotify(m.cfg.Notify, task.Def.Notify)
+	if !shouldNotify(cfg, task.Status, previous, time.Since(task.StartedAt)) {
+		return nil
+	}
+	title := m.cfg.Title
+	if title == "" {
+		title = "suite"
+	}
+	message := fmt.Sprintf("%s %s", task.Def.Name, statusName(task.Status))
+	if task.Status == StatusFailed {
+		if label, line := m.failedStepSummary(task); label != "" {
+			message = fmt.Sprintf("%s: %s failed: %s", message, label, line)
+		}
+	}
+	note := notification{Title: title, Message: message, Task: task.Def.Name, Status: statusName(task.Status)}
+	return notifyCmd(cfg, note, m.cfg.Shell)
 }
 
 func (m *model) handleStepStarted(msg StepStartedMsg) {
@@ -864,10 +1232,12 @@ func (m *model) handleStepStarted(msg StepStartedMsg) {
 			step.RunSeq = task.RunSeq
 		}
 	}
-	step.Output = nil
+	step.Output.Reset()
+	delete(m.terms, step.ID)
 	step.ExitCode = 0
 	step.Running = true
 	step.Status = StatusRunning
+	step.StartedAt = time.Now()
 	if entry := m.selectedEntry(); entry != nil && entry.Kind == entryStep && entry.Target == msg.StepID {
 		m.refreshViewport()
 	}
@@ -880,12 +1250,11 @@ func (m *model) handleStepFinished(msg StepFinishedMsg) {
 	}
 	step.Running = false
 	delete(m.stepCancel, msg.StepID)
-	if msg.Canceled {
-		step.Status = StatusCanceled
-	} else if msg.Err != nil {
-		step.Status = StatusFailed
-	} else {
-		step.Status = StatusSuccess
+	step.Status = finishedStatus(msg.Err, msg.Canceled, msg.Skipped, msg.Warned)
+	step.FinishedAt = time.Now()
+	if msg.Skipped {
+		step.Output.Reset()
+		delete(m.terms, step.ID)
 	}
 	step.ExitCode = msg.ExitCode
 	if entry := m.selectedEntry(); entry != nil && entry.Kind == entryStep && entry.Target == msg.StepID {
@@ -893,6 +1262,19 @@ func (m *model) handleStepFinished(msg StepFinishedMsg) {
 	}
 }
 
+func (m *model) handleJobSlot(msg JobSlotMsg) {
+	status := StatusRunning
+	if msg.Waiting {
+		status = StatusQueued
+	}
+	if step := m.stepByID[msg.Target]; step != nil {
+		step.Status = status
+	} else if task := m.taskByName[msg.Target]; task != nil && task.Running {
+		task.Status = status
+		m.updateParentStepRuns(msg.Target, status, 0)
+	}
+}
+
 func (m *model) resetChildTaskStatuses(task *Task) {
 	for _, step := range task.Steps {
 		if step.Kind != StepTask {
@@ -936,63 +1318,115 @@ func (m *model) updateParentStepRuns(childName string, status TaskStatus, exitCo
 			run.ExitCode = exitCode
 			run.Running = status == StatusRunning
 			run.Status = status
+			if run.Running {
+				run.StartedAt = time.Now()
+			} else {
+				run.FinishedAt = time.Now()
+			}
 		}
 	}
 }
 
 func (m *model) handleOutput(msg TaskOutputMsg) {
-	if task := m.taskByName[msg.Target]; task != nil {
-		task.Output = append(task.Output, msg.Line)
+	m.handleOutputLines(msg.Target, []string{msg.Line})
+}
+
+// handleOutputLines appends lines from target to its output and, prefixed,
+// to the output of every running task it is a step of. The viewport catches
+// up once per update, in flushOutput.
+func (m *model) handleOutputLines(target string, lines []string) {
+	var dests []outputDest
+	if task := m.taskByName[target]; task != nil {
+		dests = append(dests, outputDest{buf: &task.Output})
 	}
-	if step := m.stepByID[msg.Target]; step != nil {
-		step.Output = append(step.Output, msg.Line)
+	if step := m.stepByID[target]; step != nil {
+		dests = append(dests, outputDest{buf: &step.Output})
 	}
 
-	shouldRefresh := false
+	selected := m.selectedEntry()
 	for _, task := range m.tasks {
 		if !task.Running || len(task.StepTargets) == 0 {
 			continue
 		}
-		if info, ok := task.StepTargets[msg.Target]; ok {
-			prefix := m.stepOutputPrefix(info)
-			task.Output = append(task.Output, fmt.Sprintf("%s: %s", prefix, msg.Line))
-			if entry := m.selectedEntry(); entry != nil && entry.Kind == entryTask && entry.Target == task.Def.Name {
-				shouldRefresh = true
+		if info, ok := m.outputStepTarget(task, target, 0); ok {
+			dests = append(dests, outputDest{buf: &task.Output, prefix: m.stepOutputPrefix(info) + ": ", strip: task.Def.StripANSI})
+			if selected != nil && selected.Kind == entryTask && selected.Target == task.Def.Name {
+				m.outputDirty = true
 			}
-			continue
 		}
-		if taskName, ok := stepTaskFromID(msg.Target); ok {
-			if info, ok := task.StepTargets[taskName]; ok {
-				prefix := m.stepOutputPrefix(info)
-				task.Output = append(task.Output, fmt.Sprintf("%s: %s", prefix, msg.Line))
-				if entry := m.selectedEntry(); entry != nil && entry.Kind == entryTask && entry.Target == task.Def.Name {
-					shouldRefresh = true
-				}
-			}
+	}
+
+	if selected != nil && selected.Target == target {
+		m.outputDirty = true
+	}
+
+	term := m.terminalFor(target)
+	for _, line := range lines {
+		for _, row := range term.write(line) {
+			term.show(row, dests)
 		}
 	}
+}
 
-	if entry := m.selectedEntry(); entry != nil && entry.Target == msg.Target {
-		shouldRefresh = true
+// terminalFor returns the terminal target's output goes through, starting
+// a new one with each run.
+func (m *model) terminalFor(target string) *terminal {
+	term := m.terms[target]
+	if term == nil {
+		term = &terminal{strip: m.stripANSI(target)}
+		m.terms[target] = term
 	}
+	return term
+}
 
-	if shouldRefresh {
-		m.refreshViewport()
-		if m.autoScroll {
-			m.viewport.GotoBottom()
+// stripANSI reports whether target belongs to a task with strip_ansi.
+func (m *model) stripANSI(target string) bool {
+	if taskName, ok := stepTaskFromID(target); ok {
+		target = taskName
+	}
+	task := m.taskByName[target]
+	return task != nil && task.Def.StripANSI
+}
+
+// outputStepTarget finds which of task's steps produced output for target,
+// following running child tasks so nested tasks and combos are prefixed by
+// the top-level step they belong to.
+func (m *model) outputStepTarget(task *Task, target string, depth int) (stepTargetInfo, bool) {
+	if info, ok := task.StepTargets[target]; ok {
+		return info, true
+	}
+	if taskName, ok := stepTaskFromID(target); ok {
+		if info, ok := task.StepTargets[taskName]; ok {
+			return info, true
 		}
 	}
+	if depth > len(m.tasks) {
+		return stepTargetInfo{}, false
+	}
+	for _, step := range task.Steps {
+		if step.Kind != StepTask {
+			continue
+		}
+		child := m.taskByName[step.TaskName]
+		if child == nil || child == task || !child.Running {
+			continue
+		}
+		if _, ok := m.outputStepTarget(child, target, depth+1); ok {
+			return task.StepTargets[step.TaskName], true
+		}
+	}
+	return stepTargetInfo{}, false
 }
 
-func (m *model) outputForEntry(entry entry) []string {
+func (m *model) outputForEntry(entry entry) *outputBuffer {
 	if entry.Kind == entryStep {
 		if step := m.stepByID[entry.Target]; step != nil {
-			return step.Output
+			return &step.Output
 		}
 		return nil
 	}
 	if task := m.taskByName[entry.Target]; task != nil {
-		return task.Output
+		return &task.Output
 	}
 	return nil
 }
@@ -1033,10 +1467,11 @@ func (m *model) selectionText() string {
 	if entry == nil {
 		return ""
 	}
-	lines := m.outputForEntry(*entry)
-	if len(lines) == 0 {
+	buf := m.outputForEntry(*entry)
+	if buf == nil || buf.Len() == 0 {
 		return ""
 	}
+	lines := buf.Lines(m.outputStart, m.view.end)
 
 	start, end := normalizeSelection(m.selection.Start, m.selection.End)
 	if start.Line == end.Line && start.Col == end.Col {
@@ -1116,6 +1551,9 @@ func (m *model) entryStatus(entry entry) (TaskStatus, string) {
 }
 
 func (m *model) entryMarker(entry entry) string {
+	if status, _ := m.entryStatus(entry); status == StatusSkipped {
+		return markerSkipped
+	}
 	if !m.entryExpandable(entry) {
 		return " "
 	}
@@ -1165,6 +1603,14 @@ func (m *model) renderEntryLine(entry entry) string {
 		}
 		marker := m.entryMarker(entry)
 		line := fmt.Sprintf("%s%s %s", indent, marker, base)
+		if task != nil {
+			if badge := m.retriggerBadge(task); badge != "" {
+				line = fmt.Sprintf("%s %s", line, badge)
+			}
+			if badge := m.scheduleBadge(task); badge != "" {
+				line = fmt.Sprintf("%s %s", line, badge)
+			}
+		}
 		statusKind, status := m.entryStatus(entry)
 		if status == "" {
 			return line
@@ -1176,6 +1622,9 @@ func (m *model) renderEntryLine(entry entry) string {
 	}
 
 	prefix := stepPrefix(entry.Mode, entry.Index)
+	if marker := m.entryMarker(entry); marker == markerSkipped {
+		prefix = marker
+	}
 	line := fmt.Sprintf("%s%s %s", indent, prefix, entry.Label)
 	statusKind, status := m.entryStatus(entry)
 	if status == "" {
@@ -1184,7 +1633,7 @@ func (m *model) renderEntryLine(entry entry) string {
 	return fmt.Sprintf("%s  %s", line, statusStyle(statusKind).Render(status))
 }
 
-func (m *model) startTask(taskName string, fromCombo bool) tea.Cmd {
+func (m *model) startTask(taskName string, background bool) tea.Cmd {
 	task := m.taskByName[taskName]
 	if task == nil {
 		return nil
@@ -1192,30 +1641,63 @@ func (m *model) startTask(taskName string, fromCombo bool) tea.Cmd {
 	if task.Running {
 		return nil
 	}
-	if !fromCombo && m.isTaskDisabled(taskName) {
+	if busy := m.busyTask(taskName); busy != "" {
+		if !background {
+			m.notice = fmt.Sprintf("%s not started: %s is running", taskName, busy)
+		}
+		return nil
+	}
+	if m.remote != nil {
+		m.remote.send(daemonRequest{Op: "start", Target: taskName})
+		if !background {
+			m.selectTaskEntry(taskName)
+		}
 		return nil
 	}
 
-	task.Output = nil
+	task.Output.Reset()
+	delete(m.terms, task.Def.Name)
 	task.Status = StatusRunning
 	task.ExitCode = 0
 	task.Running = true
+	task.StartedAt = time.Now()
+	task.Usage = TaskUsageMsg{}
 	m.runSeq++
 	task.RunSeq = m.runSeq
+	m.runIDs[taskName] = task.RunSeq
 	m.prepareTaskSteps(task)
 	m.resetChildTaskStatuses(task)
 
-	ctx, cancel := context.WithCancel(context.Background())
-	task.cancel = cancel
+	runCtx, cancel := context.WithCancel(context.Background())
+	ctx, killHooks := withHookKill(runCtx)
+	task.cancel = func() {
+		// A second kill stops the finish hooks of a canceled run.
+		if runCtx.Err() != nil {
+			killHooks()
+		}
+		cancel()
+	}
+	task.killHooks = killHooks
+	ctx, m.runProcs[taskName] = withRunProcesses(ctx)
 
 	msgCh := make(chan tea.Msg, 128)
 	task.msgCh = msgCh
 	m.streamBySource[taskName] = msgCh
 
+	// Interactive tasks take the terminal over from the UI. A daemon has no
+	// terminal, and headless runs (no input table) hand it to commands
+	// anyway.
+	if task.Def.Interactive && inputs != nil && m.daemon == nil {
+		run := &foregroundRun{ctx: ctx, taskName: taskName, def: task.Def, shell: m.cfg.Shell, init: m.cfg.Init, resolve: m.resolveTask, msgCh: msgCh}
+		m.rebuildEntries()
+		m.selectTaskEntry(taskName)
+		return tea.Batch(tea.Exec(run, nil), listenTaskMsgs(taskName, msgCh))
+	}
+
 	go runTask(ctx, taskName, task.Def, m.cfg.Shell, m.cfg.Init, m.resolveTask, msgCh)
 
 	m.rebuildEntries()
-	if !fromCombo {
+	if !background {
 		m.selectTaskEntry(taskName)
 	}
 	entry := m.selectedEntry()
@@ -1228,6 +1710,35 @@ func (m *model) startTask(taskName string, fromCombo bool) tea.Cmd {
 	return listenTaskMsgs(taskName, msgCh)
 }
 
+// busyTask names the running task that keeps taskName from starting
+// without running a task twice: one of the tasks it runs, or a running task
+// (a combo, say) that runs it. It is "" when taskName can start.
+func (m *model) busyTask(taskName string) string {
+	runs := map[string]int{}
+	countTaskRuns(taskName, m.resolveTask, runs, map[string]bool{})
+	for _, task := range m.tasks {
+		if task.Running && runs[task.Def.Name] > 0 {
+			return task.Def.Name
+		}
+	}
+	for _, task := range m.tasks {
+		if !task.Running || task.Def.Name == taskName {
+			continue
+		}
+		runs := map[string]int{}
+		countTaskRuns(task.Def.Name, m.resolveTask, runs, map[string]bool{})
+		if runs[taskName] > 0 {
+			return task.Def.Name
+		}
+	}
+	return ""
+}
+
+func (m *model) isComboEntry(entry entry) bool {
+	task := m.taskByName[entry.Target]
+	return entry.Kind == entryTask && task != nil && task.Def.Combo
+}
+
 func (m *model) startStepEntry(entry entry) tea.Cmd {
 	if entry.Kind != entryStep {
 		return nil
@@ -1246,12 +1757,22 @@ func (m *model) startStepEntry(entry entry) tea.Cmd {
 	if step.Running {
 		return nil
 	}
+	if m.remote != nil {
+		m.remote.send(daemonRequest{Op: "step", Target: stepID})
+		return nil
+	}
 
 	ctx, cancel := context.WithCancel(context.Background())
 	m.stepCancel[stepID] = cancel
+	ctx, m.runProcs[stepID] = withRunProcesses(ctx)
+	if len(entry.Env) > 0 {
+		ctx = withStepEnv(ctx, entry.Env)
+	}
 
 	msgCh := make(chan tea.Msg, 128)
 	m.streamBySource[stepID] = msgCh
+	m.runSeq++
+	m.runIDs[stepID] = m.runSeq
 
 	go func() {
 		defer close(msgCh)
@@ -1316,12 +1837,20 @@ func (m *model) killSelectedTask() tea.Cmd {
 		}
 	}
 	if task != nil && task.cancel != nil {
+		delete(m.queuePending, task.Def.Name)
 		task.cancel()
 	}
 	return nil
 }
 
+// killAllTasks cancels every run on quit. Unlike a single ctrl+k, it stops
+// finish hooks right away too: suite exits after waitForTasks and would
+// otherwise leave them running for up to hookTimeout.
 func (m *model) killAllTasks() {
+	if m.remote != nil {
+		// Detaching leaves the daemon's tasks running.
+		return
+	}
 	for _, cancel := range m.stepCancel {
 		if cancel != nil {
 			cancel()
@@ -1329,11 +1858,34 @@ func (m *model) killAllTasks() {
 	}
 	for _, task := range m.taskByName {
 		if task != nil && task.cancel != nil {
+			task.killHooks()
 			task.cancel()
 		}
 	}
 }
 
+// waitForTasks drains the runners of canceled tasks until they exit, so
+// their processes are gone (in order, for procfile mode) before suite is.
+func (m *model) waitForTasks(timeout time.Duration) {
+	deadline := time.After(timeout)
+	for _, task := range m.tasks {
+		if task.msgCh == nil {
+			continue
+		}
+	drain:
+		for {
+			select {
+			case _, ok := <-task.msgCh:
+				if !ok {
+					break drain
+				}
+			case <-deadline:
+				return
+			}
+		}
+	}
+}
+
 func (m *model) restartSelectedTask() tea.Cmd {
 	entry := m.selectedEntry()
 	if entry == nil {
@@ -1366,96 +1918,84 @@ func (m *model) restartTask(taskName string) tea.Cmd {
 	return m.startTask(taskName, false)
 }
 
-func (m *model) maybeRestartTask(taskName string) tea.Cmd {
-	if !m.restartPending[taskName] {
-		return nil
+// triggerTask starts a task, or applies its on_retrigger policy when it is
+// already running.
+func (m *model) triggerTask(taskName string) tea.Cmd {
+	task := m.taskByName[taskName]
+	if task == nil || !task.Running {
+		return m.startTask(taskName, false)
 	}
-	delete(m.restartPending, taskName)
-	return m.startTask(taskName, false)
+	switch retriggerPolicy(task.Def) {
+	case "queue":
+		m.queuePending[taskName] = true
+	case "restart":
+		return m.restartTask(taskName)
+	}
+	return nil
+}
+
+// startPendingRuns starts the queued and restarting runs that can start
+// now. A run stays pending while its task, or a task it runs or that runs
+// it, is still running.
+func (m *model) startPendingRuns() tea.Cmd {
+	var cmds []tea.Cmd
+	for _, task := range m.tasks {
+		name := task.Def.Name
+		restart := m.restartPending[name]
+		if !restart && !m.queuePending[name] || task.Running || m.busyTask(name) != "" {
+			continue
+		}
+		delete(m.restartPending, name)
+		delete(m.queuePending, name)
+		cmds = append(cmds, m.startTask(name, !restart))
+	}
+	return tea.Batch(cmds...)
 }
 
-func (m *model) triggerCombo(comboName string) tea.Cmd {
-	cb, ok := m.comboByName[comboName]
+// scheduleNext arms a tick for the task's next scheduled run. Runs that land
+// while the task is still running are skipped by startTask.
+func (m *model) scheduleNext(taskName string, now time.Time) tea.Cmd {
+	sched, ok := m.schedules[taskName]
 	if !ok {
 		return nil
 	}
-	if m.isComboDisabled(comboName) {
+	next := sched.Next(now)
+	if next.IsZero() {
+		delete(m.nextRun, taskName)
 		return nil
 	}
-
-	if cb.Mode == "parallel" {
-		cmds := make([]tea.Cmd, 0, len(cb.Run))
-		run := &comboRun{Def: cb, Pending: len(cb.Run)}
-		m.comboActive[cb.Name] = run
-		for _, name := range cb.Run {
-			cmds = append(cmds, m.startTask(name, true))
-		}
-		return tea.Batch(cmds...)
-	}
-
-	run := &comboRun{Def: cb, Index: 0}
-	m.comboActive[cb.Name] = run
-
-	cmd := m.startNextComboTask(run)
-	return cmd
+	m.nextRun[taskName] = next
+	return tea.Tick(next.Sub(now), func(time.Time) tea.Msg {
+		return scheduleMsg{TaskName: taskName}
+	})
 }
 
-func (m *model) startNextComboTask(run *comboRun) tea.Cmd {
-	for run.Index < len(run.Def.Run) {
-		taskName := run.Def.Run[run.Index]
-		run.WaitingOn = taskName
-
-		if m.taskByName[taskName].Running {
-			return nil
-		}
-		return m.startTask(taskName, true)
+func (m *model) scheduleBadge(task *Task) string {
+	sched, ok := m.schedules[task.Def.Name]
+	if !ok {
+		return ""
 	}
-	run.WaitingOn = ""
-	return nil
-}
-
-func (m *model) advanceCombos(msg TaskFinishedMsg, cmds *[]tea.Cmd) {
-	if len(m.comboActive) == 0 {
-		return
+	if m.schedulePaused {
+		return sectionStyle.Render(scheduleIcon + " paused")
 	}
+	next, ok := m.nextRun[task.Def.Name]
+	if !ok {
+		return ""
+	}
+	return sectionStyle.Render(scheduleIcon + next.Format(sched.clockFormat()))
+}
 
-	comboNames := m.combosByTask[msg.TaskID]
-	for _, comboName := range comboNames {
-		run, ok := m.comboActive[comboName]
-		if !ok {
-			continue
-		}
-
-		if run.Def.Mode == "parallel" {
-			if run.Pending > 0 {
-				run.Pending--
-			}
-			if run.Pending <= 0 {
-				delete(m.comboActive, comboName)
-			}
-			continue
-		}
-
-		if run.WaitingOn != msg.TaskID {
-			continue
-		}
-
-		if msg.Err != nil && stopOnFail(run.Def) {
-			delete(m.comboActive, comboName)
-			continue
-		}
-
-		run.Index++
-		if run.Index >= len(run.Def.Run) {
-			delete(m.comboActive, comboName)
-			continue
-		}
-
-		cmd := m.startNextComboTask(run)
-		if cmd != nil {
-			*cmds = append(*cmds, cmd)
+func (m *model) retriggerBadge(task *Task) string {
+	switch retriggerPolicy(task.Def) {
+	case "queue":
+		if m.queuePending[task.Def.Name] {
+			return statusStyle(StatusRunning).Render(retriggerIconQueue + "1")
 		}
+		return sectionStyle.Render(retriggerIconQueue)
+	case "restart":
+		return sectionStyle.Render(retriggerIconRestart)
 	}
+	return ""
 }
 
 func (m model) renderSidebar(height int) string {
@@ -1476,35 +2016,19 @@ func (m model) renderSidebar(height int) string {
 	title := titleStyle.Render(m.cfg.Title)
 	lines := []string{title, sectionStyle.Render("Tasks")}
 
+	inCombos := false
 	for i, entry := range m.entries {
-		line := m.renderEntryLine(entry)
-		disabled := entry.Kind == entryTask && m.isTaskDisabled(entry.Target)
-		if disabled && entry.Kind == entryTask {
-			line = disabledStyle.Render(line)
+		if entry.Depth == 0 && !inCombos && m.isComboEntry(entry) {
+			inCombos = true
+			lines = append(lines, "", sectionStyle.Render("Combos"))
 		}
+		line := m.renderEntryLine(entry)
 		if i == m.selected {
-			if disabled {
-				line = selectedDisabledStyle.Render(line)
-			} else {
-				line = selectedStyle.Render(line)
-			}
+			line = selectedStyle.Render(line)
 		}
 		lines = append(lines, line)
 	}
 
-	if len(m.combos) > 0 {
-		lines = append(lines, "", sectionStyle.Render("Combos"))
-		for _, cb := range m.combos {
-			line := renderComboLine(cb, m.comboActive[cb.Name] != nil)
-			if m.isComboDisabled(cb.Name) && m.comboActive[cb.Name] == nil {
-				line = disabledStyle.Render(line)
-			} else {
-				line = comboStyle.Render(line)
-			}
-			lines = append(lines, line)
-		}
-	}
-
 	content := strings.Join(lines, "\n")
 	panel := sidebarStyle.Copy().Width(contentWidth).Height(contentHeight)
 	if m.focus == focusList {
@@ -1626,16 +2150,20 @@ func (m model) renderCheatsheet() string {
 		{"→/l", "Expand group"},
 		{"g", "Scroll to top (output)"},
 		{"G", "Scroll to bottom (output)"},
+		{"/ then n/N", "Search output, next/previous match"},
 		{"enter", "Run selected task/step"},
 		{"tab", "Toggle focus list/output"},
 		{"ctrl+h", "Focus list"},
 		{"ctrl+l", "Focus output"},
 		{"ctrl+k or ctrl+x", "Kill selected"},
 		{"ctrl+r", "Restart selected task"},
+		{"ctrl+p", "Pause/resume schedules"},
+		{"ctrl+e", "Explain selected (dry-run plan)"},
+		{"ctrl+s", "Send a signal or action to selected"},
+		{"i (output)", "Type input to the running command"},
 		{"ctrl+z", "Suspend (background)"},
 		{"ctrl+q or ctrl+c", "Quit"},
-		{"task key", "Run task by hotkey"},
-		{"combo key", "Run combo by hotkey"},
+		{"task key", "Run task or combo by hotkey"},
 		{"q/esc", "Focus list + jump to bottom"},
 		{"?", "Close help"},
 	}
@@ -1652,6 +2180,22 @@ func (m model) renderCheatsheet() string {
 		keyText := modalKeyStyle.Render(padRight(row.key, keyWidth))
 		lines = append(lines, fmt.Sprintf("%s  %s", keyText, row.desc))
 	}
+	var actions []cheatRow
+	for _, task := range m.tasks {
+		for _, action := range taskActions(task.Def) {
+			actions = append(actions, cheatRow{task.Def.Name + " " + action.label, "SIG" + action.signal})
+		}
+	}
+	if len(actions) > 0 {
+		width := 0
+		for _, row := range actions {
+			width = max(width, ansi.StringWidth(row.key))
+		}
+		lines = append(lines, "", modalTitleStyle.Render("Actions (ctrl+s)"), "")
+		for _, row := range actions {
+			lines = append(lines, fmt.Sprintf("%s  %s", modalKeyStyle.Render(padRight(row.key, width)), row.desc))
+		}
+	}
 	lines = append(lines, "", modalHintStyle.Render("Press ? or Esc to close"))
 
 	body := strings.Join(lines, "\n")
@@ -1660,6 +2204,15 @@ func (m model) renderCheatsheet() string {
 }
 
 func (m model) statusBarLine(entry *entry) string {
+	if m.input != nil {
+		return m.inputStatus()
+	}
+	if m.search != nil {
+		return m.searchStatus()
+	}
+	if m.notice != "" {
+		return m.notice
+	}
 	if entry == nil {
 		return "idle"
 	}
@@ -1677,15 +2230,24 @@ func (m model) stepStatusLine(entry *entry) string {
 	switch step.Status {
 	case StatusRunning:
 		return "running"
+	case StatusQueued:
+		return "queued (waiting for a job slot)"
 	case StatusSuccess:
 		return "all good"
 	case StatusFailed:
-		if line := lastLine(step.Output); line != "" {
+		if line := step.Output.Last(); line != "" {
 			return fmt.Sprintf("%s failed: %s", step.Label, line)
 		}
 		return fmt.Sprintf("%s failed", step.Label)
 	case StatusCanceled:
 		return fmt.Sprintf("%s canceled", step.Label)
+	case StatusSkipped:
+		return fmt.Sprintf("%s skipped", step.Label)
+	case StatusWarning:
+		if line := step.Output.Last(); line != "" {
+			return fmt.Sprintf("%s failed (allowed): %s", step.Label, line)
+		}
+		return fmt.Sprintf("%s passed with warnings", step.Label)
 	default:
 		return "idle"
 	}
@@ -1697,10 +2259,17 @@ func (m model) taskStatusLine(entry *entry) string {
 		return "idle"
 	}
 	if task.Running {
+		if task.Status == StatusQueued {
+			return "queued (waiting for a job slot)"
+		}
+		status := "running"
 		if total, done := m.taskStepProgress(task); total > 0 {
-			return fmt.Sprintf("running (%d/%d)", done, total)
+			status = fmt.Sprintf("running (%d/%d)", done, total)
 		}
-		return "running"
+		if usage := usageSummary(task); usage != "" {
+			status += " · " + usage
+		}
+		return status
 	}
 	switch task.Status {
 	case StatusSuccess:
@@ -1712,6 +2281,23 @@ func (m model) taskStatusLine(entry *entry) string {
 		return "failed"
 	case StatusCanceled:
 		return "canceled"
+	case StatusSkipped:
+		return "skipped"
+	case StatusIdle:
+		if _, ok := m.schedules[task.Def.Name]; ok {
+			if m.schedulePaused {
+				return "idle (schedules paused)"
+			}
+			if next, ok := m.nextRun[task.Def.Name]; ok {
+				return fmt.Sprintf("idle (next run %s)", next.Format("Jan 2 15:04:05"))
+			}
+		}
+		return "idle"
+	case StatusWarning:
+		if label, line := m.warnedStepSummary(task); label != "" {
+			return fmt.Sprintf("passed with warnings: %s failed: %s", label, line)
+		}
+		return "passed with warnings"
 	default:
 		return "idle"
 	}
@@ -1728,7 +2314,7 @@ func (m model) taskStepProgress(task *Task) (int, int) {
 		if run == nil || run.RunSeq != task.RunSeq {
 			continue
 		}
-		if run.Status != StatusIdle && run.Status != StatusRunning {
+		if run.Status != StatusIdle && run.Status != StatusRunning && run.Status != StatusQueued {
 			done++
 		}
 	}
@@ -1742,10 +2328,10 @@ func (m model) failedStepSummary(task *Task) (string, string) {
 			continue
 		}
 		if run.Status == StatusFailed {
-			line := lastLine(run.Output)
+			line := run.Output.Last()
 			if step.Kind == StepTask {
 				if child := m.taskByName[step.TaskName]; child != nil {
-					line = lastLine(child.Output)
+					line = child.Output.Last()
 				}
 			}
 			return run.Label, line
@@ -1757,11 +2343,36 @@ func (m model) failedStepSummary(task *Task) (string, string) {
 	return "", ""
 }
 
-func lastLine(lines []string) string {
-	if len(lines) == 0 {
+func (m model) warnedStepSummary(task *Task) (string, string) {
+	for _, step := range task.Steps {
+		run := task.StepRuns[step.ID]
+		if run == nil || run.RunSeq != task.RunSeq || run.Status != StatusWarning {
+			continue
+		}
+		if step.Kind == StepTask {
+			if child := m.taskByName[step.TaskName]; child != nil {
+				if label, line := m.warnedStepSummary(child); label != "" {
+					return fmt.Sprintf("%s > %s", run.Label, label), line
+				}
+				return run.Label, child.Output.Last()
+			}
+		}
+		return run.Label, run.Output.Last()
+	}
+	return "", ""
+}
+
+// usageSummary renders a running task's latest CPU and memory sample, with
+// its max_memory when set.
+func usageSummary(task *Task) string {
+	if task.Usage.RSS == 0 {
 		return ""
 	}
-	return strings.TrimSpace(lines[len(lines)-1])
+	memory := formatBytes(task.Usage.RSS)
+	if task.Def.MaxMemory != "" {
+		memory += " of " + task.Def.MaxMemory
+	}
+	return fmt.Sprintf("%.0f%% cpu · %s", task.Usage.CPU, memory)
 }
 
 func fitView(view string, width, height int) string {
@@ -1863,16 +2474,8 @@ func taskDisplayName(def TaskDef) string {
 	return fmt.Sprintf("%s %s", keyLabel, label)
 }
 
-func renderComboLine(cb ComboDef, running bool) string {
-	base := fmt.Sprintf("[%s] %s (%s)", cb.Key, cb.Name, cb.Mode)
-	if !running {
-		return base
-	}
-	return fmt.Sprintf("%s  %s", base, statusStyle(StatusRunning).Render(statusIconRunning))
-}
-
 func taskStatusText(task *Task) string {
-	if task.Running && task.Def.Persistent {
+	if task.Running && task.Def.Persistent && task.Status != StatusQueued {
 		return statusIconPersistent
 	}
 	return statusLabel(task.Status, task.ExitCode)
@@ -1884,6 +2487,17 @@ const (
 	statusIconSuccess    = ""
 	statusIconFailed     = ""
 	statusIconCanceled   = ""
+	statusIconSkipped    = ""
+	statusIconWarning    = ""
+	statusIconQueued     = ""
+)
+
+const markerSkipped = "↷"
+
+const (
+	retriggerIconQueue   = "⇉"
+	retriggerIconRestart = "↻"
+	scheduleIcon         = ""
 )
 
 func statusLabel(status TaskStatus, exitCode int) string {
@@ -1899,6 +2513,15 @@ func statusLabel(status TaskStatus, exitCode int) string {
 		return statusIconFailed
 	case StatusCanceled:
 		return statusIconCanceled
+	case StatusSkipped:
+		return statusIconSkipped
+	case StatusWarning:
+		if exitCode != 0 {
+			return fmt.Sprintf("%s %d", statusIconWarning, exitCode)
+		}
+		return statusIconWarning
+	case StatusQueued:
+		return statusIconQueued
 	default:
 		return ""
 	}
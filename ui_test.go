@@ -1,7 +1,10 @@
 package main
 
 import (
+	"fmt"
+	"strings"
 	"testing"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 )
@@ -58,6 +61,11 @@ func TestTaskStatusText(t *testing.T) {
 		t.Fatalf("expected canceled icon")
 	}
 
+	task = &Task{Status: StatusWarning}
+	if taskStatusText(task) != statusIconWarning {
+		t.Fatalf("expected warning icon")
+	}
+
 	task = &Task{Status: StatusIdle}
 	if taskStatusText(task) != "" {
 		t.Fatalf("expected empty status")
@@ -77,16 +85,16 @@ func TestUpdateContinuesListeningAfterChildTaskFinished(t *testing.T) {
 	ch := make(chan tea.Msg, 1)
 	m.streamBySource["parent"] = ch
 
-	_, cmd := m.Update(taskStreamMsg{Source: "parent", Msg: TaskFinishedMsg{TaskID: "child"}})
+	_, cmd := m.Update(taskStreamBatchMsg{Source: "parent", Msgs: []tea.Msg{TaskFinishedMsg{TaskID: "child"}}})
 	if cmd == nil {
 		t.Fatalf("expected listen command after child task finished")
 	}
 
 	ch <- TaskStartedMsg{TaskName: "parent"}
 	msg := cmd()
-	stream, ok := msg.(taskStreamMsg)
+	stream, ok := msg.(taskStreamBatchMsg)
 	if !ok {
-		t.Fatalf("expected taskStreamMsg, got %T", msg)
+		t.Fatalf("expected taskStreamBatchMsg, got %T", msg)
 	}
 	if stream.Source != "parent" {
 		t.Fatalf("expected source parent, got %q", stream.Source)
@@ -161,3 +169,330 @@ func TestNamedStepUsesLabel(t *testing.T) {
 		t.Fatalf("expected step entry for parent task")
 	}
 }
+
+func TestSkippedStepMarker(t *testing.T) {
+	cfg := Config{
+		Tasks: []TaskDef{
+			{Name: "parent", Seq: StepList{{Value: "echo one", Kind: StepCommand}, {Value: "echo two", Kind: StepCommand}}},
+		},
+		SidebarWidth: 32,
+	}
+
+	m := newModel(cfg)
+	task := m.taskByName["parent"]
+	m.handleTaskStarted("parent")
+	m.handleStepFinished(StepFinishedMsg{StepID: stepID("parent", StepModeSeq, 1), Skipped: true})
+	m.expanded["task:parent"] = true
+	m.rebuildEntries()
+
+	for _, entry := range m.entries {
+		if entry.Kind != entryStep {
+			continue
+		}
+		marker := m.entryMarker(entry)
+		if entry.Index == 1 && marker != markerSkipped {
+			t.Fatalf("expected skipped marker, got %q", marker)
+		}
+		if entry.Index == 0 && marker == markerSkipped {
+			t.Fatalf("did not expect skipped marker on idle step")
+		}
+	}
+	if task.StepRuns[stepID("parent", StepModeSeq, 1)].Status != StatusSkipped {
+		t.Fatalf("expected skipped status")
+	}
+}
+
+func TestCombosAreTaskEntries(t *testing.T) {
+	cfg := Config{
+		Tasks: []TaskDef{
+			{Name: "lint", Cmd: StepList{{Value: "echo lint", Kind: StepCommand}}},
+		},
+		Combos:       []ComboDef{{Name: "all", Key: "a", Mode: "sequential", Run: StepList{{Value: "lint", Kind: StepTask}}}},
+		SidebarWidth: 32,
+	}
+	cfg.migrateCombos()
+
+	m := newModel(cfg)
+	if m.taskKeys["a"] != "all" {
+		t.Fatalf("expected combo key to map to its task")
+	}
+	if len(m.entries) != 2 || !m.isComboEntry(m.entries[1]) {
+		t.Fatalf("expected combo entry after tasks, got %#v", m.entries)
+	}
+	if !m.entryExpandable(m.entries[1]) {
+		t.Fatalf("expected combo entry to be expandable")
+	}
+}
+
+func TestComboWaitsForRunningChildren(t *testing.T) {
+	cfg := Config{
+		Tasks: []TaskDef{
+			{Name: "build", Key: "b", Cmd: StepList{{Value: "true", Kind: StepCommand}}},
+			{Name: "test", Key: "t", Cmd: StepList{{Value: "true", Kind: StepCommand}}},
+			{Name: "all", Key: "a", Combo: true, Seq: StepList{{Value: "build", Kind: StepTask}, {Value: "test", Kind: StepTask}}},
+		},
+		Shell:        "/bin/sh",
+		SidebarWidth: 32,
+	}
+
+	m := newModel(cfg)
+	m.taskByName["test"].Running = true
+	if cmd := m.startTask("all", false); cmd != nil || m.taskByName["all"].Running {
+		t.Fatalf("expected combo to wait while a child task runs")
+	}
+
+	m.taskByName["test"].Running = false
+	m.taskByName["all"].Running = true
+	if cmd := m.startTask("test", false); cmd != nil || m.taskByName["test"].Running {
+		t.Fatalf("expected child task to wait while its combo runs")
+	}
+	if cmd := m.startTask("build", false); cmd != nil {
+		t.Fatalf("expected child task to wait while its combo runs")
+	}
+	if got := m.statusBarLine(m.selectedEntry()); got != "build not started: all is running" {
+		t.Fatalf("expected the refusal in the status bar, got %q", got)
+	}
+}
+
+func TestRetriggerQueueInsideCombo(t *testing.T) {
+	cfg := Config{
+		Tasks: []TaskDef{
+			{Name: "test", Key: "t", OnRetrigger: "queue", Cmd: StepList{{Value: "true", Kind: StepCommand}}},
+			{Name: "all", Key: "a", Combo: true, Seq: StepList{{Value: "test", Kind: StepTask}}},
+		},
+		Shell:        "/bin/sh",
+		SidebarWidth: 32,
+	}
+
+	m := newModel(cfg)
+	m.taskByName["all"].Running = true
+	m.taskByName["test"].Running = true
+	m.triggerTask("test")
+	if !m.queuePending["test"] {
+		t.Fatalf("expected queued rerun")
+	}
+
+	m.handleTaskFinished(TaskFinishedMsg{TaskID: "test"})
+	if cmd := m.startPendingRuns(); cmd != nil || !m.queuePending["test"] {
+		t.Fatalf("expected the rerun to stay queued while the combo runs")
+	}
+
+	m.handleTaskFinished(TaskFinishedMsg{TaskID: "all"})
+	if cmd := m.startPendingRuns(); cmd == nil || m.queuePending["test"] {
+		t.Fatalf("expected the queued run to start once the combo finished")
+	}
+	t.Cleanup(m.killAllTasks)
+	if !m.taskByName["test"].Running {
+		t.Fatalf("expected test to be running")
+	}
+}
+
+func TestRetriggerQueueCoalesces(t *testing.T) {
+	cfg := Config{
+		Tasks: []TaskDef{
+			{Name: "test", Key: "t", OnRetrigger: "queue", Cmd: StepList{{Value: "true", Kind: StepCommand}}},
+			{Name: "lint", Key: "l", Cmd: StepList{{Value: "true", Kind: StepCommand}}},
+		},
+		Shell:        "/bin/sh",
+		SidebarWidth: 32,
+	}
+
+	m := newModel(cfg)
+	task := m.taskByName["test"]
+	task.Running = true
+
+	if cmd := m.triggerTask("test"); cmd != nil {
+		t.Fatalf("expected no command while queued")
+	}
+	m.triggerTask("test")
+	if !m.queuePending["test"] {
+		t.Fatalf("expected queued rerun")
+	}
+	if badge := m.retriggerBadge(task); badge == "" {
+		t.Fatalf("expected queue badge")
+	}
+
+	m.handleTaskFinished(TaskFinishedMsg{TaskID: "test"})
+	if cmd := m.startPendingRuns(); cmd == nil {
+		t.Fatalf("expected queued run to start")
+	}
+	t.Cleanup(m.killAllTasks)
+	if m.queuePending["test"] {
+		t.Fatalf("expected queue to be drained")
+	}
+	if cmd := m.startPendingRuns(); cmd != nil {
+		t.Fatalf("expected repeated presses to coalesce into one run")
+	}
+
+	lint := m.taskByName["lint"]
+	lint.Running = true
+	m.triggerTask("lint")
+	if m.queuePending["lint"] || m.restartPending["lint"] {
+		t.Fatalf("expected default policy to ignore retrigger")
+	}
+}
+
+func TestRetriggerRestart(t *testing.T) {
+	cfg := Config{
+		Tasks: []TaskDef{
+			{Name: "server", OnRetrigger: "restart", Cmd: StepList{{Value: "true", Kind: StepCommand}}},
+		},
+		SidebarWidth: 32,
+	}
+
+	m := newModel(cfg)
+	canceled := false
+	task := m.taskByName["server"]
+	task.Running = true
+	task.cancel = func() { canceled = true }
+
+	m.triggerTask("server")
+	if !canceled || !m.restartPending["server"] {
+		t.Fatalf("expected running task to be canceled and restarted")
+	}
+}
+
+func TestScheduledTaskPaused(t *testing.T) {
+	cfg := Config{
+		Tasks: []TaskDef{
+			{Name: "fetch", Schedule: "every 5m", Cmd: StepList{{Value: "git fetch", Kind: StepCommand}}},
+		},
+		SidebarWidth: 32,
+	}
+
+	m := newModel(cfg)
+	now := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	if cmd := m.scheduleNext("fetch", now); cmd == nil {
+		t.Fatalf("expected schedule tick")
+	}
+	if next := m.nextRun["fetch"]; !next.Equal(now.Add(5 * time.Minute)) {
+		t.Fatalf("unexpected next run: %v", next)
+	}
+	if badge := m.scheduleBadge(m.taskByName["fetch"]); !strings.Contains(badge, "12:05") {
+		t.Fatalf("expected next run in badge, got %q", badge)
+	}
+
+	updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyCtrlP})
+	m = updated.(model)
+	if !m.schedulePaused {
+		t.Fatalf("expected schedules paused")
+	}
+	updated, _ = m.Update(scheduleMsg{TaskName: "fetch"})
+	m = updated.(model)
+	if m.taskByName["fetch"].Running {
+		t.Fatalf("did not expect paused schedule to start the task")
+	}
+}
+
+func TestStatusBarShowsUsage(t *testing.T) {
+	cfg := Config{
+		Tasks: []TaskDef{
+			{Name: "web", Key: "w", MaxMemory: "1G", Persistent: true, Cmd: StepList{{Value: "bin/server", Kind: StepCommand}}},
+		},
+		SidebarWidth: 32,
+	}
+	m := newModel(cfg)
+	m.handleTaskStarted("web")
+	m.handleStreamMsg(taskStreamMsg{Source: "web", Msg: TaskUsageMsg{TaskID: "web", CPU: 12.4, RSS: 300 << 20}})
+	if got := m.statusBarLine(m.selectedEntry()); got != "running · 12% cpu · 300MiB of 1G" {
+		t.Fatalf("unexpected status line %q", got)
+	}
+
+	m.handleTaskFinished(TaskFinishedMsg{TaskID: "web", ExitCode: -1, Err: &memoryLimitError{RSS: 2 << 30, Limit: 1 << 30}})
+	if task := m.taskByName["web"]; task.Usage.RSS != 0 || task.Status != StatusFailed {
+		t.Fatalf("expected a failed task without usage, got %#v", task)
+	}
+}
+
+func TestOutputBatchAppendsToViewport(t *testing.T) {
+	cfg := Config{
+		Tasks: []TaskDef{
+			{Name: "web", Key: "w", Persistent: true, Cmd: StepList{{Value: "bin/server", Kind: StepCommand}}},
+		},
+		SidebarWidth: 32,
+	}
+	m := newModel(cfg)
+	updated, _ := m.Update(tea.WindowSizeMsg{Width: 100, Height: 20})
+	m = updated.(model)
+	m.handleTaskStarted("web")
+
+	batch := func(lines ...string) taskStreamBatchMsg {
+		msg := taskStreamBatchMsg{Source: "web"}
+		for _, line := range lines {
+			msg.Msgs = append(msg.Msgs, TaskOutputMsg{Target: "web", Line: line})
+		}
+		return msg
+	}
+	updated, _ = m.Update(batch("one", "two"))
+	m = updated.(model)
+	updated, _ = m.Update(batch("three"))
+	m = updated.(model)
+
+	if got := m.view.content.String(); got != "one\ntwo\nthree" {
+		t.Fatalf("unexpected viewport content %q", got)
+	}
+	if m.viewport.TotalLineCount() != 3 || !m.viewport.AtBottom() {
+		t.Fatalf("expected the viewport to follow the output")
+	}
+
+	m.handleTaskFinished(TaskFinishedMsg{TaskID: "web"})
+	m.handleTaskStarted("web")
+	updated, _ = m.Update(batch("again"))
+	m = updated.(model)
+	if got := m.view.content.String(); got != "again" {
+		t.Fatalf("expected a restart to start the content over, got %q", got)
+	}
+}
+
+// BenchmarkOutputThroughput feeds output through Update a frame at a time,
+// rendering after each frame; lines/s should stay well above 50k.
+func BenchmarkOutputThroughput(b *testing.B) {
+	cfg := Config{
+		Tasks: []TaskDef{
+			{Name: "build", Key: "b", Cmd: StepList{{Value: "webpack", Kind: StepCommand}}},
+		},
+		SidebarWidth: 32,
+	}
+	m := newModel(cfg)
+	updated, _ := m.Update(tea.WindowSizeMsg{Width: 160, Height: 50})
+	m = updated.(model)
+	m.handleTaskStarted("build")
+
+	const frameLines = 1000
+	frame := taskStreamBatchMsg{Source: "build"}
+	for i := 0; i < frameLines; i++ {
+		line := fmt.Sprintf("\x1b[32m[%04d]\x1b[0m asset main.%x.js 1.2 MiB [emitted] [immutable] (name: main)", i, i*7919)
+		frame.Msgs = append(frame.Msgs, TaskOutputMsg{Target: "build", Line: line})
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		updated, _ = m.Update(frame)
+		m = updated.(model)
+		_ = m.View()
+	}
+	b.ReportMetric(float64(b.N*frameLines)/b.Elapsed().Seconds(), "lines/s")
+}
+
+func TestKillAllTasksStopsFinishHooks(t *testing.T) {
+	cfg := Config{
+		Tasks: []TaskDef{{
+			Name:      "web",
+			Key:       "w",
+			TaskHooks: TaskHooks{OnExit: CommandList{"sleep 30"}},
+			Cmd:       StepList{{Value: "sleep 30", Kind: StepCommand}},
+		}},
+		Shell:        "/bin/sh",
+		SidebarWidth: 32,
+	}
+
+	m := newModel(cfg)
+	m.startTask("web", true)
+	time.Sleep(100 * time.Millisecond)
+	started := time.Now()
+	m.killAllTasks()
+	m.waitForTasks(10 * time.Second)
+	if elapsed := time.Since(started); elapsed > 5*time.Second {
+		t.Fatalf("expected quitting to stop on_exit, waited %v", elapsed)
+	}
+}
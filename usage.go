@@ -0,0 +1,249 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// usageInterval is how often a running task samples its process tree, as a
+// time.Duration.
+var usageInterval atomic.Int64
+
+func init() {
+	usageInterval.Store(int64(2 * time.Second))
+}
+
+// TaskUsageMsg reports the CPU and memory used by a running task's
+// processes, including its steps and the tasks it runs.
+type TaskUsageMsg struct {
+	TaskID string
+	// CPU is a percentage of one core; RSS is in bytes.
+	CPU float64
+	RSS uint64
+}
+
+// procInfo is one entry of the process table.
+type procInfo struct {
+	PID  int
+	PPID int
+	PGID int
+	// CPU is the user plus system time used so far; RSS is in bytes.
+	CPU time.Duration
+	RSS uint64
+}
+
+// memoryLimitError is how a task killed for exceeding max_memory fails.
+type memoryLimitError struct {
+	RSS   uint64
+	Limit uint64
+}
+
+func (e *memoryLimitError) Error() string {
+	return fmt.Sprintf("killed: memory %s exceeded max_memory %s", formatBytes(e.RSS), formatBytes(e.Limit))
+}
+
+// runProcesses is the set of commands one task run has running. runSingle
+// registers its command through the context, so nested tasks and steps
+// count toward the run that started them.
+type runProcesses struct {
+	mu       sync.Mutex
+	commands map[int]runCommand
+}
+
+// runCommand is one running command of a run, keyed by its pid.
+type runCommand struct {
+	target string
+	cgroup string
+}
+
+type runProcessesKey struct{}
+
+func withRunProcesses(ctx context.Context) (context.Context, *runProcesses) {
+	if procs, ok := ctx.Value(runProcessesKey{}).(*runProcesses); ok {
+		return ctx, procs
+	}
+	procs := &runProcesses{commands: make(map[int]runCommand)}
+	return context.WithValue(ctx, runProcessesKey{}, procs), procs
+}
+
+// trackRunProcess adds pid, started for target, to the run in ctx until the
+// returned func is called.
+func trackRunProcess(ctx context.Context, pid int, target, cgroup string) func() {
+	procs, ok := ctx.Value(runProcessesKey{}).(*runProcesses)
+	if !ok {
+		return func() {}
+	}
+	procs.mu.Lock()
+	procs.commands[pid] = runCommand{target: target, cgroup: cgroup}
+	procs.mu.Unlock()
+	return func() {
+		procs.mu.Lock()
+		delete(procs.commands, pid)
+		procs.mu.Unlock()
+	}
+}
+
+// pids lists the run's commands whose target matches.
+func (r *runProcesses) pids(match func(target string) bool) []int {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var pids []int
+	for pid, command := range r.commands {
+		if match(command.target) {
+			pids = append(pids, pid)
+		}
+	}
+	return pids
+}
+
+// sample lists every process of the run: its commands, their descendants,
+// and anything left in their cgroups.
+func (r *runProcesses) sample() []procInfo {
+	r.mu.Lock()
+	roots := make([]int, 0, len(r.commands))
+	var extra []int
+	for pid, command := range r.commands {
+		roots = append(roots, pid)
+		extra = append(extra, cgroupPIDs(command.cgroup)...)
+	}
+	r.mu.Unlock()
+	if len(roots) == 0 {
+		return nil
+	}
+	return processTree(roots, extra)
+}
+
+// usageSampler turns successive samples into CPU percentages.
+type usageSampler struct {
+	last map[int]time.Duration
+	at   time.Time
+}
+
+func newUsageSampler(now time.Time) *usageSampler {
+	return &usageSampler{last: map[int]time.Duration{}, at: now}
+}
+
+// add returns the CPU used since the previous sample as a percentage of one
+// core, and the total RSS. Processes seen for the first time count all the
+// CPU they have used, since they started after the previous sample.
+func (s *usageSampler) add(procs []procInfo, now time.Time) (float64, uint64) {
+	elapsed := now.Sub(s.at)
+	var cpu time.Duration
+	var rss uint64
+	seen := make(map[int]time.Duration, len(procs))
+	for _, proc := range procs {
+		seen[proc.PID] = proc.CPU
+		rss += proc.RSS
+		if delta := proc.CPU - s.last[proc.PID]; delta > 0 {
+			cpu += delta
+		}
+	}
+	s.last, s.at = seen, now
+	if elapsed <= 0 {
+		return 0, rss
+	}
+	return float64(cpu) / float64(elapsed) * 100, rss
+}
+
+// watchUsage samples the run's processes every usageInterval and reports
+// them as TaskUsageMsg when report is set. With a limit, the run is canceled with a
+// memoryLimitError once its RSS goes over it. The returned func stops the
+// watcher and waits for it, so nothing is sent after it returns.
+func watchUsage(ctx context.Context, taskName string, limit uint64, report bool, procs *runProcesses, cancel context.CancelCauseFunc, msgCh chan<- tea.Msg) func() {
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(time.Duration(usageInterval.Load()))
+		defer ticker.Stop()
+		sampler := newUsageSampler(time.Now())
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				cpu, rss := sampler.add(procs.sample(), now)
+				if report {
+					select {
+					case msgCh <- TaskUsageMsg{TaskID: taskName, CPU: cpu, RSS: rss}:
+					case <-stop:
+						return
+					}
+				}
+				if limit > 0 && rss > limit {
+					err := &memoryLimitError{RSS: rss, Limit: limit}
+					msgCh <- TaskOutputMsg{Target: taskName, Line: "suite: " + err.Error()}
+					cancel(err)
+					return
+				}
+			}
+		}
+	}()
+	return func() {
+		close(stop)
+		<-done
+	}
+}
+
+// memoryLimitCause returns the memoryLimitError ctx was canceled with, if
+// any.
+func memoryLimitCause(ctx context.Context) *memoryLimitError {
+	err, ok := context.Cause(ctx).(*memoryLimitError)
+	if !ok {
+		return nil
+	}
+	return err
+}
+
+// parseByteSize reads a size like 512M, 1.5GiB or 800000000. Units are
+// binary (K = 1024) whether or not they end in iB.
+func parseByteSize(value string) (uint64, error) {
+	value = strings.TrimSpace(value)
+	number := strings.TrimRightFunc(value, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	unit := strings.ToUpper(strings.TrimSpace(value[len(number):]))
+	unit = strings.TrimSuffix(strings.TrimSuffix(unit, "B"), "I")
+	shift := 0
+	if unit != "" {
+		shift = strings.Index("KMGT", unit) + 1
+	}
+	if number == "" || len(unit) > 1 || (unit != "" && shift == 0) {
+		return 0, fmt.Errorf("invalid size %q (use e.g. 512M or 2G)", value)
+	}
+	n, err := strconv.ParseFloat(number, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid size %q (use e.g. 512M or 2G)", value)
+	}
+	return uint64(n * float64(uint64(1)<<(10*shift))), nil
+}
+
+func formatBytes(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%dB", n)
+	}
+	value, suffix := float64(n)/unit, "KiB"
+	for _, next := range []string{"MiB", "GiB", "TiB"} {
+		if value < unit {
+			break
+		}
+		value, suffix = value/unit, next
+	}
+	if value >= 100 {
+		return fmt.Sprintf("%.0f%s", value, suffix)
+	}
+	return fmt.Sprintf("%.1f%s", value, suffix)
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestParseByteSize(t *testing.T) {
+	cases := map[string]uint64{
+		"512":    512,
+		"64K":    64 << 10,
+		"512M":   512 << 20,
+		"512MB":  512 << 20,
+		"1.5GiB": 3 << 29,
+		"2g":     2 << 30,
+	}
+	for input, want := range cases {
+		got, err := parseByteSize(input)
+		if err != nil || got != want {
+			t.Fatalf("parseByteSize(%q) = %d, %v; want %d", input, got, err, want)
+		}
+	}
+	for _, input := range []string{"", "M", "lots", "12X", "0", "-1G"} {
+		if _, err := parseByteSize(input); err == nil {
+			t.Fatalf("expected %q to be rejected", input)
+		}
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	cases := map[uint64]string{
+		512:       "512B",
+		1536:      "1.5KiB",
+		300 << 20: "300MiB",
+		3 << 29:   "1.5GiB",
+	}
+	for input, want := range cases {
+		if got := formatBytes(input); got != want {
+			t.Fatalf("formatBytes(%d) = %q; want %q", input, got, want)
+		}
+	}
+}
+
+func TestUsageSamplerCPU(t *testing.T) {
+	start := time.Now()
+	sampler := newUsageSampler(start)
+	first := []procInfo{{PID: 1, CPU: time.Second, RSS: 100}, {PID: 2, CPU: 500 * time.Millisecond, RSS: 50}}
+	if cpu, rss := sampler.add(first, start.Add(2*time.Second)); cpu != 75 || rss != 150 {
+		t.Fatalf("unexpected first sample: %v%% %d", cpu, rss)
+	}
+	// pid 2 exited; pid 3 is new.
+	second := []procInfo{{PID: 1, CPU: 2 * time.Second, RSS: 100}, {PID: 3, CPU: time.Second, RSS: 10}}
+	if cpu, rss := sampler.add(second, start.Add(4*time.Second)); cpu != 100 || rss != 110 {
+		t.Fatalf("unexpected second sample: %v%% %d", cpu, rss)
+	}
+}
+
+func TestMaxMemoryKillsTask(t *testing.T) {
+	previous := usageInterval.Swap(int64(20 * time.Millisecond))
+	t.Cleanup(func() { usageInterval.Store(previous) })
+
+	def := TaskDef{MaxMemory: "1K", Cmd: StepList{{Value: "sleep 30", Kind: StepCommand}}}
+	msgCh := make(chan tea.Msg, 32)
+	go runTask(context.Background(), "leaky", def, "/bin/sh", nil, nil, msgCh)
+
+	var usage bool
+	var output []string
+	var finished TaskFinishedMsg
+	timeout := time.After(5 * time.Second)
+	for done := false; !done; {
+		select {
+		case msg, ok := <-msgCh:
+			if !ok {
+				done = true
+				break
+			}
+			switch msg := msg.(type) {
+			case TaskUsageMsg:
+				usage = usage || msg.RSS > 0
+			case TaskOutputMsg:
+				output = append(output, msg.Line)
+			case TaskFinishedMsg:
+				finished = msg
+			}
+		case <-timeout:
+			t.Fatalf("expected the task to be killed for its memory")
+		}
+	}
+	if !usage {
+		t.Fatalf("expected a usage sample")
+	}
+	var limitErr *memoryLimitError
+	if !errors.As(finished.Err, &limitErr) || finished.Canceled {
+		t.Fatalf("expected a memory limit failure, got %#v", finished)
+	}
+	if len(output) == 0 || !strings.Contains(output[len(output)-1], "exceeded max_memory 1.0KiB") {
+		t.Fatalf("expected the kill to be reported, got %v", output)
+	}
+}